@@ -0,0 +1,58 @@
+package main
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/neural"
+	"MultilayerPerceptron/validation"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+)
+
+// compare runs the single layer perceptron and a multilayer perceptron on identical folds
+// of a CSV dataset and reports statistical tests on their difference.
+func main() {
+	var filePath = flag.String("data", "./resources/sonar.all_data.csv", "CSV dataset, class label in last column")
+	var folds = flag.Int("folds", 5, "number of folds")
+	var repeats = flag.Int("repeats", 2, "number of k-fold repetitions")
+	var seed = flag.Int64("seed", 1, "seed of first repetition")
+	var epochs = flag.Int("epochs", 100, "training epochs of both models")
+	var learningRate = flag.Float64("lr", 0.1, "learning rate of both models")
+	var hidden = flag.Int("hidden", 20, "neurons of mlp hidden layer")
+	flag.Parse()
+
+	logging.Configure(logging.Options{
+		Logger: logging.Slog(slog.New(slog.NewTextHandler(os.Stderr, nil))),
+		Levels: map[logging.Subsystem]logging.Level{logging.Loading: logging.WarnLevel, logging.Training: logging.WarnLevel, logging.Validation: logging.WarnLevel},
+	})
+
+	var dataset, errorValue = neural.LoadDataset(*filePath, neural.LoadPatternsFromCSVFile)
+	if errorValue != nil {
+		fmt.Fprintln(os.Stderr, errorValue)
+		os.Exit(1)
+	}
+
+	var perceptron = &neural.Perceptron{NeuronUnit: neural.NeuronUnit{Weights: make([]float64, len(dataset.Patterns[0].Features)), LearningRate: *learningRate}}
+	var mlp = neural.PrepareMLPNet([]int{len(dataset.Patterns[0].Features), *hidden, len(dataset.Classes)}, *learningRate,
+		neural.SigmoidTransfer, neural.SigmoidTransferDerivative)
+
+	comparison, errorValue := validation.CompareClassifiers(&mlp, perceptron, dataset, *epochs, *folds, *repeats, *seed)
+	if errorValue != nil {
+		fmt.Fprintln(os.Stderr, errorValue)
+		os.Exit(1)
+	}
+
+	fmt.Printf("mlp mean accuracy: %.2f%%, perceptron mean accuracy: %.2f%% (%d folds x %d repetitions)\n",
+		comparison.MeanA, comparison.MeanB, *folds, *repeats)
+	fmt.Printf("out-of-fold patterns correct only for mlp: %d, only for perceptron: %d\n\n", comparison.OnlyA, comparison.OnlyB)
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "test\tstatistic\tp-value\teffect size")
+	fmt.Fprintf(table, "paired t-test\t%.4f\t%.4f\t%.4f (Cohen's d)\n", comparison.PairedTTest.Statistic, comparison.PairedTTest.PValue, comparison.PairedTTest.EffectSize)
+	fmt.Fprintf(table, "corrected resampled t-test\t%.4f\t%.4f\t%.4f (Cohen's d)\n", comparison.CorrectedTTest.Statistic, comparison.CorrectedTTest.PValue, comparison.CorrectedTTest.EffectSize)
+	fmt.Fprintf(table, "McNemar\t%.4f\t%.4f\t%.4f (odds ratio)\n", comparison.McNemar.Statistic, comparison.McNemar.PValue, comparison.McNemar.EffectSize)
+	fmt.Fprintf(table, "Wilcoxon signed-rank\t%.4f\t%.4f\t%.4f (r)\n", comparison.Wilcoxon.Statistic, comparison.Wilcoxon.PValue, comparison.Wilcoxon.EffectSize)
+	table.Flush()
+}
@@ -1,6 +1,6 @@
 module MultilayerPerceptron
 
-go 1.19
+go 1.21
 
 require (
 	github.com/made2591/go-perceptron-go v0.2.0
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"log/slog"
+	"sort"
+)
+
+// logrusLogger adapts a logrus logger to Logger.
+type logrusLogger struct {
+	logger *logrus.Logger
+}
+
+// Logrus returns a Logger writing entries to logger, with the subsystem in field "subsystem".
+// Entries are still filtered by the level of logger.
+func Logrus(logger *logrus.Logger) Logger {
+	return logrusLogger{logger: logger}
+}
+
+func (adapter logrusLogger) Log(level Level, subsystem Subsystem, msg string, fields Fields) {
+	entry := adapter.logger.WithFields(logrus.Fields(fields)).WithField("subsystem", subsystem.String())
+	entry.Log(logrusLevel(level), msg)
+}
+
+// logrusLevel returns the logrus level of level.
+func logrusLevel(level Level) logrus.Level {
+	switch level {
+	case TraceLevel:
+		return logrus.TraceLevel
+	case DebugLevel:
+		return logrus.DebugLevel
+	case InfoLevel:
+		return logrus.InfoLevel
+	case WarnLevel:
+		return logrus.WarnLevel
+	}
+	return logrus.ErrorLevel
+}
+
+// slogLogger adapts a log/slog logger to Logger.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// SlogTraceLevel is the slog level of TraceLevel entries, slog has no level below debug.
+const SlogTraceLevel = slog.LevelDebug - 4
+
+// Slog returns a Logger writing entries to logger, with the subsystem in attribute "subsystem".
+// TraceLevel entries are written with SlogTraceLevel. Entries are still filtered by the handler of logger.
+func Slog(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+func (adapter slogLogger) Log(level Level, subsystem Subsystem, msg string, fields Fields) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	attributes := make([]slog.Attr, 0, len(fields)+1)
+	attributes = append(attributes, slog.String("subsystem", subsystem.String()))
+	for _, key := range keys {
+		attributes = append(attributes, slog.Any(key, fields[key]))
+	}
+	adapter.logger.LogAttrs(context.Background(), slogLevel(level), msg, attributes...)
+}
+
+// slogLevel returns the slog level of level.
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case TraceLevel:
+		return SlogTraceLevel
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case WarnLevel:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
@@ -0,0 +1,195 @@
+package logging
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+const (
+	// TraceLevel is used by hot loops, such as the propagation of a single pattern
+	TraceLevel Level = iota
+	// DebugLevel is used for progress of single epochs, folds and patterns
+	DebugLevel
+	// InfoLevel is used for completion of models, datasets and validations
+	InfoLevel
+	// WarnLevel is used for recoverable anomalies
+	WarnLevel
+	// ErrorLevel is used for failures also returned as errors
+	ErrorLevel
+)
+
+// String returns the lower case name of the level.
+func (level Level) String() string {
+	switch level {
+	case TraceLevel:
+		return "trace"
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(level))
+}
+
+// Subsystem is the part of the library writing a log entry.
+type Subsystem int
+
+const (
+	// Loading of patterns, datasets and models
+	Loading Subsystem = iota
+	// Training and execution of models
+	Training
+	// Validation, comparison and tuning of models
+	Validation
+)
+
+// String returns the lower case name of the subsystem.
+func (subsystem Subsystem) String() string {
+	switch subsystem {
+	case Loading:
+		return "loading"
+	case Training:
+		return "training"
+	case Validation:
+		return "validation"
+	}
+	return fmt.Sprintf("subsystem(%d)", int(subsystem))
+}
+
+// Fields are the key value pairs of a log entry.
+type Fields map[string]interface{}
+
+// Logger receives the log entries of the library, already filtered by level.
+type Logger interface {
+	Log(level Level, subsystem Subsystem, msg string, fields Fields)
+}
+
+// Options are the logger and verbosity of the library, set for the whole process by Configure
+// or for a single loader or model through its Logging setting (see Channel.With).
+type Options struct {
+	// receiver of log entries, nil discards them
+	Logger Logger
+	// minimum level logged for each subsystem, InfoLevel for subsystems not in map.
+	// Entries of hot loops are only built when the level of their subsystem is TraceLevel.
+	Levels map[Subsystem]Level
+}
+
+// options currently in use, the zero value is silent
+var options atomic.Value
+
+// Configure sets logger and verbosity used by the library where no Options are passed to loaders or models.
+// Without a call to Configure nothing is logged. It is safe to call concurrently with logging.
+func Configure(o Options) {
+	levels := make(map[Subsystem]Level, len(o.Levels))
+	for subsystem, level := range o.Levels {
+		levels[subsystem] = level
+	}
+	o.Levels = levels
+	options.Store(o)
+}
+
+// current returns options set by Configure.
+func current() Options {
+	o, _ := options.Load().(Options)
+	return o
+}
+
+// Channel writes log entries of one subsystem.
+type Channel struct {
+	subsystem Subsystem
+	// options overriding the ones set by Configure (nil to use them)
+	options *Options
+}
+
+// For returns the Channel of subsystem.
+func For(subsystem Subsystem) Channel {
+	return Channel{subsystem: subsystem}
+}
+
+// With returns a copy of the channel writing entries with o instead of the options set by Configure,
+// so that several embedders in one process keep their own logger. With nil o the channel is returned unchanged.
+// o must not be modified while the channel is in use.
+func (channel Channel) With(o *Options) Channel {
+	if o != nil {
+		channel.options = o
+	}
+	return channel
+}
+
+// Enabled returns whether entries of level are logged by the channel, so that hot loops
+// can skip building them.
+func (channel Channel) Enabled(level Level) bool {
+	return enabled(channel.current(), channel.subsystem, level)
+}
+
+// current returns the options of the channel, or the ones set by Configure.
+func (channel Channel) current() Options {
+	if channel.options != nil {
+		return *channel.options
+	}
+	return current()
+}
+
+// enabled returns whether o logs entries of subsystem at level.
+func enabled(o Options, subsystem Subsystem, level Level) bool {
+	if o.Logger == nil {
+		return false
+	}
+	minimum, found := o.Levels[subsystem]
+	if !found {
+		minimum = InfoLevel
+	}
+	return level >= minimum
+}
+
+// WithFields returns an entry of the channel holding fields.
+func (channel Channel) WithFields(fields Fields) Entry {
+	return Entry{channel: channel, fields: fields}
+}
+
+// Entry is a log entry waiting for its level and message.
+type Entry struct {
+	channel Channel
+	fields  Fields
+}
+
+// Trace logs the entry at TraceLevel, args are formatted as in fmt.Sprint.
+func (entry Entry) Trace(args ...interface{}) {
+	entry.log(TraceLevel, args)
+}
+
+// Debug logs the entry at DebugLevel, args are formatted as in fmt.Sprint.
+func (entry Entry) Debug(args ...interface{}) {
+	entry.log(DebugLevel, args)
+}
+
+// Info logs the entry at InfoLevel, args are formatted as in fmt.Sprint.
+func (entry Entry) Info(args ...interface{}) {
+	entry.log(InfoLevel, args)
+}
+
+// Warn logs the entry at WarnLevel, args are formatted as in fmt.Sprint.
+func (entry Entry) Warn(args ...interface{}) {
+	entry.log(WarnLevel, args)
+}
+
+// Error logs the entry at ErrorLevel, args are formatted as in fmt.Sprint.
+func (entry Entry) Error(args ...interface{}) {
+	entry.log(ErrorLevel, args)
+}
+
+// log sends the entry to the Logger of its channel if level is enabled for its subsystem.
+func (entry Entry) log(level Level, args []interface{}) {
+	o := entry.channel.current()
+	if !enabled(o, entry.channel.subsystem, level) {
+		return
+	}
+	o.Logger.Log(level, entry.channel.subsystem, fmt.Sprint(args...), entry.fields)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"MultilayerPerceptron/logging"
 	"MultilayerPerceptron/neural"
 	"MultilayerPerceptron/util"
 	"MultilayerPerceptron/validation"
@@ -11,6 +12,8 @@ import (
 func init() {
 	log.SetOutput(os.Stdout)
 	log.SetLevel(log.InfoLevel)
+	// library entries go to the same logrus logger, at info level for every subsystem
+	logging.Configure(logging.Options{Logger: logging.Logrus(log.StandardLogger())})
 }
 
 func main() {
@@ -28,11 +31,34 @@ func main() {
 		var learningRate = 0.01
 		var epochs = 500
 		var folds = 5
-		var patterns, _, _ = neural.LoadPatternsFromCSVFile(filePath)
-		var neuron = neural.NeuronUnit{Weights: make([]float64, len(patterns[0].Features)), Bias: bias, LearningRate: learningRate}
-		var scores = validation.KFoldValidation(&neuron, patterns, epochs, folds, shuffle)
-		var neuron2 = neural.NeuronUnit{Weights: make([]float64, len(patterns[0].Features)), Bias: bias, LearningRate: learningRate}
-		var scores2 = validation.RandomSubsamplingValidation(&neuron2, patterns, percentage, epochs, folds, shuffle)
+		var dataset, errorValue = neural.LoadDataset(filePath, neural.LoadPatternsFromCSVFile)
+		if errorValue != nil {
+			log.WithFields(log.Fields{
+				"level": "error",
+				"place": "main",
+				"error": errorValue,
+			}).Error("Failed to load dataset.")
+			return
+		}
+		var neuron = &neural.Perceptron{NeuronUnit: neural.NeuronUnit{Weights: make([]float64, len(dataset.Patterns[0].Features)), Bias: bias, LearningRate: learningRate}}
+		scores, _, _, errorValue := validation.KFoldValidation(neuron, dataset, epochs, folds, shuffle)
+		if errorValue != nil {
+			log.WithFields(log.Fields{
+				"level": "error",
+				"place": "main",
+				"error": errorValue,
+			}).Error("Failed to validate model.")
+			return
+		}
+		scores2, _, _, errorValue := validation.RandomSubsamplingValidation(neuron, dataset, percentage, epochs, folds, shuffle)
+		if errorValue != nil {
+			log.WithFields(log.Fields{
+				"level": "error",
+				"place": "main",
+				"error": errorValue,
+			}).Error("Failed to validate model.")
+			return
+		}
 
 		log.WithFields(log.Fields{
 			"level":  "info",
@@ -53,23 +79,48 @@ func main() {
 		}).Info("Compute backpropagation multi layer perceptron on sonar data set (binary classification problem)")
 
 		var filePath = "./resources/iris.all_data.csv"
-		var learningRate = 0.01
+		var learningRate = 0.1
 		var percentage = 0.67
 		var shuffle = 1
 		var epochs = 500
 		var folds = 3
-		var patterns, _, mapped = neural.LoadPatternsFromCSVFile(filePath)
+		var dataset, errorValue = neural.LoadDataset(filePath, neural.LoadPatternsFromCSVFile)
+		if errorValue != nil {
+			log.WithFields(log.Fields{
+				"level": "error",
+				"place": "main",
+				"error": errorValue,
+			}).Error("Failed to load dataset.")
+			return
+		}
 
 		//input  layer : 4 neuron, represents the feature of Iris, more in general dimensions of pattern
 		//hidden layer : 3 neuron, activation using sigmoid, number of neuron in hidden level
 		// 2° hidden l : * neuron, insert number of level you want
 		//output layer : 3 neuron, represents the class of Iris, more in general dimensions of mapped values
-		var layers = []int{len(patterns[0].Features), 20, len(mapped)}
+		var layers = []int{len(dataset.Patterns[0].Features), 20, len(dataset.Classes)}
 
 		var mlp = neural.PrepareMLPNet(layers, learningRate, neural.SigmoidTransfer, neural.SigmoidTransferDerivative)
-		var scores = validation.MLPKFoldValidation(&mlp, patterns, epochs, folds, shuffle, mapped)
-		var mlp2 = neural.PrepareMLPNet(layers, learningRate, neural.SigmoidTransfer, neural.SigmoidTransferDerivative)
-		var scores2 = validation.MLPRandomSubsamplingValidation(&mlp2, patterns, percentage, epochs, folds, shuffle, mapped)
+		// iris features go up to about 7.9, scale them in [0, 1] with min and max of each training fold
+		mlp.Scaler = neural.NewScaler(neural.MinMaxScaling)
+		scores, _, _, errorValue := validation.KFoldValidation(&mlp, dataset, epochs, folds, shuffle)
+		if errorValue != nil {
+			log.WithFields(log.Fields{
+				"level": "error",
+				"place": "main",
+				"error": errorValue,
+			}).Error("Failed to validate model.")
+			return
+		}
+		scores2, _, _, errorValue := validation.RandomSubsamplingValidation(&mlp, dataset, percentage, epochs, folds, shuffle)
+		if errorValue != nil {
+			log.WithFields(log.Fields{
+				"level": "error",
+				"place": "main",
+				"error": errorValue,
+			}).Error("Failed to validate model.")
+			return
+		}
 
 		log.WithFields(log.Fields{
 			"level":  "info",
@@ -101,15 +152,120 @@ func main() {
 		// 2° hidden l : * neuron, insert number of level you want
 		//output layer : 3 neuron, represents the class of Iris, more in general dimensions of mapped value
 		//Multilayer perceptron model, with one hidden layer.
-		var mlp = neural.PrepareElmanNet(len(patterns[0].Features)+10,
-			10, len(patterns[0].MultipleExpectation), learningRate,
-			neural.SigmoidTransfer, neural.SigmoidTransferDerivative)
+		var elman = neural.PrepareElmanNet(len(patterns[0].Features),
+			10, len(patterns[0].MultipleExpectation), learningRate, "sigmoid")
+		// errors flow back through all the 30 patterns, clip them to keep weights from diverging
+		elman.GradientClip = 1.0
 
-		var mean, _ = validation.RNNValidation(&mlp, patterns, epochs)
+		var mean, _, errorValue = validation.RNNValidation(&elman, patterns, epochs)
+		if errorValue != nil {
+			log.WithFields(log.Fields{
+				"level": "error",
+				"place": "main",
+				"error": errorValue,
+			}).Error("Failed to validate model.")
+			return
+		}
 		log.WithFields(log.Fields{
 			"level":     "info",
 			"place":     "main",
 			"precision": util.Round(mean, .5, 2),
 		}).Info("Scores reached: ", util.Round(mean, .5, 2))
 	}
+	if true {
+
+		log.WithFields(log.Fields{
+			"level": "info",
+			"place": "main",
+			"msg":   "elman, jordan and stacked recurrent networks train and test over binary addition sequences",
+		}).Info("Compare recurrent networks on binary addition of 6 bit numbers")
+
+		var learningRate = 0.5
+		var epochs = 100
+		var train = neural.CreateBinaryAdditionSequences(6, 200)
+		var test = neural.CreateBinaryAdditionSequences(6, 50)
+
+		var elman = neural.PrepareElmanNet(2, 10, 1, learningRate, "tanh")
+		var jordan = neural.PrepareJordanNet(2, 10, 1, learningRate, "tanh")
+		var stacked = neural.PrepareStackedNet([]int{2, 8, 8, 1}, learningRate, "tanh")
+		// batches of 10 sequences, gradients clipped to keep weights from diverging
+		elman.BatchSize, jordan.BatchSize, stacked.BatchSize = 10, 10, 10
+		elman.GradientClip, jordan.GradientClip, stacked.GradientClip = 5.0, 5.0, 5.0
+		var models = map[string]neural.SequenceModel{"elman": &elman, "jordan": &jordan, "stacked": &stacked}
+		for _, name := range []string{"elman", "jordan", "stacked"} {
+			var mean, _, errorValue = validation.SequenceValidation(models[name], train, test, nil, epochs)
+			if errorValue != nil {
+				log.WithFields(log.Fields{
+					"level": "error",
+					"place": "main",
+					"error": errorValue,
+				}).Error("Failed to validate model.")
+				return
+			}
+			log.WithFields(log.Fields{
+				"level":     "info",
+				"place":     "main",
+				"network":   name,
+				"precision": util.Round(mean, .5, 2),
+			}).Info("Scores reached: ", util.Round(mean, .5, 2))
+		}
+	}
+	if true {
+
+		log.WithFields(log.Fields{
+			"level": "info",
+			"place": "main",
+			"msg":   "simple, lstm and gru recurrent networks train and test over long range sequence tasks",
+		}).Info("Compare recurrent layers on the adding problem and on delayed recall")
+
+		var learningRate = 0.5
+		var epochs = 60
+		var train = neural.CreateAddingProblemSequences(15, 500)
+		var test = neural.CreateAddingProblemSequences(15, 100)
+		var recallTrain = neural.CreateDelayedRecallSequences(4, 20, 500)
+		var recallTest = neural.CreateDelayedRecallSequences(4, 20, 100)
+		var symbols = []string{"a", "b", "c", "d"}
+
+		var networks = map[string]func(layer []int) neural.RecurrentNetwork{
+			"simple": func(layer []int) neural.RecurrentNetwork {
+				return neural.PrepareStackedNet(layer, learningRate, "tanh")
+			},
+			"lstm": func(layer []int) neural.RecurrentNetwork { return neural.PrepareLSTMNet(layer, learningRate, true) },
+			"gru":  func(layer []int) neural.RecurrentNetwork { return neural.PrepareGRUNet(layer, learningRate) },
+		}
+		for _, name := range []string{"simple", "lstm", "gru"} {
+			// the sum of marked values is a real number, read from a linear output
+			var adding = networks[name]([]int{2, 16, 1})
+			adding.OutputTransfer = "linear"
+			adding.BatchSize, adding.GradientClip = 20, 1.0
+			var score, errorValue = validation.SequenceRegressionValidation(&adding, train, test, epochs)
+			if errorValue != nil {
+				log.WithFields(log.Fields{
+					"level": "error",
+					"place": "main",
+					"error": errorValue,
+				}).Error("Failed to validate model.")
+				return
+			}
+
+			var recall = networks[name]([]int{5, 16, 4})
+			recall.BatchSize, recall.GradientClip = 10, 1.0
+			var mean float64
+			if mean, _, errorValue = validation.SequenceValidation(&recall, recallTrain, recallTest, symbols, epochs); errorValue != nil {
+				log.WithFields(log.Fields{
+					"level": "error",
+					"place": "main",
+					"error": errorValue,
+				}).Error("Failed to validate model.")
+				return
+			}
+			log.WithFields(log.Fields{
+				"level":     "info",
+				"place":     "main",
+				"network":   name,
+				"addingR2":  util.Round(score.R2, .5, 2),
+				"precision": util.Round(mean, .5, 2),
+			}).Info("Scores reached: ", util.Round(mean, .5, 2))
+		}
+	}
 }
@@ -0,0 +1,387 @@
+package neural
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// arffAttribute is an attribute declared in the header of an ARFF file.
+type arffAttribute struct {
+	name string
+	// numeric, integer, real, string, date or nominal
+	kind string
+	// declared values of nominal attributes
+	values []string
+}
+
+// LoadPatternsFromARFFFile load a Weka ARFF dataset into an array of Pattern.
+// See ReadPatternsFromARFF for options.
+func LoadPatternsFromARFFFile(filePath string, options CSVOptions) ([]Pattern, error, []string) {
+	return loadPatternsFile(filePath, "LoadPatternsFromARFFFile", loadingLog.With(options.Logging), func(r io.Reader) ([]Pattern, error, []string) {
+		return ReadPatternsFromARFF(r, options)
+	})
+}
+
+// ReadPatternsFromARFF reads a Weka ARFF dataset, dense or sparse, into an array of Pattern.
+// Attribute types come from the header: nominal features are categorical (so options need an Encoder),
+// string and date attributes are ignored and '?' is a missing value. The class attribute is selected
+// as a CSV target column (last attribute by default); a numeric class attribute makes it a regression dataset.
+// Classes and categories are mapped in their declared order.
+// It returns patterns and the class of each mapped value (nil for regression).
+func ReadPatternsFromARFF(r io.Reader, options CSVOptions) ([]Pattern, error, []string) {
+	var attributes []arffAttribute
+	var records [][]string
+	var lines []int
+	data := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "%") {
+			continue
+		}
+		if data {
+			record, errorValue := arffRecord(text, attributes)
+			if errorValue != nil {
+				return nil, fmt.Errorf("line %d: %w", line, errorValue), nil
+			}
+			records = append(records, record)
+			lines = append(lines, line)
+			continue
+		}
+
+		keyword := strings.ToLower(strings.Fields(text)[0])
+		switch keyword {
+		case "@relation":
+		case "@attribute":
+			attribute, errorValue := arffParseAttribute(strings.TrimSpace(text[len(keyword):]))
+			if errorValue != nil {
+				return nil, fmt.Errorf("line %d: %w", line, errorValue), nil
+			}
+			attributes = append(attributes, attribute)
+		case "@data":
+			if len(attributes) == 0 {
+				return nil, fmt.Errorf("line %d: @data without attributes", line), nil
+			}
+			data = true
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q in ARFF header", line, keyword), nil
+		}
+	}
+	if errorValue := scanner.Err(); errorValue != nil {
+		return nil, errorValue, nil
+	}
+
+	schema := &csvSchema{options: arffOptions(options, attributes), nominal: make(map[int][]string)}
+	schema.header = make([]string, len(attributes))
+	for index, attribute := range attributes {
+		schema.header[index] = attribute.name
+		if attribute.kind == "nominal" {
+			schema.nominal[index] = attribute.values
+		}
+	}
+	return schema.patterns(records, lines)
+}
+
+// arffOptions completes options with the types of attributes: nominal features are categorical,
+// string and date attributes are ignored, '?' is missing and a numeric class attribute means regression.
+func arffOptions(options CSVOptions, attributes []arffAttribute) CSVOptions {
+	options.Header = true
+	options.DetectCategorical = false
+	options.MissingValues = append(append([]string{}, options.MissingValues...), "?")
+
+	targets := map[int]bool{}
+	for _, name := range options.TargetNames {
+		for index, attribute := range attributes {
+			if attribute.name == name {
+				targets[index] = true
+			}
+		}
+	}
+	if len(options.TargetNames) == 0 {
+		for _, index := range options.TargetColumns {
+			if index < 0 {
+				index = len(attributes) + index
+			}
+			targets[index] = true
+		}
+		if len(options.TargetColumns) == 0 {
+			targets[len(attributes)-1] = true
+		}
+	}
+
+	types := make(map[int]ColumnType)
+	for index, columnType := range options.ColumnTypes {
+		types[index] = columnType
+	}
+	options.IgnoreColumns = append([]int{}, options.IgnoreColumns...)
+	numericTarget := len(targets) > 0
+	for index, attribute := range attributes {
+		if targets[index] {
+			numericTarget = numericTarget && attribute.kind != "nominal" && attribute.kind != "string"
+			continue
+		}
+		switch attribute.kind {
+		case "nominal":
+			if _, declared := types[index]; !declared {
+				types[index] = CategoricalColumn
+			}
+		case "string", "date":
+			options.IgnoreColumns = append(options.IgnoreColumns, index)
+		}
+	}
+	options.ColumnTypes = types
+	options.Regression = options.Regression || numericTarget
+	return options
+}
+
+// arffParseAttribute parses the name and the type of an @attribute declaration.
+func arffParseAttribute(declaration string) (arffAttribute, error) {
+	var attribute arffAttribute
+	name, rest, errorValue := arffNextToken(declaration, " \t")
+	if errorValue != nil || name == "" {
+		return attribute, fmt.Errorf("invalid attribute declaration %q", declaration)
+	}
+	attribute.name = name
+	rest = strings.TrimSpace(rest)
+
+	if strings.HasPrefix(rest, "{") {
+		end := strings.LastIndex(rest, "}")
+		if end < 0 {
+			return attribute, fmt.Errorf("attribute %s: unterminated nominal values", name)
+		}
+		attribute.kind = "nominal"
+		values, errorValue := arffSplit(rest[1:end])
+		if errorValue != nil {
+			return attribute, fmt.Errorf("attribute %s: %w", name, errorValue)
+		}
+		attribute.values = values
+		return attribute, nil
+	}
+
+	fields := strings.Fields(strings.ToLower(rest))
+	if len(fields) == 0 {
+		return attribute, fmt.Errorf("attribute %s: missing type", name)
+	}
+	switch fields[0] {
+	case "numeric", "real", "integer", "string", "date":
+		attribute.kind = fields[0]
+	default:
+		return attribute, fmt.Errorf("attribute %s: unsupported type %q", name, fields[0])
+	}
+	return attribute, nil
+}
+
+// arffRecord converts a dense or sparse data line into a record with a field for each attribute.
+// Values omitted by sparse lines are 0, or the first declared value for nominal attributes.
+func arffRecord(text string, attributes []arffAttribute) ([]string, error) {
+	if !strings.HasPrefix(text, "{") {
+		record, errorValue := arffSplit(text)
+		if errorValue == nil && len(record) != len(attributes) {
+			errorValue = fmt.Errorf("expected %d values, got %d", len(attributes), len(record))
+		}
+		return record, errorValue
+	}
+
+	end := strings.LastIndex(text, "}")
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated sparse instance")
+	}
+	record := make([]string, len(attributes))
+	for index, attribute := range attributes {
+		record[index] = "0"
+		if attribute.kind == "nominal" && len(attribute.values) > 0 {
+			record[index] = attribute.values[0]
+		}
+	}
+	entries, errorValue := arffSplit(text[1:end])
+	if errorValue != nil {
+		return nil, errorValue
+	}
+	for _, entry := range entries {
+		position, value, errorValue := arffNextToken(entry, " \t")
+		if errorValue != nil {
+			return nil, errorValue
+		}
+		index, errorValue := strconv.Atoi(position)
+		if errorValue != nil || index < 0 || index >= len(attributes) {
+			return nil, fmt.Errorf("invalid sparse index in %q", entry)
+		}
+		value, _, errorValue = arffNextToken(strings.TrimSpace(value), "")
+		if errorValue != nil {
+			return nil, errorValue
+		}
+		record[index] = value
+	}
+	return record, nil
+}
+
+// arffSplit splits comma separated values, removing quotes.
+func arffSplit(text string) ([]string, error) {
+	var values []string
+	if strings.TrimSpace(text) == "" {
+		return values, nil
+	}
+	for {
+		value, rest, errorValue := arffNextToken(strings.TrimSpace(text), ",")
+		if errorValue != nil {
+			return nil, errorValue
+		}
+		values = append(values, value)
+		rest = strings.TrimSpace(rest)
+		if !strings.HasPrefix(rest, ",") {
+			if rest != "" {
+				return nil, fmt.Errorf("unexpected %q after value %q", rest, value)
+			}
+			return values, nil
+		}
+		text = rest[1:]
+	}
+}
+
+// arffNextToken reads a value, single or double quoted, or unquoted up to one of separators.
+// It returns the unquoted value and the text after it.
+func arffNextToken(text string, separators string) (string, string, error) {
+	if text == "" || (text[0] != '\'' && text[0] != '"') {
+		end := len(text)
+		if separators != "" {
+			if index := strings.IndexAny(text, separators); index >= 0 {
+				end = index
+			}
+		}
+		return strings.TrimSpace(text[:end]), text[end:], nil
+	}
+
+	quote := text[0]
+	var value strings.Builder
+	for index := 1; index < len(text); index++ {
+		switch text[index] {
+		case '\\':
+			if index+1 < len(text) {
+				index++
+				value.WriteByte(text[index])
+			}
+		case quote:
+			return value.String(), text[index+1:], nil
+		default:
+			value.WriteByte(text[index])
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted value %q", text)
+}
+
+// WritePatternsToARFF writes patterns as a Weka ARFF dataset named relation.
+// Features are numeric attributes, categorical features are nominal attributes with the columns of encoder
+// (if not nil) and the class is a nominal attribute with values mapped, or numeric targets if mapped is nil.
+// Missing values are written as '?'.
+// It returns an error wrapping ErrEmptyDataset without patterns and a ClassError for classes not in mapped.
+func WritePatternsToARFF(w io.Writer, relation string, patterns []Pattern, mapped []string, encoder *Encoder) error {
+	if len(patterns) == 0 {
+		return emptyDatasetError("WritePatternsToARFF")
+	}
+	writer := bufio.NewWriter(w)
+	columns := writtenCategoricalColumns(patterns, encoder)
+	targets := len(patterns[0].MultipleExpectation)
+	if targets == 0 {
+		targets = 1
+	}
+
+	fmt.Fprintf(writer, "@relation %s\n\n", arffQuote(relation))
+	for f := range patterns[0].Features {
+		fmt.Fprintf(writer, "@attribute feature%d numeric\n", f+1)
+	}
+	for _, column := range columns {
+		quoted := make([]string, len(column.Categories))
+		for k, category := range column.Categories {
+			quoted[k] = arffQuote(category)
+		}
+		fmt.Fprintf(writer, "@attribute %s {%s}\n", arffQuote(column.Name), strings.Join(quoted, ","))
+	}
+	if mapped != nil {
+		quoted := make([]string, len(mapped))
+		for k, class := range mapped {
+			quoted[k] = arffQuote(class)
+		}
+		fmt.Fprintf(writer, "@attribute class {%s}\n", strings.Join(quoted, ","))
+	} else {
+		for t := 0; t < targets; t++ {
+			fmt.Fprintf(writer, "@attribute target%d numeric\n", t+1)
+		}
+	}
+
+	writer.WriteString("\n@data\n")
+	for index, pattern := range patterns {
+		var values []string
+		for _, value := range pattern.Features {
+			values = append(values, arffValue(value))
+		}
+		for c := range columns {
+			if category := categoryAt(&pattern, c); category != "" {
+				values = append(values, arffQuote(category))
+			} else {
+				values = append(values, "?")
+			}
+		}
+		if mapped != nil {
+			class := int(pattern.SingleExpectation)
+			if class < 0 || class >= len(mapped) {
+				return fmt.Errorf("pattern %d: %w", index, &ClassError{Class: pattern.SingleExpectation, Classes: len(mapped)})
+			}
+			values = append(values, arffQuote(mapped[class]))
+		} else if pattern.MultipleExpectation != nil {
+			for _, value := range pattern.MultipleExpectation {
+				values = append(values, arffValue(value))
+			}
+		} else {
+			values = append(values, arffValue(pattern.SingleExpectation))
+		}
+		writer.WriteString(strings.Join(values, ",") + "\n")
+	}
+	return writer.Flush()
+}
+
+// writtenCategoricalColumns returns the categorical columns of encoder or, if nil, columns named
+// category1, category2, ... Categories known by encoder are followed by the other ones found in patterns.
+func writtenCategoricalColumns(patterns []Pattern, encoder *Encoder) []EncodedColumn {
+	var columns []EncodedColumn
+	if encoder != nil {
+		columns = make([]EncodedColumn, len(encoder.Columns))
+		for c, column := range encoder.Columns {
+			columns[c] = EncodedColumn{Name: column.Name, Categories: append([]string{}, column.Categories...)}
+		}
+	} else {
+		columns = make([]EncodedColumn, len(patterns[0].Categories))
+		for c := range columns {
+			columns[c].Name = fmt.Sprintf("category%d", c+1)
+		}
+	}
+	for c := range columns {
+		for _, pattern := range patterns {
+			if category := categoryAt(&pattern, c); category != "" && categoryIndex(columns[c], category) < 0 {
+				columns[c].Categories = append(columns[c].Categories, category)
+			}
+		}
+	}
+	return columns
+}
+
+// arffValue formats a numeric value, '?' if missing.
+func arffValue(value float64) string {
+	if IsMissing(value) || math.IsInf(value, 0) {
+		return "?"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// arffQuote single quotes a value if it holds spaces or ARFF special characters.
+func arffQuote(value string) string {
+	if value != "" && value != "?" && !strings.ContainsAny(value, " \t,'\"%{}\\") {
+		return value
+	}
+	return "'" + strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(value) + "'"
+}
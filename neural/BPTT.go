@@ -0,0 +1,237 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"math"
+)
+
+// RecurrentTrain train a RecurrentNetwork for assisted learning with RecurrentTrainSequences,
+// feeding patterns in order as a single sequence, toward MultipleExpectation of each pattern.
+func RecurrentTrain(network *RecurrentNetwork, patterns []Pattern, epochs int) error {
+	return RecurrentTrainSequences(network, []Sequence{{Steps: patterns}}, nil, epochs)
+}
+
+// RecurrentTrainSequences train a RecurrentNetwork with backpropagation through time for passed number of epochs,
+// toward one-hot targets of the class of mapped each step belongs to, or toward MultipleExpectation
+// of each step if mapped is nil (steps without MultipleExpectation have no target). Masked steps are skipped.
+// Each sequence starts from ResetState. Errors flow back through the whole sequence, or through windows
+// of Truncation steps, updating weights at the end of each window while the state is carried over to the next one.
+// If BatchSize is greater than 1, batches of BatchSize sequences go through their windows side by side
+// and updates are averaged over the sequences of the batch (see BucketSequences).
+// Gradients with L2 norm greater than GradientClip are scaled down to GradientClip.
+// Within a window, errors go through every layer of the stack, through the state of each layer and,
+// with OutputContext, through outputs fed back to the following step.
+// It returns a ConfigError, an error wrapping ErrEmptyDataset, a ClassError or a ShapeError
+// if network and sequences don't fit together, leaving the network partially trained.
+func RecurrentTrainSequences(network *RecurrentNetwork, sequences []Sequence, mapped []string, epochs int) error {
+	if errorValue := checkRecurrentNet(network); errorValue != nil {
+		return errorValue
+	}
+	steps := 0
+	for s := range sequences {
+		steps += sequences[s].Length()
+	}
+	if steps == 0 {
+		return emptyDatasetError("RecurrentTrainSequences")
+	}
+	target := regressionTarget
+	if mapped != nil {
+		network.Classes = mapped
+		target = oneHotTarget(len(mapped))
+	}
+	batchSize := network.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	for epoch := 0; epoch < epochs; epoch++ {
+		deltaError, targets := 0.0, 0
+		for start := 0; start < len(sequences); start += batchSize {
+			end := start + batchSize
+			if end > len(sequences) {
+				end = len(sequences)
+			}
+			batchError, batchTargets, errorValue := recurrentBatch(network, sequences[start:end], target)
+			if errorValue != nil {
+				network.truncate()
+				return errorValue
+			}
+			deltaError += batchError
+			targets += batchTargets
+		}
+		network.Epoch++
+
+		if targets > 0 {
+			deltaError = deltaError / float64(targets)
+		}
+		network.log().WithFields(logging.Fields{
+			"level":  "info",
+			"place":  "validation",
+			"method": "RecurrentTrainSequences",
+			"epoch":  network.Epoch,
+			"loss":   deltaError,
+		}).Debug("Training epoch completed.")
+	}
+	network.ResetState()
+	return nil
+}
+
+// recurrentBatch runs backpropagation through time over the sequences of a batch, window by window,
+// averaging, clipping and applying the gradients of each window. It returns the sum of delta errors of steps with a target and their number.
+func recurrentBatch(network *RecurrentNetwork, batch []Sequence, target func(pattern *Pattern) ([]float64, error)) (float64, int, error) {
+	longest := 0
+	for s := range batch {
+		if len(batch[s].Steps) > longest {
+			longest = len(batch[s].Steps)
+		}
+	}
+	window := network.Truncation
+	if window <= 0 {
+		window = longest
+	}
+	// state reached by each sequence at the end of the previous window
+	states := make([][]float64, len(batch))
+	deltaError, targets := 0.0, 0
+	for start := 0; start < longest; start += window {
+		contributing := 0
+		for s := range batch {
+			sequence := &batch[s]
+			if start >= len(sequence.Steps) {
+				continue
+			}
+			if start == 0 {
+				network.ResetState()
+			} else if errorValue := network.SetState(states[s]); errorValue != nil {
+				return 0.0, 0, errorValue
+			}
+			end := start + window
+			if end > len(sequence.Steps) {
+				end = len(sequence.Steps)
+			}
+			if errorValue := recurrentForward(network, sequence, start, end, target); errorValue != nil {
+				return 0.0, 0, errorValue
+			}
+			states[s] = network.State()
+			if len(network.history) == 0 {
+				continue
+			}
+
+			windowError, windowTargets, errorValue := recurrentWindow(network)
+			if errorValue != nil {
+				return 0.0, 0, errorValue
+			}
+			deltaError += windowError
+			targets += windowTargets
+			contributing++
+		}
+		if contributing > 0 {
+			parameters, gradients := network.parameters()
+			scaleRows(gradients, 1.0/float64(contributing))
+			clipRows(gradients, network.GradientClip)
+			for i, row := range parameters {
+				for k := range row {
+					row[k] += network.LearningRate * (gradients[i][k] - network.Regularization*row[k])
+					gradients[i][k] = 0.0
+				}
+			}
+		}
+	}
+	return deltaError, targets, nil
+}
+
+// recurrentForward executes the steps of sequence in [start, end) holding data, recording them with their targets.
+func recurrentForward(network *RecurrentNetwork, sequence *Sequence, start int, end int, target func(pattern *Pattern) ([]float64, error)) error {
+	network.truncate()
+	last := sequence.lastStep()
+	for t := start; t < end; t++ {
+		if sequence.Masked(t) {
+			continue
+		}
+		if _, errorValue := network.step(&sequence.Steps[t], true); errorValue != nil {
+			return errorValue
+		}
+		if !sequence.FinalTarget || t == last {
+			expected, errorValue := target(&sequence.Steps[t])
+			if errorValue != nil {
+				return errorValue
+			}
+			// targets may share their slice between steps
+			network.history[len(network.history)-1].expected = append([]float64(nil), expected...)
+		}
+	}
+	return nil
+}
+
+// recurrentWindow propagates backward the error signal of recorded steps, from the last one to the first one,
+// through output neurons and recurrent layers, accumulating gradients in the network and in its layers.
+// Error signals and delta errors of outputs follow LossFunctionGradient and LossFunction, as in a multi layer network.
+// It returns the sum of delta errors of steps with a target and their number.
+func recurrentWindow(network *RecurrentNetwork) (float64, int, error) {
+	network.parameters()
+	_, otfd, _ := TransferFunctionByName(network.OutputTransfer)
+	features := network.Features()
+	deltaError, targets := 0.0, 0
+	// error signal reaching the outputs of a step from the following step, through fed back outputs
+	var future []float64
+	for t := len(network.history) - 1; t >= 0; t-- {
+		step := network.history[t]
+		signal := make([]float64, len(step.output))
+		if step.expected != nil {
+			stepError := 0.0
+			var errorValue error
+			if signal, stepError, errorValue = outputErrors(network.LossFunction, network.LossFunctionGradient, step.output, step.expected); errorValue != nil {
+				return 0.0, 0, errorValue
+			}
+			deltaError += stepError
+			targets++
+		}
+		for j := range signal {
+			if future != nil {
+				signal[j] += future[j]
+			}
+			signal[j] *= otfd(step.output[j])
+		}
+
+		delta := make([]float64, len(step.hidden))
+		for j, s := range signal {
+			for k, value := range step.hidden {
+				network.outputGradients[j][k] += s * value
+				delta[k] += s * network.OutputWeights[j][k]
+			}
+			network.biasGradients[j] += s
+		}
+		for i := len(network.Layers) - 1; i >= 0; i-- {
+			delta = network.Layers[i].Backward(delta)
+		}
+		if network.OutputContext {
+			future = delta[features:]
+		}
+	}
+	network.truncate()
+	return deltaError, targets, nil
+}
+
+// scaleRows multiplies every value of rows by scale.
+func scaleRows(rows [][]float64, scale float64) {
+	for _, row := range rows {
+		for k := range row {
+			row[k] *= scale
+		}
+	}
+}
+
+// clipRows scales down rows so that their L2 norm is at most maxNorm (no clipping if maxNorm is 0).
+func clipRows(rows [][]float64, maxNorm float64) {
+	if maxNorm <= 0.0 {
+		return
+	}
+	squares := 0.0
+	for _, row := range rows {
+		for _, value := range row {
+			squares += value * value
+		}
+	}
+	if norm := math.Sqrt(squares); norm > maxNorm {
+		scaleRows(rows, maxNorm/norm)
+	}
+}
@@ -0,0 +1,539 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/util"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type ColumnType string
+
+const (
+	// FloatColumn holds float64 values (default type of feature columns)
+	FloatColumn ColumnType = "float"
+	// IntegerColumn holds integer values
+	IntegerColumn ColumnType = "int"
+	// CategoricalColumn holds labels stored in Pattern.Categories and encoded by the Encoder of the model
+	CategoricalColumn ColumnType = "categorical"
+)
+
+type CSVOptions struct {
+	// first row holds column names
+	Header bool
+	// field delimiter (',' if zero)
+	Delimiter rune
+	// lines beginning with this character are skipped (disabled if zero)
+	Comment rune
+	// a quote may appear in an unquoted field and a non-doubled quote may appear in a quoted field
+	LazyQuotes bool
+	// indexes of target columns, negative indexes count from the end (last column if empty)
+	TargetColumns []int
+	// names of target columns, used instead of TargetColumns (requires Header)
+	TargetNames []string
+	// indexes of ignored columns, negative indexes count from the end
+	IgnoreColumns []int
+	// names of ignored columns (requires Header)
+	IgnoreNames []string
+	// type of feature columns by index (FloatColumn if not listed)
+	ColumnTypes map[int]ColumnType
+	// type of feature columns by name (requires Header)
+	ColumnTypesByName map[string]ColumnType
+	// targets are continuous values stored in MultipleExpectation instead of a class label
+	Regression bool
+	// feature columns without a declared type holding any non numeric value are categorical
+	DetectCategorical bool
+	// encoder of categorical columns, filled with their names and categories (required if any column is categorical)
+	Encoder *Encoder
+	// fields (after trimming spaces) holding a missing value, such as "", "?" or "NA" (none if empty)
+	MissingValues []string
+	// imputer of missing numeric features, filled with the features which can be missing (required if any is missing)
+	Imputer *Imputer
+	// logger and levels of loading, instead of the ones set by logging.Configure (nil to use them)
+	Logging *logging.Options
+}
+
+// csvSchema holds the role of each column of a CSV file, resolved from options and header.
+type csvSchema struct {
+	options CSVOptions
+	header  []string
+	columns int
+	// target column indexes
+	targets []int
+	// numeric feature column indexes
+	features []int
+	// categorical feature column indexes
+	categorical []int
+	// type of each column
+	types []ColumnType
+	// whether the type of each column was set by options
+	declared []bool
+	// classes in order of first appearance
+	classes []string
+	// name and categories of each categorical column: the declared ones, then the others in order of first appearance
+	vocabulary []EncodedColumn
+	// whether each numeric feature has missing values
+	missing []bool
+	// declared categories of columns by index, kept first in order (such as ARFF nominal attributes)
+	nominal map[int][]string
+}
+
+// LoadPatternsFromCSVFileWithOptions load a CSV dataset into an array of Pattern as described by options.
+// Any value that can't be parsed fails loading with its line and column number.
+// It returns patterns and the class of each mapped value (nil for regression).
+func LoadPatternsFromCSVFileWithOptions(filePath string, options CSVOptions) ([]Pattern, error, []string) {
+	return loadPatternsFile(filePath, "LoadPatternsFromCSVFileWithOptions", loadingLog.With(options.Logging), func(r io.Reader) ([]Pattern, error, []string) {
+		return ReadPatternsFromCSV(r, options)
+	})
+}
+
+// loadPatternsFile opens the file at filePath and reads patterns from it with read, logging the outcome as method on log.
+func loadPatternsFile(filePath string, method string, log logging.Channel, read func(r io.Reader) ([]Pattern, error, []string)) ([]Pattern, error, []string) {
+	file, errorValue := os.Open(filePath)
+	if errorValue != nil {
+		log.WithFields(logging.Fields{
+			"level":      "error",
+			"place":      "patterns",
+			"method":     method,
+			"msg":        "reading file in specific path",
+			"filePath":   filePath,
+			"errorValue": errorValue,
+		}).Error("Failed to read file in specified path.")
+		return nil, errorValue, nil
+	}
+	defer file.Close()
+
+	patterns, errorValue, mapped := read(file)
+	if errorValue != nil {
+		log.WithFields(logging.Fields{
+			"level":      "error",
+			"place":      "patterns",
+			"method":     method,
+			"msg":        "parsing file in specific path",
+			"filePath":   filePath,
+			"errorValue": errorValue,
+		}).Error("Failed to parse file.")
+		return patterns, fmt.Errorf("%s: %w", filePath, errorValue), mapped
+	}
+
+	log.WithFields(logging.Fields{
+		"level":    "info",
+		"place":    "patterns",
+		"method":   method,
+		"readData": len(patterns),
+		"msg":      "File reading completed.",
+	}).Info("File reading completed.")
+
+	return patterns, nil, mapped
+}
+
+// ReadPatternsFromCSV reads a CSV dataset from r into an array of Pattern as described by options.
+// It returns patterns and the class of each mapped value (nil for regression).
+func ReadPatternsFromCSV(r io.Reader, options CSVOptions) ([]Pattern, error, []string) {
+	return readCSV(r, &csvSchema{options: options})
+}
+
+// readCSV reads a CSV dataset from r into an array of Pattern, resolving columns in schema.
+func readCSV(r io.Reader, schema *csvSchema) ([]Pattern, error, []string) {
+	records, lines, errorValue := readRecords(r, schema)
+	if errorValue != nil {
+		return nil, errorValue, nil
+	}
+	return schema.patterns(records, lines)
+}
+
+// readRecords reads the records of a CSV file from r and the line of each of them, storing the header in schema.
+func readRecords(r io.Reader, schema *csvSchema) ([][]string, []int, error) {
+	var records [][]string
+	var lines []int
+	options := schema.options
+	reader := newCSVReader(r, options)
+
+	for {
+		record, errorValue := reader.Read()
+		if errorValue == io.EOF {
+			break
+		}
+		if errorValue != nil {
+			return nil, nil, errorValue
+		}
+		line, _ := reader.FieldPos(0)
+		if schema.header == nil && options.Header {
+			schema.header = record
+			continue
+		}
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return records, lines, nil
+}
+
+// patterns converts records, read at lines, into an array of Pattern.
+// It returns patterns and the class of each mapped value (nil for regression).
+func (schema *csvSchema) patterns(records [][]string, lines []int) ([]Pattern, error, []string) {
+	var patterns []Pattern
+	if len(records) == 0 {
+		return patterns, nil, nil
+	}
+
+	if errorValue := schema.resolve(len(records[0])); errorValue != nil {
+		return patterns, errorValue, nil
+	}
+	if schema.options.DetectCategorical {
+		schema.detectCategorical(records)
+	}
+	if errorValue := schema.split(); errorValue != nil {
+		return patterns, errorValue, nil
+	}
+	for index, record := range records {
+		pattern, errorValue := schema.parse(record, lines[index])
+		if errorValue != nil {
+			return patterns, errorValue, nil
+		}
+		schema.collect(&pattern)
+		patterns = append(patterns, pattern)
+	}
+	if errorValue := schema.finish(); errorValue != nil {
+		return patterns, errorValue, nil
+	}
+	for index := range patterns {
+		schema.label(&patterns[index])
+	}
+
+	loadingLog.With(schema.options.Logging).WithFields(logging.Fields{
+		"level":             "info",
+		"place":             "patterns",
+		"msg":               "raw class extraction completed",
+		"numberOfRawUnique": len(schema.classes),
+	}).Info("Complete SingleRawExpectation value set filling.")
+
+	return patterns, nil, schema.classes
+}
+
+// newCSVReader creates a csv.Reader configured by options.
+func newCSVReader(r io.Reader, options CSVOptions) *csv.Reader {
+	reader := csv.NewReader(r)
+	if options.Delimiter != 0 {
+		reader.Comma = options.Delimiter
+	}
+	reader.Comment = options.Comment
+	reader.LazyQuotes = options.LazyQuotes
+	return reader
+}
+
+// resolve assigns a role and a type to each of the columns of the file.
+func (schema *csvSchema) resolve(columns int) error {
+	schema.columns = columns
+	schema.types = make([]ColumnType, columns)
+	schema.declared = make([]bool, columns)
+	for index := range schema.types {
+		schema.types[index] = FloatColumn
+	}
+
+	role := make([]string, columns)
+	targets := schema.options.TargetColumns
+	if len(schema.options.TargetNames) > 0 {
+		indexes, errorValue := schema.columnsByName(schema.options.TargetNames)
+		if errorValue != nil {
+			return errorValue
+		}
+		targets = indexes
+	}
+	if len(targets) == 0 {
+		targets = []int{-1}
+	}
+	for _, index := range targets {
+		index, errorValue := schema.columnIndex(index)
+		if errorValue != nil {
+			return errorValue
+		}
+		role[index] = "target"
+		schema.targets = append(schema.targets, index)
+	}
+	if !schema.options.Regression && len(schema.targets) != 1 {
+		return fmt.Errorf("classification needs exactly one target column, got %d", len(schema.targets))
+	}
+
+	ignored := schema.options.IgnoreColumns
+	if len(schema.options.IgnoreNames) > 0 {
+		indexes, errorValue := schema.columnsByName(schema.options.IgnoreNames)
+		if errorValue != nil {
+			return errorValue
+		}
+		ignored = append(append([]int{}, ignored...), indexes...)
+	}
+	for _, index := range ignored {
+		index, errorValue := schema.columnIndex(index)
+		if errorValue != nil {
+			return errorValue
+		}
+		if role[index] == "target" {
+			return fmt.Errorf("column %d is both a target and ignored", index+1)
+		}
+		role[index] = "ignored"
+	}
+
+	for index, columnType := range schema.options.ColumnTypes {
+		index, errorValue := schema.columnIndex(index)
+		if errorValue != nil {
+			return errorValue
+		}
+		schema.types[index], schema.declared[index] = columnType, true
+	}
+	for name, columnType := range schema.options.ColumnTypesByName {
+		indexes, errorValue := schema.columnsByName([]string{name})
+		if errorValue != nil {
+			return errorValue
+		}
+		schema.types[indexes[0]], schema.declared[indexes[0]] = columnType, true
+	}
+	for index, columnType := range schema.types {
+		if columnType != FloatColumn && columnType != IntegerColumn && columnType != CategoricalColumn {
+			return fmt.Errorf("column %d: unknown column type %q", index+1, columnType)
+		}
+	}
+
+	for index := 0; index < columns; index++ {
+		if role[index] == "" {
+			schema.features = append(schema.features, index)
+		}
+	}
+	if len(schema.features) == 0 {
+		return fmt.Errorf("no feature columns left among %d columns", columns)
+	}
+	return nil
+}
+
+// detectCategorical marks as categorical the feature columns without a declared type
+// holding any value that can't be parsed as float64.
+func (schema *csvSchema) detectCategorical(records [][]string) {
+	for _, index := range schema.features {
+		if schema.declared[index] {
+			continue
+		}
+		for _, record := range records {
+			if index >= len(record) {
+				continue
+			}
+			if schema.isMissing(record[index]) {
+				continue
+			}
+			if _, errorValue := schema.parseValue(record[index], FloatColumn); errorValue != nil {
+				schema.types[index] = CategoricalColumn
+				break
+			}
+		}
+	}
+}
+
+// split separates categorical feature columns from numeric ones.
+// It returns an error if there are categorical columns but no Encoder in options.
+func (schema *csvSchema) split() error {
+	var numeric []int
+	for _, index := range schema.features {
+		if schema.types[index] == CategoricalColumn {
+			schema.categorical = append(schema.categorical, index)
+		} else {
+			numeric = append(numeric, index)
+		}
+	}
+	schema.features = numeric
+
+	schema.missing = make([]bool, len(schema.features))
+	schema.vocabulary = make([]EncodedColumn, len(schema.categorical))
+	for c, index := range schema.categorical {
+		schema.vocabulary[c].Name = schema.columnName(index)
+		schema.vocabulary[c].Categories = append([]string{}, schema.nominal[index]...)
+	}
+	if !schema.options.Regression {
+		schema.classes = append([]string{}, schema.nominal[schema.targets[0]]...)
+	}
+	if len(schema.categorical) > 0 && schema.options.Encoder == nil {
+		return fmt.Errorf("%s is categorical but no Encoder is set in options", schema.describeColumn(schema.categorical[0]))
+	}
+	return nil
+}
+
+// collect updates classes, categories and missing features seen so far with pattern.
+// Categories only fix the vocabulary of the encoder, FitEncoder finds which of them each training set holds.
+func (schema *csvSchema) collect(pattern *Pattern) {
+	if !schema.options.Regression {
+		if found, _ := util.StringInSlice(pattern.SingleRawExpectation, schema.classes); !found {
+			schema.classes = append(schema.classes, pattern.SingleRawExpectation)
+		}
+	}
+	for c, category := range pattern.Categories {
+		if category != "" && categoryIndex(schema.vocabulary[c], category) < 0 {
+			schema.vocabulary[c].Categories = append(schema.vocabulary[c].Categories, category)
+		}
+	}
+	for f, value := range pattern.Features {
+		schema.missing[f] = schema.missing[f] || IsMissing(value)
+	}
+}
+
+// finish stores categorical columns, with their categories, in the Encoder of options
+// and features with missing values in the Imputer of options.
+// It returns an error if there are missing values but no Imputer in options.
+func (schema *csvSchema) finish() error {
+	var missing []int
+	for f, index := range schema.features {
+		if f < len(schema.missing) && schema.missing[f] {
+			if schema.options.Imputer == nil {
+				return fmt.Errorf("%s has missing values but no Imputer is set in options", schema.describeColumn(index))
+			}
+			missing = append(missing, f)
+		}
+	}
+	if schema.options.Imputer != nil {
+		schema.options.Imputer.MissingFeatures = missing
+	}
+
+	if encoder := schema.options.Encoder; encoder != nil && len(schema.categorical) > 0 {
+		encoder.Columns = schema.vocabulary
+		encoder.Regression = schema.options.Regression
+		encoder.Targets = len(schema.classes)
+		if encoder.Regression {
+			encoder.Targets = len(schema.targets)
+		}
+	}
+	return nil
+}
+
+// label sets the class index of pattern from the classes collected by the schema.
+func (schema *csvSchema) label(pattern *Pattern) {
+	for index, class := range schema.classes {
+		if class == pattern.SingleRawExpectation {
+			pattern.SingleExpectation = float64(index)
+			return
+		}
+	}
+}
+
+// isMissing tells whether field is one of the missing value tokens of options.
+func (schema *csvSchema) isMissing(field string) bool {
+	field = strings.TrimSpace(field)
+	for _, token := range schema.options.MissingValues {
+		if field == token {
+			return true
+		}
+	}
+	return false
+}
+
+// columnName returns the header name of column index, or "column N" without header.
+func (schema *csvSchema) columnName(index int) string {
+	if schema.header != nil && index < len(schema.header) {
+		return strings.TrimSpace(schema.header[index])
+	}
+	return fmt.Sprintf("column %d", index+1)
+}
+
+// parse converts a CSV record read at line into a Pattern.
+func (schema *csvSchema) parse(record []string, line int) (Pattern, error) {
+	var pattern Pattern
+	if len(record) != schema.columns {
+		return pattern, fmt.Errorf("line %d: expected %d columns, got %d", line, schema.columns, len(record))
+	}
+
+	pattern.Features = make([]float64, len(schema.features))
+	for f, index := range schema.features {
+		if schema.isMissing(record[index]) {
+			pattern.Features[f] = math.NaN()
+			continue
+		}
+		value, errorValue := schema.parseValue(record[index], schema.types[index])
+		if errorValue != nil {
+			return pattern, schema.valueError(line, index, record[index], errorValue)
+		}
+		pattern.Features[f] = value
+	}
+	for _, index := range schema.categorical {
+		category := strings.TrimSpace(record[index])
+		if schema.isMissing(category) {
+			category = ""
+		}
+		pattern.Categories = append(pattern.Categories, category)
+	}
+
+	for _, index := range schema.targets {
+		if schema.isMissing(record[index]) {
+			return pattern, fmt.Errorf("line %d, %s: missing target value", line, schema.describeColumn(index))
+		}
+	}
+	if schema.options.Regression {
+		pattern.MultipleExpectation = make([]float64, len(schema.targets))
+		for t, index := range schema.targets {
+			value, errorValue := schema.parseValue(record[index], FloatColumn)
+			if errorValue != nil {
+				return pattern, schema.valueError(line, index, record[index], errorValue)
+			}
+			pattern.MultipleExpectation[t] = value
+		}
+		pattern.SingleExpectation = pattern.MultipleExpectation[0]
+	} else {
+		pattern.SingleRawExpectation = strings.TrimSpace(record[schema.targets[0]])
+	}
+	return pattern, nil
+}
+
+// parseValue converts a field to float64 according to columnType.
+func (schema *csvSchema) parseValue(field string, columnType ColumnType) (float64, error) {
+	field = strings.TrimSpace(field)
+	if columnType == IntegerColumn {
+		value, errorValue := strconv.ParseInt(field, 10, 64)
+		return float64(value), errorValue
+	}
+	return strconv.ParseFloat(field, 64)
+}
+
+// valueError describes a field that can't be parsed with its position.
+func (schema *csvSchema) valueError(line int, index int, field string, errorValue error) error {
+	return fmt.Errorf("line %d, %s: cannot parse %q as %s: %w", line, schema.describeColumn(index), field, schema.types[index], errorValue)
+}
+
+// describeColumn returns the position of column index, with its header name if any.
+func (schema *csvSchema) describeColumn(index int) string {
+	if schema.header != nil && index < len(schema.header) {
+		return fmt.Sprintf("column %d (%s)", index+1, strings.TrimSpace(schema.header[index]))
+	}
+	return fmt.Sprintf("column %d", index+1)
+}
+
+// columnIndex converts a possibly negative column index into a position in the record.
+func (schema *csvSchema) columnIndex(index int) (int, error) {
+	if index < 0 {
+		index = schema.columns + index
+	}
+	if index < 0 || index >= schema.columns {
+		return 0, fmt.Errorf("column index out of range [0, %d)", schema.columns)
+	}
+	return index, nil
+}
+
+// columnsByName finds the indexes of named columns in header.
+func (schema *csvSchema) columnsByName(names []string) ([]int, error) {
+	if schema.header == nil {
+		return nil, fmt.Errorf("columns selected by name %v but file has no header", names)
+	}
+	var indexes []int
+	for _, name := range names {
+		found := false
+		for index, column := range schema.header {
+			if strings.TrimSpace(column) == name {
+				indexes = append(indexes, index)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("column %q not found in header", name)
+		}
+	}
+	return indexes, nil
+}
@@ -0,0 +1,65 @@
+package neural
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadPatternsFromCSVErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		options CSVOptions
+		// substrings of the error, locating the faulty field
+		want []string
+	}{
+		{
+			name:    "float feature",
+			data:    "a,b,class\n1,2,x\n3,oops,y\n",
+			options: CSVOptions{Header: true},
+			want:    []string{"line 3", "column 2 (b)", `"oops"`, "float"},
+		},
+		{
+			name:    "integer feature without header",
+			data:    "1,2,x\n1.5,2,y\n",
+			options: CSVOptions{ColumnTypes: map[int]ColumnType{0: IntegerColumn}},
+			want:    []string{"line 2", "column 1", `"1.5"`, "int"},
+		},
+		{
+			name:    "regression target",
+			data:    "a,y\n1,2\n2,3\n3,high\n",
+			options: CSVOptions{Header: true, Regression: true},
+			want:    []string{"line 4", "column 2 (y)", `"high"`},
+		},
+		{
+			name:    "missing target",
+			data:    "a,class\n1,x\n2,?\n",
+			options: CSVOptions{Header: true, MissingValues: []string{"?"}, Imputer: &Imputer{}},
+			want:    []string{"line 3", "column 2 (class)", "missing target"},
+		},
+		{
+			name:    "wrong number of fields",
+			data:    "1,2,x\n3,y\n",
+			options: CSVOptions{},
+			want:    []string{"line 2"},
+		},
+		{
+			name:    "unknown target name",
+			data:    "a,b\n1,x\n",
+			options: CSVOptions{Header: true, TargetNames: []string{"class"}},
+			want:    []string{`"class"`, "not found"},
+		},
+	}
+	for _, test := range tests {
+		_, errorValue, _ := ReadPatternsFromCSV(strings.NewReader(test.data), test.options)
+		if errorValue == nil {
+			t.Errorf("%s: no error", test.name)
+			continue
+		}
+		for _, want := range test.want {
+			if !strings.Contains(errorValue.Error(), want) {
+				t.Errorf("%s: error %q doesn't contain %q", test.name, errorValue, want)
+			}
+		}
+	}
+}
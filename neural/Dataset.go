@@ -0,0 +1,262 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/util"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Dataset holds patterns together with the metadata needed to map predictions back to labels.
+type Dataset struct {
+	// patterns, with SingleExpectation as index of Classes
+	Patterns []Pattern
+	// name of each value of Features ("column N" without header)
+	FeatureNames []string
+	// class labels sorted (numerically if all of them are numbers), so that indexes don't depend on row order
+	Classes []string
+	// whether patterns hold continuous targets in MultipleExpectation instead of classes
+	Regression bool
+	// path of the file patterns were loaded from ("" if not loaded from a file)
+	Source string
+	// SHA-256 of the source file, hex encoded
+	Hash string
+	// summary statistics of each value of Features
+	Stats []FeatureStats
+	// number of patterns of each class
+	ClassCounts []int
+	// unfitted imputer and encoder filled by the loader, to be set on models and fitted on their training patterns
+	// (nil if the file has no missing values or no categorical columns)
+	Imputer *Imputer
+	Encoder *Encoder
+}
+
+// FeatureStats holds summary statistics of a feature, computed on its non missing values (all 0 if every value is missing).
+type FeatureStats struct {
+	Min    float64
+	Max    float64
+	Mean   float64
+	StdDev float64
+	// number of missing (NaN) values
+	Missing int
+}
+
+// NewDataset creates a Dataset from patterns and the class of each mapped value (nil for regression).
+// Classes are sorted and SingleExpectation of patterns renumbered accordingly; patterns passed are not modified.
+// Feature names are feature1, feature2, ...
+// It returns a ClassError if a pattern has a class outside mapped.
+func NewDataset(patterns []Pattern, mapped []string) (*Dataset, error) {
+	if mapped != nil {
+		if errorValue := checkClasses(patterns, len(mapped)); errorValue != nil {
+			return nil, errorValue
+		}
+	}
+	dataset := &Dataset{Patterns: make([]Pattern, len(patterns)), Regression: mapped == nil}
+	copy(dataset.Patterns, patterns)
+	if len(patterns) > 0 {
+		dataset.FeatureNames = make([]string, len(patterns[0].Features))
+		for f := range dataset.FeatureNames {
+			dataset.FeatureNames[f] = fmt.Sprintf("feature%d", f+1)
+		}
+	}
+
+	if mapped != nil {
+		dataset.Classes = sortedClasses(mapped)
+		positions := make(map[string]float64, len(dataset.Classes))
+		for position, class := range dataset.Classes {
+			positions[class] = float64(position)
+		}
+		renumber := make([]float64, len(mapped))
+		for index, class := range mapped {
+			renumber[index] = positions[class]
+		}
+		for index := range dataset.Patterns {
+			dataset.Patterns[index].SingleExpectation = renumber[int(patterns[index].SingleExpectation)]
+		}
+	}
+	computeDatasetStats(dataset)
+	return dataset, nil
+}
+
+// SubsetDataset returns a Dataset with passed patterns and the metadata of dataset, such as a training fold.
+// Patterns must be numbered by the classes of dataset. Stats are computed on passed patterns.
+func SubsetDataset(dataset *Dataset, patterns []Pattern) *Dataset {
+	subset := *dataset
+	subset.Patterns = patterns
+	computeDatasetStats(&subset)
+	return &subset
+}
+
+// Inputs returns the number of features a model fed by Imputer and Encoder of dataset sees,
+// the size of its input layer. It doesn't depend on the patterns preprocessing is fitted on.
+func (dataset *Dataset) Inputs() int {
+	if len(dataset.Patterns) == 0 {
+		return 0
+	}
+	return PreprocessedSize(dataset.Imputer, dataset.Encoder, len(dataset.Patterns[0].Features))
+}
+
+// LoadDataset loads the file at filePath with load, such as LoadPatternsFromCSVFile, into a Dataset
+// with sorted classes and the hash of the file.
+func LoadDataset(filePath string, load func(filePath string) ([]Pattern, error, []string)) (*Dataset, error) {
+	patterns, errorValue, mapped := load(filePath)
+	if errorValue != nil {
+		return nil, errorValue
+	}
+	dataset, errorValue := NewDataset(patterns, mapped)
+	if errorValue != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, errorValue)
+	}
+	dataset.Source = filePath
+	if dataset.Hash, errorValue = hashFile(filePath); errorValue != nil {
+		return nil, errorValue
+	}
+	return dataset, nil
+}
+
+// LoadDatasetFromCSVFile load a CSV dataset into a Dataset as described by options,
+// with feature names taken from the header, and the Imputer and Encoder of options if the file needs them.
+func LoadDatasetFromCSVFile(filePath string, options CSVOptions) (*Dataset, error) {
+	var schema *csvSchema
+	hash := sha256.New()
+	patterns, errorValue, mapped := loadPatternsFile(filePath, "LoadDatasetFromCSVFile", loadingLog.With(options.Logging), func(r io.Reader) ([]Pattern, error, []string) {
+		schema = &csvSchema{options: options}
+		patterns, errorValue, mapped := readCSV(io.TeeReader(r, hash), schema)
+		if errorValue == nil {
+			_, errorValue = io.Copy(hash, r)
+		}
+		return patterns, errorValue, mapped
+	})
+	if errorValue != nil {
+		return nil, errorValue
+	}
+	if options.Regression {
+		mapped = nil
+	} else if mapped == nil {
+		mapped = []string{}
+	}
+
+	dataset, errorValue := NewDataset(patterns, mapped)
+	if errorValue != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, errorValue)
+	}
+	dataset.Source = filePath
+	dataset.Hash = hex.EncodeToString(hash.Sum(nil))
+	if len(patterns) > 0 {
+		dataset.FeatureNames = make([]string, len(schema.features))
+		for f, column := range schema.features {
+			dataset.FeatureNames[f] = schema.columnName(column)
+		}
+	}
+	if len(schema.categorical) > 0 {
+		dataset.Encoder = options.Encoder
+	}
+	if options.Imputer != nil && len(options.Imputer.MissingFeatures) > 0 {
+		dataset.Imputer = options.Imputer
+	}
+
+	loadingLog.WithFields(logging.Fields{
+		"level":    "info",
+		"place":    "patterns",
+		"method":   "LoadDatasetFromCSVFile",
+		"filePath": filePath,
+		"classes":  len(dataset.Classes),
+		"hash":     dataset.Hash,
+	}).Debug("Dataset loaded.")
+
+	return dataset, nil
+}
+
+// PredictClass executes the network on pattern and returns the label of the max output,
+// taken from the classes the network was trained on.
+// It returns the errors of Execute, or a ClassError if the network doesn't know the label of its output.
+func PredictClass(multiLayerPerceptron *MultiLayerNetwork, pattern *Pattern) (string, error) {
+	output, errorValue := Execute(multiLayerPerceptron, pattern)
+	if errorValue != nil {
+		return "", errorValue
+	}
+	_, indexMaxOut := util.MaxInSlice(output)
+	if indexMaxOut >= len(multiLayerPerceptron.Classes) {
+		return "", &ClassError{Class: float64(indexMaxOut), Classes: len(multiLayerPerceptron.Classes)}
+	}
+	return multiLayerPerceptron.Classes[indexMaxOut], nil
+}
+
+// sortedClasses returns a sorted copy of classes, in numeric order if all of them are numbers.
+func sortedClasses(classes []string) []string {
+	sorted := append([]string{}, classes...)
+	numbers := make(map[string]float64, len(sorted))
+	for _, class := range sorted {
+		number, errorValue := strconv.ParseFloat(class, 64)
+		if errorValue != nil {
+			sort.Strings(sorted)
+			return sorted
+		}
+		numbers[class] = number
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if numbers[sorted[i]] != numbers[sorted[j]] {
+			return numbers[sorted[i]] < numbers[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
+// computeDatasetStats fills Stats and ClassCounts of dataset from its patterns.
+func computeDatasetStats(dataset *Dataset) {
+	dataset.Stats, dataset.ClassCounts = nil, nil
+	if !dataset.Regression {
+		dataset.ClassCounts = make([]int, len(dataset.Classes))
+		for _, pattern := range dataset.Patterns {
+			if class := int(pattern.SingleExpectation); class >= 0 && class < len(dataset.ClassCounts) {
+				dataset.ClassCounts[class]++
+			}
+		}
+	}
+	if len(dataset.Patterns) == 0 {
+		return
+	}
+
+	dataset.Stats = make([]FeatureStats, len(dataset.Patterns[0].Features))
+	for f := range dataset.Stats {
+		stats := FeatureStats{Min: math.Inf(1), Max: math.Inf(-1)}
+		sum, squares, count := 0.0, 0.0, 0
+		for _, pattern := range dataset.Patterns {
+			if f >= len(pattern.Features) || IsMissing(pattern.Features[f]) {
+				stats.Missing++
+				continue
+			}
+			value := pattern.Features[f]
+			stats.Min, stats.Max = math.Min(stats.Min, value), math.Max(stats.Max, value)
+			sum, squares, count = sum+value, squares+value*value, count+1
+		}
+		if count > 0 {
+			stats.Mean = sum / float64(count)
+			stats.StdDev = math.Sqrt(math.Max(squares/float64(count)-stats.Mean*stats.Mean, 0.0))
+		} else {
+			stats.Min, stats.Max = 0.0, 0.0
+		}
+		dataset.Stats[f] = stats
+	}
+}
+
+// hashFile returns the SHA-256 of the file at filePath, hex encoded.
+func hashFile(filePath string) (string, error) {
+	file, errorValue := os.Open(filePath)
+	if errorValue != nil {
+		return "", errorValue
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, errorValue = io.Copy(hash, file); errorValue != nil {
+		return "", errorValue
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
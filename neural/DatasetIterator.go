@@ -0,0 +1,242 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+)
+
+// DatasetIterator streams the patterns of a dataset in chunks,
+// so that datasets larger than memory can be used for training.
+type DatasetIterator interface {
+	// Next returns up to n next patterns. It returns io.EOF, and no patterns, when the dataset is exhausted.
+	Next(n int) ([]Pattern, error)
+	// Reset rewinds the iterator to the first pattern, to start a new epoch.
+	Reset() error
+}
+
+// CSVIterator streams patterns of a CSV dataset described by CSVOptions, keeping in memory
+// only the current record. Classes, categories and missing features are collected by a first pass
+// over the file, so that patterns, Encoder and Imputer match LoadPatternsFromCSVFileWithOptions.
+type CSVIterator struct {
+	// class of each mapped value, sorted as Dataset.Classes (nil for regression)
+	Mapped []string
+	// opens the dataset from its beginning
+	open    func() (io.ReadCloser, error)
+	options CSVOptions
+	schema  *csvSchema
+	source  io.ReadCloser
+	reader  *csv.Reader
+	header  bool
+}
+
+// SliceIterator streams patterns already in memory.
+type SliceIterator struct {
+	patterns []Pattern
+	position int
+}
+
+// shuffledIterator shuffles patterns of a source iterator through a buffer of bounded size.
+type shuffledIterator struct {
+	source    DatasetIterator
+	size      int
+	random    *rand.Rand
+	buffer    []Pattern
+	exhausted bool
+}
+
+// NewCSVIterator creates a CSVIterator over the dataset returned by open, which is called again at each Reset.
+// Categorical columns must be declared in options, since DetectCategorical needs the whole file.
+func NewCSVIterator(open func() (io.ReadCloser, error), options CSVOptions) (*CSVIterator, error) {
+	if options.DetectCategorical {
+		return nil, fmt.Errorf("categorical columns can't be detected while streaming, declare them in ColumnTypes")
+	}
+	iterator := &CSVIterator{open: open, options: options, schema: &csvSchema{options: options}}
+	if errorValue := iterator.Reset(); errorValue != nil {
+		return nil, errorValue
+	}
+	for {
+		_, errorValue := iterator.next(false)
+		if errorValue == io.EOF {
+			break
+		}
+		if errorValue != nil {
+			iterator.Close()
+			return nil, errorValue
+		}
+	}
+	if errorValue := iterator.schema.finish(); errorValue != nil {
+		iterator.Close()
+		return nil, errorValue
+	}
+	if !options.Regression {
+		// classes are numbered as in a Dataset, so that a file is labelled the same way streamed or loaded
+		iterator.schema.classes = sortedClasses(iterator.schema.classes)
+		iterator.Mapped = iterator.schema.classes
+	}
+
+	loadingLog.With(options.Logging).WithFields(logging.Fields{
+		"level":   "info",
+		"place":   "patterns",
+		"method":  "NewCSVIterator",
+		"classes": len(iterator.Mapped),
+	}).Info("Dataset scan completed.")
+
+	return iterator, iterator.Reset()
+}
+
+// NewCSVFileIterator creates a CSVIterator over the CSV file at filePath.
+func NewCSVFileIterator(filePath string, options CSVOptions) (*CSVIterator, error) {
+	return NewCSVIterator(func() (io.ReadCloser, error) {
+		return os.Open(filePath)
+	}, options)
+}
+
+// Next returns up to n next patterns of the file.
+func (iterator *CSVIterator) Next(n int) ([]Pattern, error) {
+	var patterns []Pattern
+	for len(patterns) < n {
+		pattern, errorValue := iterator.next(true)
+		if errorValue == io.EOF {
+			break
+		}
+		if errorValue != nil {
+			return patterns, errorValue
+		}
+		patterns = append(patterns, pattern)
+	}
+	if len(patterns) == 0 {
+		return nil, io.EOF
+	}
+	return patterns, nil
+}
+
+// Reset reopens the file from its beginning.
+func (iterator *CSVIterator) Reset() error {
+	iterator.Close()
+	source, errorValue := iterator.open()
+	if errorValue != nil {
+		return errorValue
+	}
+	iterator.source = source
+	iterator.reader = newCSVReader(source, iterator.options)
+	iterator.header = iterator.options.Header
+	return nil
+}
+
+// Close closes the file currently streamed.
+func (iterator *CSVIterator) Close() error {
+	if iterator.source == nil {
+		return nil
+	}
+	errorValue := iterator.source.Close()
+	iterator.source, iterator.reader = nil, nil
+	return errorValue
+}
+
+// next parses the next record of the file. If labelled, the class index of the pattern is set,
+// otherwise the pattern is collected in the schema.
+func (iterator *CSVIterator) next(labelled bool) (Pattern, error) {
+	schema := iterator.schema
+	for {
+		record, errorValue := iterator.reader.Read()
+		if errorValue != nil {
+			return Pattern{}, errorValue
+		}
+		line, _ := iterator.reader.FieldPos(0)
+		if iterator.header {
+			iterator.header = false
+			schema.header = record
+			continue
+		}
+		if schema.columns == 0 {
+			if errorValue = schema.resolve(len(record)); errorValue != nil {
+				return Pattern{}, errorValue
+			}
+			if errorValue = schema.split(); errorValue != nil {
+				return Pattern{}, errorValue
+			}
+		}
+		pattern, errorValue := schema.parse(record, line)
+		if errorValue != nil {
+			return pattern, errorValue
+		}
+		if labelled {
+			schema.label(&pattern)
+		} else {
+			schema.collect(&pattern)
+		}
+		return pattern, nil
+	}
+}
+
+// NewSliceIterator creates a SliceIterator over patterns.
+func NewSliceIterator(patterns []Pattern) *SliceIterator {
+	return &SliceIterator{patterns: patterns}
+}
+
+// Next returns up to n next patterns of the slice.
+func (iterator *SliceIterator) Next(n int) ([]Pattern, error) {
+	if iterator.position >= len(iterator.patterns) {
+		return nil, io.EOF
+	}
+	end := iterator.position + n
+	if end > len(iterator.patterns) {
+		end = len(iterator.patterns)
+	}
+	patterns := iterator.patterns[iterator.position:end]
+	iterator.position = end
+	return patterns, nil
+}
+
+// Reset rewinds the iterator to the first pattern of the slice.
+func (iterator *SliceIterator) Reset() error {
+	iterator.position = 0
+	return nil
+}
+
+// NewShuffledIterator returns an iterator over patterns of source in random order, drawing each pattern
+// from a buffer of bufferSize patterns refilled from source. The larger the buffer, the closer to a full shuffle.
+func NewShuffledIterator(source DatasetIterator, bufferSize int, random *rand.Rand) DatasetIterator {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	return &shuffledIterator{source: source, size: bufferSize, random: random}
+}
+
+// Next returns up to n next patterns drawn from the buffer.
+func (iterator *shuffledIterator) Next(n int) ([]Pattern, error) {
+	var patterns []Pattern
+	for len(patterns) < n {
+		if !iterator.exhausted && len(iterator.buffer) < iterator.size {
+			chunk, errorValue := iterator.source.Next(iterator.size - len(iterator.buffer))
+			if errorValue == io.EOF {
+				iterator.exhausted = true
+			} else if errorValue != nil {
+				return patterns, errorValue
+			}
+			iterator.buffer = append(iterator.buffer, chunk...)
+		}
+		if len(iterator.buffer) == 0 {
+			break
+		}
+		last := len(iterator.buffer) - 1
+		drawn := iterator.random.Intn(len(iterator.buffer))
+		patterns = append(patterns, iterator.buffer[drawn])
+		iterator.buffer[drawn] = iterator.buffer[last]
+		iterator.buffer = iterator.buffer[:last]
+	}
+	if len(patterns) == 0 {
+		return nil, io.EOF
+	}
+	return patterns, nil
+}
+
+// Reset rewinds source and empties the buffer.
+func (iterator *shuffledIterator) Reset() error {
+	iterator.buffer, iterator.exhausted = nil, false
+	return iterator.source.Reset()
+}
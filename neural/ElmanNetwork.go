@@ -0,0 +1,85 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+)
+
+// ContextInit is the way the outputs of the previous step of a SimpleRecurrentLayer, the context units
+// of an Elman network, are set at the beginning of each sequence.
+type ContextInit string
+
+const (
+	// ZeroContext sets every context unit to 0
+	ZeroContext ContextInit = "zero"
+	// ConstantContext sets every context unit to ContextValue
+	ConstantContext ContextInit = "constant"
+	// RandomContext draws each context unit uniformly in [-ContextValue, ContextValue]
+	RandomContext ContextInit = "random"
+)
+
+// ElmanNetwork is a simple recurrent network: the hidden layer receives the features of the current step
+// together with the context layer, which holds the values of the hidden layer at the previous step.
+// It is a RecurrentNetwork with a single recurrent SimpleRecurrentLayer, so it is trained, executed
+// step by step (Step, ResetState, State, SetState) and validated as any other RecurrentNetwork.
+type ElmanNetwork struct {
+	RecurrentNetwork
+}
+
+// PrepareElmanNet create an Elman network: a RecurrentNetwork whose single SimpleRecurrentLayer receives
+// the features of the current step together with its own outputs at the previous step, the context units.
+// [inputLayer:int] number of features of each step
+// [hiddenLayer:int] number of hidden neurons, and of context units
+// [outputLayer:int] number of outputs of each step
+// [learningRate:float64] is the learning rate of neural network
+// [transfer:string] is the name of the transfer function of hidden neurons (see TransferFunctionByName)
+// Context units start each sequence at 0, change ContextInit and ContextValue of ContextLayer to use another value.
+func PrepareElmanNet(inputLayer int, hiddenLayer int, outputLayer int, learningRate float64, transfer string) (elman ElmanNetwork) {
+	if inputLayer < 1 || hiddenLayer < 1 || outputLayer < 1 {
+		trainingLog.WithFields(logging.Fields{
+			"level":  "error",
+			"msg":    "recurrent neural network init failed",
+			"layers": []int{inputLayer, hiddenLayer, outputLayer},
+		}).Error("Invalid layer sizes of Elman network.")
+		return
+	}
+	hidden := NewSimpleRecurrentLayer(inputLayer, hiddenLayer, transfer, true)
+	elman.RecurrentNetwork = NewRecurrentNetwork([]RecurrentLayer{hidden}, outputLayer, learningRate)
+
+	trainingLog.WithFields(logging.Fields{
+		"level":          "info",
+		"msg":            "recurrent neural network init completed",
+		"inputLayer":     inputLayer,
+		"hiddenLayer":    hiddenLayer,
+		"outputLayer":    outputLayer,
+		"learningRate: ": elman.LearningRate,
+	}).Info("Complete RNN init.")
+	return
+}
+
+// ContextLayer returns the hidden layer of the network, whose state holds the context units,
+// or nil if the network is not made of a single SimpleRecurrentLayer.
+func (elman *ElmanNetwork) ContextLayer() *SimpleRecurrentLayer {
+	return elmanLayer(&elman.RecurrentNetwork)
+}
+
+// Clone returns an Elman network with the same layers and settings, with new random weights.
+func (elman *ElmanNetwork) Clone() Model {
+	return &ElmanNetwork{RecurrentNetwork: *elman.RecurrentNetwork.Clone().(*RecurrentNetwork)}
+}
+
+// elmanLayer returns the single SimpleRecurrentLayer of network, or nil if network has other layers.
+func elmanLayer(network *RecurrentNetwork) *SimpleRecurrentLayer {
+	if len(network.Layers) != 1 {
+		return nil
+	}
+	hidden, _ := network.Layers[0].(*SimpleRecurrentLayer)
+	return hidden
+}
+
+// checkElmanNet returns a ConfigError if network is not made of a single recurrent SimpleRecurrentLayer.
+func checkElmanNet(network *RecurrentNetwork) error {
+	if hidden := elmanLayer(network); hidden == nil || hidden.RecurrentWeights == nil {
+		return &ConfigError{Field: "Layers", Reason: "an Elman network needs a single recurrent simple layer"}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package neural
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestElmanNetworkContext(t *testing.T) {
+	rand.Seed(1)
+	elman := PrepareElmanNet(2, 3, 1, 0.1, "tanh")
+	context := elman.ContextLayer()
+	if context == nil {
+		t.Fatal("ContextLayer of PrepareElmanNet is nil")
+	}
+	tests := []struct {
+		init  ContextInit
+		value float64
+		want  func(unit float64) bool
+	}{
+		{ZeroContext, 0.5, func(unit float64) bool { return unit == 0 }},
+		{ConstantContext, 0.5, func(unit float64) bool { return unit == 0.5 }},
+		{RandomContext, 0.5, func(unit float64) bool { return unit >= -0.5 && unit <= 0.5 }},
+	}
+	for _, test := range tests {
+		context.ContextInit, context.ContextValue = test.init, test.value
+		if _, errorValue := elman.Step(&Pattern{Features: []float64{1, -1}}); errorValue != nil {
+			t.Fatal(errorValue)
+		}
+		// the context units hold the outputs of the hidden layer at the last step
+		if state := elman.State(); len(state) != 3 || state[0] == 0 {
+			t.Errorf("%s: State after a step = %v, want the 3 hidden outputs", test.init, state)
+		}
+		elman.ResetState()
+		for _, unit := range elman.State() {
+			if !test.want(unit) {
+				t.Errorf("%s: context unit is %g after ResetState", test.init, unit)
+			}
+		}
+	}
+
+	clone, isElman := elman.Clone().(*ElmanNetwork)
+	if !isElman || clone.ContextLayer() == nil || clone.ContextLayer() == context {
+		t.Errorf("Clone = %T, want an Elman network with its own context layer", elman.Clone())
+	}
+}
@@ -0,0 +1,326 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"math/bits"
+)
+
+type CategoricalEncoding string
+
+const (
+	// OneHotEncoding maps each category to a vector with a single 1 in its position
+	OneHotEncoding CategoricalEncoding = "onehot"
+	// OrdinalEncoding maps each category to its index
+	OrdinalEncoding CategoricalEncoding = "ordinal"
+	// BinaryEncoding maps each category to the binary digits of its index + 1
+	BinaryEncoding CategoricalEncoding = "binary"
+	// TargetEncoding maps each category to the smoothed mean target of its training patterns
+	TargetEncoding CategoricalEncoding = "target"
+)
+
+type UnseenPolicy string
+
+const (
+	// IgnoreUnseen encodes unknown categories as all zeros (-1 for ordinal, prior for target encoding)
+	IgnoreUnseen UnseenPolicy = "ignore"
+	// MostFrequentUnseen encodes unknown categories as the most frequent training category
+	MostFrequentUnseen UnseenPolicy = "frequent"
+	// UnknownUnseen encodes unknown categories as an extra category of each column, the unknown bucket,
+	// placed after the known ones (prior for target encoding)
+	UnknownUnseen UnseenPolicy = "unknown"
+)
+
+type EncodedColumn struct {
+	// column name (from header, or "column N")
+	Name string
+	// vocabulary of the column: the declared categories (such as ARFF nominal values), followed by the ones
+	// found by the loader in order of first appearance. It fixes the encoded width and is never changed by fitting.
+	Categories []string
+}
+
+type Encoder struct {
+	// encoding method
+	Method CategoricalEncoding
+	// encoding of unseen categories: categories not in Columns, or without training patterns
+	Unseen UnseenPolicy
+	// weight of the prior in target encoding, in number of patterns
+	Smoothing float64
+	// categorical columns, in the order of Pattern.Categories
+	Columns []EncodedColumn
+	// number of target values: classes for classification, target columns for regression
+	Targets int
+	// targets are continuous values in MultipleExpectation instead of class indexes
+	Regression bool
+	// number of training patterns of each category of each column
+	Counts [][]int
+	// index of most frequent training category of each column
+	MostFrequent []int
+	// target encoding of each category of each column
+	TargetMeans [][][]float64
+	// mean target of training patterns
+	Prior []float64
+}
+
+// NewEncoder creates an unfitted Encoder with passed method and unseen category policy.
+// Column names, categories and targets are filled by the CSV loader, so that every model fed by the encoder
+// has the same number of features whatever the patterns it is fitted on.
+func NewEncoder(method CategoricalEncoding, unseen UnseenPolicy) *Encoder {
+	return &Encoder{Method: method, Unseen: unseen, Smoothing: 1.0}
+}
+
+// FitEncoder counts the patterns of each category of each column, and finds the most frequent category
+// and target encoding from patterns passed (training set). Columns are left unchanged, so that the encoded
+// width doesn't depend on patterns; categories without training patterns, such as the ones appearing
+// only in a test fold, are unseen. Empty categories are missing values and are never counted.
+// Unseen categories get the prior as target encoding.
+func FitEncoder(encoder *Encoder, patterns []Pattern) {
+	encoder.Counts = make([][]int, len(encoder.Columns))
+	encoder.MostFrequent = make([]int, len(encoder.Columns))
+	encoder.TargetMeans = make([][][]float64, len(encoder.Columns))
+	encoder.Prior = make([]float64, encoder.Targets)
+	if len(patterns) == 0 {
+		return
+	}
+
+	for index := range patterns {
+		for t, value := range encodingTarget(encoder, &patterns[index]) {
+			encoder.Prior[t] += value
+		}
+	}
+	for t := range encoder.Prior {
+		encoder.Prior[t] = encoder.Prior[t] / float64(len(patterns))
+	}
+
+	for c, column := range encoder.Columns {
+		counts := make([]int, len(column.Categories))
+		sums := make([][]float64, len(column.Categories))
+		for k := range sums {
+			sums[k] = make([]float64, encoder.Targets)
+		}
+		for index := range patterns {
+			k := categoryIndex(column, categoryAt(&patterns[index], c))
+			if k < 0 {
+				continue
+			}
+			counts[k]++
+			for t, value := range encodingTarget(encoder, &patterns[index]) {
+				sums[k][t] += value
+			}
+		}
+
+		encoder.TargetMeans[c] = make([][]float64, len(column.Categories))
+		for k := range column.Categories {
+			if counts[k] > counts[encoder.MostFrequent[c]] {
+				encoder.MostFrequent[c] = k
+			}
+			encoder.TargetMeans[c][k] = make([]float64, encoder.Targets)
+			for t := range encoder.Prior {
+				encoder.TargetMeans[c][k][t] = (sums[k][t] + encoder.Smoothing*encoder.Prior[t]) / (float64(counts[k]) + encoder.Smoothing)
+			}
+		}
+		encoder.Counts[c] = counts
+	}
+
+	trainingLog.WithFields(logging.Fields{
+		"level":    "debug",
+		"place":    "encoder",
+		"method":   "FitEncoder",
+		"encoding": encoder.Method,
+		"columns":  len(encoder.Columns),
+		"patterns": len(patterns),
+	}).Debug("Complete encoder fitting.")
+}
+
+// EncodeFeatures returns the numeric features of pattern followed by the encoding of its categories.
+// It returns a new slice, leaving pattern unchanged.
+func EncodeFeatures(encoder *Encoder, pattern *Pattern) []float64 {
+	encoded := make([]float64, len(pattern.Features), len(pattern.Features)+EncodedSize(encoder))
+	copy(encoded, pattern.Features)
+	for c, column := range encoder.Columns {
+		k := categoryIndex(column, categoryAt(pattern, c))
+		if k >= 0 && c < len(encoder.Counts) && encoder.Counts[c][k] == 0 {
+			// in the vocabulary, but never seen in training
+			k = -1
+		}
+		if k < 0 {
+			switch {
+			case encoder.Unseen == UnknownUnseen:
+				k = len(column.Categories)
+			case encoder.Unseen == MostFrequentUnseen && len(column.Categories) > 0 && c < len(encoder.MostFrequent):
+				k = encoder.MostFrequent[c]
+			case encoder.Unseen == MostFrequentUnseen && len(column.Categories) > 0:
+				k = 0
+			}
+		}
+		encoded = append(encoded, encodeCategory(encoder, c, k)...)
+	}
+	return encoded
+}
+
+// EncodePatterns applies encoder to each pattern.
+// It returns new patterns with encoded features and no categories, leaving passed ones unchanged.
+func EncodePatterns(encoder *Encoder, patterns []Pattern) []Pattern {
+	encoded := make([]Pattern, len(patterns))
+	for index, pattern := range patterns {
+		encoded[index] = pattern
+		encoded[index].Features = EncodeFeatures(encoder, &patterns[index])
+		encoded[index].Categories = nil
+	}
+	return encoded
+}
+
+// EncodedSize returns the number of features produced by encoder for the categorical columns.
+func EncodedSize(encoder *Encoder) (size int) {
+	for c := range encoder.Columns {
+		size += columnWidth(encoder, c)
+	}
+	return
+}
+
+// columnWidth returns the number of features produced by encoder for column c,
+// which only depends on its vocabulary and on the unknown bucket.
+func columnWidth(encoder *Encoder, c int) int {
+	categories := len(encoder.Columns[c].Categories)
+	if encoder.Unseen == UnknownUnseen {
+		categories++
+	}
+	switch encoder.Method {
+	case OneHotEncoding:
+		return categories
+	case BinaryEncoding:
+		return bits.Len(uint(categories))
+	case TargetEncoding:
+		return encoder.Targets
+	}
+	return 1
+}
+
+// encodeCategory encodes category k of column c (-1 for unseen category, the number of categories for the unknown bucket).
+func encodeCategory(encoder *Encoder, c int, k int) []float64 {
+	encoded := make([]float64, columnWidth(encoder, c))
+	switch encoder.Method {
+	case OneHotEncoding:
+		if k >= 0 {
+			encoded[k] = 1.0
+		}
+	case BinaryEncoding:
+		code := k + 1
+		for b := range encoded {
+			encoded[len(encoded)-1-b] = float64((code >> b) & 1)
+		}
+	case TargetEncoding:
+		if c < len(encoder.TargetMeans) && k >= 0 && k < len(encoder.TargetMeans[c]) {
+			copy(encoded, encoder.TargetMeans[c][k])
+		} else {
+			copy(encoded, encoder.Prior)
+		}
+	default:
+		encoded[0] = float64(k)
+	}
+	return encoded
+}
+
+// encodingTarget returns the target vector of pattern used by target encoding:
+// the first Targets expectations for regression, the one-hot class for classification.
+func encodingTarget(encoder *Encoder, pattern *Pattern) []float64 {
+	target := make([]float64, encoder.Targets)
+	if encoder.Regression {
+		copy(target, pattern.MultipleExpectation)
+	} else if class := int(pattern.SingleExpectation); class >= 0 && class < encoder.Targets {
+		target[class] = 1.0
+	}
+	return target
+}
+
+// categoryAt returns the raw category of column c of pattern (empty if missing).
+func categoryAt(pattern *Pattern, c int) string {
+	if c < len(pattern.Categories) {
+		return pattern.Categories[c]
+	}
+	return ""
+}
+
+// categoryIndex returns the position of category among known categories of column, or -1.
+func categoryIndex(column EncodedColumn, category string) int {
+	for k, known := range column.Categories {
+		if known == category {
+			return k
+		}
+	}
+	return -1
+}
+
+// FitPreprocessing returns a new imputer, encoder and scaler with the settings of those passed,
+// fitted on train patterns only, so that models trained on different patterns never share preprocessing state.
+// Each step is fitted on the output of the previous one. Nil steps stay nil.
+// Fitted steps produce PreprocessedSize features, whatever train patterns are, so models can be sized beforehand.
+// It returns the errors of FitImputer and FitScaler.
+func FitPreprocessing(imputer *Imputer, encoder *Encoder, scaler *Scaler, train []Pattern) (*Imputer, *Encoder, *Scaler, error) {
+	if imputer != nil {
+		fitted := *imputer
+		if errorValue := FitImputer(&fitted, train); errorValue != nil {
+			return nil, nil, nil, errorValue
+		}
+		imputer = &fitted
+		train = ImputePatterns(imputer, train)
+	}
+	if encoder != nil {
+		fitted := *encoder
+		FitEncoder(&fitted, train)
+		encoder = &fitted
+		train = EncodePatterns(encoder, train)
+	}
+	if scaler != nil {
+		fitted := NewScaler(scaler.Method)
+		if errorValue := FitScaler(fitted, train); errorValue != nil {
+			return nil, nil, nil, errorValue
+		}
+		scaler = fitted
+	}
+	return imputer, encoder, scaler, nil
+}
+
+// PreprocessedSize returns the number of features a model sees for patterns of features numeric features,
+// after imputer (with its missing indicators) and encoder, fitted or not. Nil imputer or encoder are skipped.
+func PreprocessedSize(imputer *Imputer, encoder *Encoder, features int) int {
+	if imputer != nil {
+		features += IndicatorSize(imputer)
+	}
+	if encoder != nil {
+		features += EncodedSize(encoder)
+	}
+	return features
+}
+
+// checkFitted returns a ConfigError if imputer, encoder or scaler is set but hasn't been fitted,
+// for training paths which can't fit them, such as streaming training.
+func checkFitted(imputer *Imputer, encoder *Encoder, scaler *Scaler) error {
+	if imputer != nil && imputer.Values == nil {
+		return &ConfigError{Field: "Imputer", Reason: "is not fitted, call FitPreprocessing on a sample of patterns first"}
+	}
+	if encoder != nil && encoder.MostFrequent == nil {
+		return &ConfigError{Field: "Encoder", Reason: "is not fitted, call FitPreprocessing on a sample of patterns first"}
+	}
+	if scaler != nil && scaler.Method != L2Normalization && scaler.Offset == nil {
+		return &ConfigError{Field: "Scaler", Reason: "is not fitted, call FitPreprocessing on a sample of patterns first"}
+	}
+	return nil
+}
+
+// modelFeatures returns features of pattern as a model sees them: missing values imputed by imputer,
+// categories encoded by encoder, then scaled by scaler. Nil imputer, encoder or scaler are skipped.
+func modelFeatures(imputer *Imputer, encoder *Encoder, scaler *Scaler, pattern *Pattern) []float64 {
+	features := pattern.Features
+	if imputer != nil {
+		imputed := *pattern
+		imputed.Features = ImputeFeatures(imputer, features)
+		pattern, features = &imputed, imputed.Features
+	}
+	if encoder != nil {
+		features = EncodeFeatures(encoder, pattern)
+	}
+	if scaler != nil {
+		features = ScaleFeatures(scaler, features)
+	}
+	return features
+}
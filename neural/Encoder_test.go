@@ -0,0 +1,85 @@
+package neural
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncoderWidthAcrossFolds(t *testing.T) {
+	// "blue" only appears in the last pattern, so that some folds never see it
+	data := "color,size,class\nred,1,a\ngreen,2,b\nred,3,a\ngreen,4,b\nred,5,a\nblue,6,b\n"
+	folds := [][]int{{0, 1}, {2, 3}, {4, 5}}
+	for _, method := range []CategoricalEncoding{OneHotEncoding, OrdinalEncoding, BinaryEncoding, TargetEncoding} {
+		for _, unseen := range []UnseenPolicy{IgnoreUnseen, MostFrequentUnseen, UnknownUnseen} {
+			encoder := NewEncoder(method, unseen)
+			patterns, errorValue, _ := ReadPatternsFromCSV(strings.NewReader(data),
+				CSVOptions{Header: true, ColumnTypes: map[int]ColumnType{0: CategoricalColumn}, Encoder: encoder})
+			if errorValue != nil {
+				t.Fatal(errorValue)
+			}
+			if got := len(encoder.Columns[0].Categories); got != 3 {
+				t.Fatalf("vocabulary has %d categories, want 3", got)
+			}
+			width := PreprocessedSize(nil, encoder, len(patterns[0].Features))
+			for f := range folds {
+				var train []Pattern
+				for i := range folds {
+					if i != f {
+						for _, index := range folds[i] {
+							train = append(train, patterns[index])
+						}
+					}
+				}
+				_, fitted, _, errorValue := FitPreprocessing(nil, encoder, nil, train)
+				if errorValue != nil {
+					t.Fatal(errorValue)
+				}
+				for index := range patterns {
+					if got := len(EncodeFeatures(fitted, &patterns[index])); got != width {
+						t.Errorf("%s/%s fold %d: pattern %d has %d features, want %d", method, unseen, f, index, got, width)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeUnseenCategories(t *testing.T) {
+	encoder := &Encoder{Method: OneHotEncoding, Columns: []EncodedColumn{{Name: "color", Categories: []string{"red", "green", "blue"}}}, Targets: 2}
+	train := []Pattern{
+		{Categories: []string{"red"}, SingleExpectation: 0},
+		{Categories: []string{"red"}, SingleExpectation: 0},
+		{Categories: []string{"green"}, SingleExpectation: 1},
+	}
+	tests := []struct {
+		unseen   UnseenPolicy
+		category string
+		want     []float64
+	}{
+		{IgnoreUnseen, "green", []float64{0, 1, 0}},
+		// blue is in the vocabulary but not in training patterns
+		{IgnoreUnseen, "blue", []float64{0, 0, 0}},
+		{IgnoreUnseen, "purple", []float64{0, 0, 0}},
+		{MostFrequentUnseen, "blue", []float64{1, 0, 0}},
+		{MostFrequentUnseen, "purple", []float64{1, 0, 0}},
+		{UnknownUnseen, "green", []float64{0, 1, 0, 0}},
+		{UnknownUnseen, "blue", []float64{0, 0, 0, 1}},
+		{UnknownUnseen, "purple", []float64{0, 0, 0, 1}},
+	}
+	for _, test := range tests {
+		fitted := *encoder
+		fitted.Unseen = test.unseen
+		FitEncoder(&fitted, train)
+		got := EncodeFeatures(&fitted, &Pattern{Categories: []string{test.category}})
+		if len(got) != len(test.want) {
+			t.Errorf("%s %q: encoded %v, want %v", test.unseen, test.category, got, test.want)
+			continue
+		}
+		for k := range got {
+			if got[k] != test.want[k] {
+				t.Errorf("%s %q: encoded %v, want %v", test.unseen, test.category, got, test.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package neural
+
+import (
+	"MultilayerPerceptron/util"
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors wrapped by errors of the package, to be checked with errors.Is.
+var (
+	// ErrShapeMismatch reports inputs whose size doesn't match the model or each other
+	ErrShapeMismatch = util.ErrShapeMismatch
+	// ErrEmptyDataset reports an operation needing at least one pattern
+	ErrEmptyDataset = errors.New("empty dataset")
+	// ErrUnknownClass reports a class outside the classes a model is built for
+	ErrUnknownClass = errors.New("unknown class")
+	// ErrInvalidConfig reports a network or option setting that can't be used
+	ErrInvalidConfig = errors.New("invalid configuration")
+)
+
+// ShapeError reports a size mismatch. It wraps ErrShapeMismatch.
+type ShapeError struct {
+	// what was measured, such as "input features" or "expected output"
+	What string
+	// size expected by the model
+	Expected int
+	// size found
+	Actual int
+}
+
+func (e *ShapeError) Error() string {
+	return fmt.Sprintf("%v: %s has size %d, expected %d", ErrShapeMismatch, e.What, e.Actual, e.Expected)
+}
+
+func (e *ShapeError) Unwrap() error {
+	return ErrShapeMismatch
+}
+
+// ClassError reports a pattern whose class index is not one of the known classes. It wraps ErrUnknownClass.
+type ClassError struct {
+	// SingleExpectation of the pattern
+	Class float64
+	// number of known classes
+	Classes int
+}
+
+func (e *ClassError) Error() string {
+	return fmt.Sprintf("%v: class %v not in %d classes", ErrUnknownClass, e.Class, e.Classes)
+}
+
+func (e *ClassError) Unwrap() error {
+	return ErrUnknownClass
+}
+
+// ConfigError reports an invalid setting. It wraps ErrInvalidConfig.
+type ConfigError struct {
+	// name of the setting, such as "NeuralLayers"
+	Field string
+	// why the setting can't be used
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%v: %s %s", ErrInvalidConfig, e.Field, e.Reason)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// emptyDatasetError returns ErrEmptyDataset wrapped with the operation that needed patterns.
+func emptyDatasetError(operation string) error {
+	return fmt.Errorf("%s: %w", operation, ErrEmptyDataset)
+}
+
+// checkFeatures returns a ShapeError for the first pattern whose number of features differs from the first one.
+func checkFeatures(patterns []Pattern) error {
+	for index := range patterns {
+		if len(patterns[index].Features) != len(patterns[0].Features) {
+			return &ShapeError{What: fmt.Sprintf("features of pattern %d", index), Expected: len(patterns[0].Features), Actual: len(patterns[index].Features)}
+		}
+	}
+	return nil
+}
+
+// checkClasses returns a ClassError for the first pattern whose class is not an index of classes.
+func checkClasses(patterns []Pattern, classes int) error {
+	for index := range patterns {
+		if !validClass(patterns[index].SingleExpectation, classes) {
+			return &ClassError{Class: patterns[index].SingleExpectation, Classes: classes}
+		}
+	}
+	return nil
+}
+
+// validClass returns whether class is an index in [0, classes).
+func validClass(class float64, classes int) bool {
+	return class >= 0 && class < float64(classes) && class == float64(int(class))
+}
+
+// checkMLPNet returns a ConfigError if the layers of the network are not connected to each other.
+func checkMLPNet(multiLayerPerceptron *MultiLayerNetwork) error {
+	if len(multiLayerPerceptron.NeuralLayers) < 2 {
+		return &ConfigError{Field: "NeuralLayers", Reason: fmt.Sprintf("has %d layers, at least 2 needed", len(multiLayerPerceptron.NeuralLayers))}
+	}
+	if multiLayerPerceptron.TransferFunction == nil || multiLayerPerceptron.TransferFunctionDerivative == nil {
+		return &ConfigError{Field: "TransferFunction", Reason: "is not set"}
+	}
+	for i, layer := range multiLayerPerceptron.NeuralLayers {
+		if layer.Length < 1 || layer.Length != len(layer.NeuronUnits) {
+			return &ConfigError{Field: fmt.Sprintf("NeuralLayers[%d]", i), Reason: fmt.Sprintf("has Length %d and %d neurons", layer.Length, len(layer.NeuronUnits))}
+		}
+		if i == 0 {
+			continue
+		}
+		for j, neuron := range layer.NeuronUnits {
+			if len(neuron.Weights) != multiLayerPerceptron.NeuralLayers[i-1].Length {
+				return &ConfigError{Field: fmt.Sprintf("NeuralLayers[%d].NeuronUnits[%d].Weights", i, j),
+					Reason: fmt.Sprintf("has %d weights for %d inputs", len(neuron.Weights), multiLayerPerceptron.NeuralLayers[i-1].Length)}
+			}
+		}
+	}
+	return nil
+}
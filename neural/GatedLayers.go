@@ -0,0 +1,523 @@
+package neural
+
+import (
+	"MultilayerPerceptron/util"
+	"fmt"
+)
+
+// Gate holds the weights of a group of units of a gated recurrent layer, such as the forget gate of an LSTMLayer.
+// Each unit j sums Bias[j], its weighted inputs and its weighted outputs of the layer at the previous step.
+type Gate struct {
+	// InputWeights[j][k] connects input k to unit j
+	InputWeights [][]float64
+	// RecurrentWeights[j][k] connects the previous output k of the layer to unit j
+	RecurrentWeights [][]float64
+	// Peephole[j] connects the cell of neuron j to unit j (nil without peephole connections)
+	Peephole []float64 `json:",omitempty"`
+	// bias of each unit
+	Bias []float64
+}
+
+// LSTMLayer is a layer of long short-term memory neurons. Each neuron keeps a cell, which the forget gate
+// scales and the input gate adds the candidate to, and outputs the tanh of its cell scaled by the output gate,
+// so that errors flow back through cells over many steps without vanishing. With peephole connections,
+// gates also read the cell: the previous one for input and forget gates, the new one for the output gate.
+type LSTMLayer struct {
+	InputGate  Gate
+	ForgetGate Gate
+	CellGate   Gate
+	OutputGate Gate
+
+	// outputs and cells of the previous step
+	state []float64
+	cell  []float64
+	// steps recorded for Backward
+	history []lstmStep
+	// error signals of outputs and cells, carried to the previous step
+	carry     []float64
+	cellCarry []float64
+	// gradients of InputGate, ForgetGate, CellGate and OutputGate
+	gradients [4]Gate
+}
+
+// lstmStep holds what Backward needs of a step executed by an LSTMLayer.
+type lstmStep struct {
+	input, previous, previousCell []float64
+	// values of input, forget and output gates, candidate, cell and tanh of cell
+	inputGate, forgetGate, outputGate, candidate, cell, cellTanh []float64
+}
+
+// GRULayer is a layer of gated recurrent units. The update gate of each neuron mixes its previous output
+// with a candidate, computed from inputs and from previous outputs scaled by the reset gate.
+type GRULayer struct {
+	UpdateGate    Gate
+	ResetGate     Gate
+	CandidateGate Gate
+
+	// outputs of the previous step
+	state []float64
+	// steps recorded for Backward
+	history []gruStep
+	// error signal of outputs, carried to the previous step
+	carry []float64
+	// gradients of UpdateGate, ResetGate and CandidateGate
+	gradients [3]Gate
+}
+
+// gruStep holds what Backward needs of a step executed by a GRULayer.
+type gruStep struct {
+	input, previous []float64
+	// values of update and reset gates, previous outputs scaled by the reset gate, and candidate
+	update, reset, resetPrevious, candidate []float64
+}
+
+// NewLSTMLayer creates a layer of size LSTM neurons with random weights for inputs inputs,
+// and peephole connections if peepholes is true.
+func NewLSTMLayer(inputs int, size int, peepholes bool) *LSTMLayer {
+	layer := &LSTMLayer{
+		InputGate:  newGate(inputs, size, peepholes),
+		ForgetGate: newGate(inputs, size, peepholes),
+		CellGate:   newGate(inputs, size, false),
+		OutputGate: newGate(inputs, size, peepholes),
+	}
+	layer.Reset()
+	return layer
+}
+
+// NewGRULayer creates a layer of size gated recurrent units with random weights for inputs inputs.
+func NewGRULayer(inputs int, size int) *GRULayer {
+	layer := &GRULayer{
+		UpdateGate:    newGate(inputs, size, false),
+		ResetGate:     newGate(inputs, size, false),
+		CandidateGate: newGate(inputs, size, false),
+	}
+	layer.Reset()
+	return layer
+}
+
+func (layer *LSTMLayer) Inputs() int {
+	return layer.CellGate.inputs()
+}
+
+func (layer *LSTMLayer) Size() int {
+	return len(layer.CellGate.Bias)
+}
+
+// Peepholes tells whether gates of the layer have peephole connections.
+func (layer *LSTMLayer) Peepholes() bool {
+	return layer.OutputGate.Peephole != nil
+}
+
+func (layer *LSTMLayer) Check() error {
+	inputs, size := layer.Inputs(), layer.Size()
+	if size == 0 || inputs == 0 {
+		return &ConfigError{Field: "CellGate", Reason: fmt.Sprintf("has %d units and %d inputs, no neurons or inputs", size, inputs)}
+	}
+	for _, gate := range layer.gates() {
+		if errorValue := gate.gate.check(gate.name, inputs, size, layer.Peepholes() && gate.name != "CellGate"); errorValue != nil {
+			return errorValue
+		}
+	}
+	return nil
+}
+
+func (layer *LSTMLayer) Forward(input []float64, record bool) []float64 {
+	size := layer.Size()
+	if len(layer.state) != size || len(layer.cell) != size {
+		layer.ResetState()
+	}
+	step := lstmStep{previous: layer.state, previousCell: layer.cell,
+		inputGate: make([]float64, size), forgetGate: make([]float64, size), outputGate: make([]float64, size),
+		candidate: make([]float64, size), cell: make([]float64, size), cellTanh: make([]float64, size)}
+	outputs := make([]float64, size)
+	for j := 0; j < size; j++ {
+		inputGate := layer.InputGate.sum(j, input, step.previous)
+		forgetGate := layer.ForgetGate.sum(j, input, step.previous)
+		if layer.Peepholes() {
+			inputGate += layer.InputGate.Peephole[j] * step.previousCell[j]
+			forgetGate += layer.ForgetGate.Peephole[j] * step.previousCell[j]
+		}
+		step.inputGate[j] = SigmoidTransfer(inputGate)
+		step.forgetGate[j] = SigmoidTransfer(forgetGate)
+		step.candidate[j] = HyperbolicTransfer(layer.CellGate.sum(j, input, step.previous))
+		step.cell[j] = step.forgetGate[j]*step.previousCell[j] + step.inputGate[j]*step.candidate[j]
+
+		outputGate := layer.OutputGate.sum(j, input, step.previous)
+		if layer.Peepholes() {
+			outputGate += layer.OutputGate.Peephole[j] * step.cell[j]
+		}
+		step.outputGate[j] = SigmoidTransfer(outputGate)
+		step.cellTanh[j] = HyperbolicTransfer(step.cell[j])
+		outputs[j] = step.outputGate[j] * step.cellTanh[j]
+	}
+	if record {
+		step.input = append([]float64(nil), input...)
+		layer.history = append(layer.history, step)
+	}
+	layer.state = append([]float64(nil), outputs...)
+	layer.cell = step.cell
+	return outputs
+}
+
+func (layer *LSTMLayer) Backward(delta []float64) []float64 {
+	layer.allocateGradients()
+	step := layer.history[len(layer.history)-1]
+	layer.history = layer.history[:len(layer.history)-1]
+	size := layer.Size()
+
+	inputDelta := make([]float64, layer.Inputs())
+	carry := make([]float64, size)
+	cellCarry := make([]float64, size)
+	// error signals of the sums of input, forget, cell and output gates
+	signals := [4][]float64{make([]float64, size), make([]float64, size), make([]float64, size), make([]float64, size)}
+	for j := 0; j < size; j++ {
+		outputDelta := delta[j]
+		cellDelta := 0.0
+		if layer.carry != nil {
+			outputDelta += layer.carry[j]
+			cellDelta = layer.cellCarry[j]
+		}
+		signals[3][j] = outputDelta * step.cellTanh[j] * SigmoidTransferDerivative(step.outputGate[j])
+		cellDelta += outputDelta * step.outputGate[j] * HyperbolicTransferDerivative(step.cellTanh[j])
+		if layer.Peepholes() {
+			cellDelta += signals[3][j] * layer.OutputGate.Peephole[j]
+		}
+		signals[0][j] = cellDelta * step.candidate[j] * SigmoidTransferDerivative(step.inputGate[j])
+		signals[1][j] = cellDelta * step.previousCell[j] * SigmoidTransferDerivative(step.forgetGate[j])
+		signals[2][j] = cellDelta * step.inputGate[j] * HyperbolicTransferDerivative(step.candidate[j])
+
+		cellCarry[j] = cellDelta * step.forgetGate[j]
+		if layer.Peepholes() {
+			cellCarry[j] += signals[0][j]*layer.InputGate.Peephole[j] + signals[1][j]*layer.ForgetGate.Peephole[j]
+			layer.gradients[0].Peephole[j] += signals[0][j] * step.previousCell[j]
+			layer.gradients[1].Peephole[j] += signals[1][j] * step.previousCell[j]
+			layer.gradients[3].Peephole[j] += signals[3][j] * step.cell[j]
+		}
+	}
+	for g, gate := range layer.gates() {
+		gate.gate.backward(&layer.gradients[g], signals[g], step.input, step.previous, inputDelta, carry)
+	}
+	layer.carry, layer.cellCarry = carry, cellCarry
+	return inputDelta
+}
+
+func (layer *LSTMLayer) Truncate() {
+	layer.history = nil
+	layer.carry, layer.cellCarry = nil, nil
+}
+
+// State returns a copy of the outputs of the layer at the last step, followed by its cells.
+func (layer *LSTMLayer) State() []float64 {
+	state := make([]float64, 2*layer.Size())
+	copy(state, layer.state)
+	copy(state[layer.Size():], layer.cell)
+	return state
+}
+
+func (layer *LSTMLayer) SetState(state []float64) error {
+	if len(state) != 2*layer.Size() {
+		return &ShapeError{What: "state", Expected: 2 * layer.Size(), Actual: len(state)}
+	}
+	layer.state = append([]float64(nil), state[:layer.Size()]...)
+	layer.cell = append([]float64(nil), state[layer.Size():]...)
+	return nil
+}
+
+func (layer *LSTMLayer) ResetState() {
+	layer.state = make([]float64, layer.Size())
+	layer.cell = make([]float64, layer.Size())
+}
+
+func (layer *LSTMLayer) Parameters() [][]float64 {
+	var parameters [][]float64
+	for _, gate := range layer.gates() {
+		parameters = append(parameters, gate.gate.rows()...)
+	}
+	return parameters
+}
+
+func (layer *LSTMLayer) Gradients() [][]float64 {
+	layer.allocateGradients()
+	var gradients [][]float64
+	for g := range layer.gradients {
+		gradients = append(gradients, layer.gradients[g].rows()...)
+	}
+	return gradients
+}
+
+// Reset initializes weights with random values and biases with 0, except forget gate biases set to 1,
+// so that cells are kept rather than forgotten until training tells otherwise.
+func (layer *LSTMLayer) Reset() {
+	for _, gate := range layer.gates() {
+		gate.gate.randomize()
+	}
+	for j := range layer.ForgetGate.Bias {
+		layer.ForgetGate.Bias[j] = 1.0
+	}
+	layer.gradients = [4]Gate{}
+	layer.Truncate()
+	layer.ResetState()
+}
+
+func (layer *LSTMLayer) Clone() RecurrentLayer {
+	return NewLSTMLayer(layer.Inputs(), layer.Size(), layer.Peepholes())
+}
+
+// gates returns the gates of the layer with their names, in the order of gradients.
+func (layer *LSTMLayer) gates() []namedGate {
+	return []namedGate{{"InputGate", &layer.InputGate}, {"ForgetGate", &layer.ForgetGate}, {"CellGate", &layer.CellGate}, {"OutputGate", &layer.OutputGate}}
+}
+
+// allocateGradients creates the gradients of the layer if they don't have the shape of its parameters.
+func (layer *LSTMLayer) allocateGradients() {
+	if len(layer.gradients[2].Bias) == layer.Size() {
+		return
+	}
+	for g, gate := range layer.gates() {
+		layer.gradients[g] = gate.gate.zero()
+	}
+}
+
+func (layer *GRULayer) Inputs() int {
+	return layer.CandidateGate.inputs()
+}
+
+func (layer *GRULayer) Size() int {
+	return len(layer.CandidateGate.Bias)
+}
+
+func (layer *GRULayer) Check() error {
+	inputs, size := layer.Inputs(), layer.Size()
+	if size == 0 || inputs == 0 {
+		return &ConfigError{Field: "CandidateGate", Reason: fmt.Sprintf("has %d units and %d inputs, no neurons or inputs", size, inputs)}
+	}
+	for _, gate := range layer.gates() {
+		if errorValue := gate.gate.check(gate.name, inputs, size, false); errorValue != nil {
+			return errorValue
+		}
+	}
+	return nil
+}
+
+func (layer *GRULayer) Forward(input []float64, record bool) []float64 {
+	size := layer.Size()
+	if len(layer.state) != size {
+		layer.ResetState()
+	}
+	step := gruStep{previous: layer.state, update: make([]float64, size), reset: make([]float64, size),
+		resetPrevious: make([]float64, size), candidate: make([]float64, size)}
+	for j := 0; j < size; j++ {
+		step.update[j] = SigmoidTransfer(layer.UpdateGate.sum(j, input, step.previous))
+		step.reset[j] = SigmoidTransfer(layer.ResetGate.sum(j, input, step.previous))
+		step.resetPrevious[j] = step.reset[j] * step.previous[j]
+	}
+	outputs := make([]float64, size)
+	for j := 0; j < size; j++ {
+		step.candidate[j] = HyperbolicTransfer(layer.CandidateGate.sum(j, input, step.resetPrevious))
+		outputs[j] = (1.0-step.update[j])*step.candidate[j] + step.update[j]*step.previous[j]
+	}
+	if record {
+		step.input = append([]float64(nil), input...)
+		layer.history = append(layer.history, step)
+	}
+	layer.state = outputs
+	return append([]float64(nil), outputs...)
+}
+
+func (layer *GRULayer) Backward(delta []float64) []float64 {
+	layer.allocateGradients()
+	step := layer.history[len(layer.history)-1]
+	layer.history = layer.history[:len(layer.history)-1]
+	size := layer.Size()
+
+	inputDelta := make([]float64, layer.Inputs())
+	carry := make([]float64, size)
+	outputDeltas := make([]float64, size)
+	// error signals of the sums of update, reset and candidate gates
+	signals := [3][]float64{make([]float64, size), make([]float64, size), make([]float64, size)}
+	for j := 0; j < size; j++ {
+		outputDeltas[j] = delta[j]
+		if layer.carry != nil {
+			outputDeltas[j] += layer.carry[j]
+		}
+		signals[0][j] = outputDeltas[j] * (step.previous[j] - step.candidate[j]) * SigmoidTransferDerivative(step.update[j])
+		signals[2][j] = outputDeltas[j] * (1.0 - step.update[j]) * HyperbolicTransferDerivative(step.candidate[j])
+		carry[j] = outputDeltas[j] * step.update[j]
+	}
+	// error signal of previous outputs scaled by the reset gate
+	resetPreviousDelta := make([]float64, size)
+	layer.CandidateGate.backward(&layer.gradients[2], signals[2], step.input, step.resetPrevious, inputDelta, resetPreviousDelta)
+	for j := 0; j < size; j++ {
+		carry[j] += resetPreviousDelta[j] * step.reset[j]
+		signals[1][j] = resetPreviousDelta[j] * step.previous[j] * SigmoidTransferDerivative(step.reset[j])
+	}
+	layer.UpdateGate.backward(&layer.gradients[0], signals[0], step.input, step.previous, inputDelta, carry)
+	layer.ResetGate.backward(&layer.gradients[1], signals[1], step.input, step.previous, inputDelta, carry)
+	layer.carry = carry
+	return inputDelta
+}
+
+func (layer *GRULayer) Truncate() {
+	layer.history = nil
+	layer.carry = nil
+}
+
+func (layer *GRULayer) State() []float64 {
+	state := make([]float64, layer.Size())
+	copy(state, layer.state)
+	return state
+}
+
+func (layer *GRULayer) SetState(state []float64) error {
+	if len(state) != layer.Size() {
+		return &ShapeError{What: "state", Expected: layer.Size(), Actual: len(state)}
+	}
+	layer.state = append([]float64(nil), state...)
+	return nil
+}
+
+func (layer *GRULayer) ResetState() {
+	layer.state = make([]float64, layer.Size())
+}
+
+func (layer *GRULayer) Parameters() [][]float64 {
+	var parameters [][]float64
+	for _, gate := range layer.gates() {
+		parameters = append(parameters, gate.gate.rows()...)
+	}
+	return parameters
+}
+
+func (layer *GRULayer) Gradients() [][]float64 {
+	layer.allocateGradients()
+	var gradients [][]float64
+	for g := range layer.gradients {
+		gradients = append(gradients, layer.gradients[g].rows()...)
+	}
+	return gradients
+}
+
+// Reset initializes weights with random values and biases with 0, except update gate biases set to 1,
+// so that previous outputs are kept rather than replaced until training tells otherwise.
+func (layer *GRULayer) Reset() {
+	for _, gate := range layer.gates() {
+		gate.gate.randomize()
+	}
+	for j := range layer.UpdateGate.Bias {
+		layer.UpdateGate.Bias[j] = 1.0
+	}
+	layer.gradients = [3]Gate{}
+	layer.Truncate()
+	layer.ResetState()
+}
+
+func (layer *GRULayer) Clone() RecurrentLayer {
+	return NewGRULayer(layer.Inputs(), layer.Size())
+}
+
+// gates returns the gates of the layer with their names, in the order of gradients.
+func (layer *GRULayer) gates() []namedGate {
+	return []namedGate{{"UpdateGate", &layer.UpdateGate}, {"ResetGate", &layer.ResetGate}, {"CandidateGate", &layer.CandidateGate}}
+}
+
+// allocateGradients creates the gradients of the layer if they don't have the shape of its parameters.
+func (layer *GRULayer) allocateGradients() {
+	if len(layer.gradients[2].Bias) == layer.Size() {
+		return
+	}
+	for g, gate := range layer.gates() {
+		layer.gradients[g] = gate.gate.zero()
+	}
+}
+
+// namedGate is a gate of a layer, with the name of its field for errors.
+type namedGate struct {
+	name string
+	gate *Gate
+}
+
+// newGate creates a gate of size units for inputs inputs, with peephole connections if peephole is true.
+// Weights are left at 0, see randomize.
+func newGate(inputs int, size int, peephole bool) Gate {
+	gate := Gate{InputWeights: zeroMatrix(size, inputs), RecurrentWeights: zeroMatrix(size, size), Bias: make([]float64, size)}
+	if peephole {
+		gate.Peephole = make([]float64, size)
+	}
+	return gate
+}
+
+// inputs returns the number of inputs of the gate.
+func (gate *Gate) inputs() int {
+	if len(gate.InputWeights) == 0 {
+		return 0
+	}
+	return len(gate.InputWeights[0])
+}
+
+// sum returns the bias of unit j plus its weighted inputs and previous outputs.
+func (gate *Gate) sum(j int, input []float64, previous []float64) float64 {
+	// shapes are verified by the Check of the layer
+	inputSum, _ := util.ScalarProduct(gate.InputWeights[j], input)
+	previousSum, _ := util.ScalarProduct(gate.RecurrentWeights[j], previous)
+	return gate.Bias[j] + inputSum + previousSum
+}
+
+// backward adds to gradients the gradients of the weights of the gate, given the error signals of the sums
+// of its units, and adds the error signals of input and previous to inputDelta and previousDelta.
+func (gate *Gate) backward(gradients *Gate, signals []float64, input []float64, previous []float64, inputDelta []float64, previousDelta []float64) {
+	for j, signal := range signals {
+		for k, value := range input {
+			gradients.InputWeights[j][k] += signal * value
+			inputDelta[k] += signal * gate.InputWeights[j][k]
+		}
+		for k, value := range previous {
+			gradients.RecurrentWeights[j][k] += signal * value
+			previousDelta[k] += signal * gate.RecurrentWeights[j][k]
+		}
+		gradients.Bias[j] += signal
+	}
+}
+
+// rows returns weights, peephole weights and bias of the gate as rows, shared with the gate.
+func (gate *Gate) rows() [][]float64 {
+	rows := append(append([][]float64{}, gate.InputWeights...), gate.RecurrentWeights...)
+	if gate.Peephole != nil {
+		rows = append(rows, gate.Peephole)
+	}
+	return append(rows, gate.Bias)
+}
+
+// zero returns a gate of zeros with the shape of gate, to accumulate its gradients.
+func (gate *Gate) zero() Gate {
+	return newGate(gate.inputs(), len(gate.Bias), gate.Peephole != nil)
+}
+
+// randomize sets weights to random values as randomizeMatrix, peephole weights and bias to 0.
+func (gate *Gate) randomize() {
+	randomizeMatrix(gate.InputWeights, gate.inputs()+len(gate.Bias))
+	randomizeMatrix(gate.RecurrentWeights, gate.inputs()+len(gate.Bias))
+	for j := range gate.Bias {
+		gate.Bias[j] = 0.0
+	}
+	for j := range gate.Peephole {
+		gate.Peephole[j] = 0.0
+	}
+}
+
+// check returns a ConfigError if the gate named field doesn't have size units for inputs inputs,
+// with peephole weights if peephole is true.
+func (gate *Gate) check(field string, inputs int, size int, peephole bool) error {
+	if errorValue := checkMatrix(field+".InputWeights", gate.InputWeights, size, inputs); errorValue != nil {
+		return errorValue
+	}
+	if errorValue := checkMatrix(field+".RecurrentWeights", gate.RecurrentWeights, size, size); errorValue != nil {
+		return errorValue
+	}
+	if len(gate.Bias) != size {
+		return &ConfigError{Field: field + ".Bias", Reason: fmt.Sprintf("has %d values for %d units", len(gate.Bias), size)}
+	}
+	if peephole && len(gate.Peephole) != size || !peephole && gate.Peephole != nil {
+		return &ConfigError{Field: field + ".Peephole", Reason: fmt.Sprintf("has %d values, %d expected", len(gate.Peephole), size)}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package neural
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestGradientCheck(t *testing.T) {
+	networks := map[string]func() RecurrentNetwork{
+		"lstm": func() RecurrentNetwork { return PrepareLSTMNet([]int{2, 4, 1}, 0.1, false) },
+		"lstm with peepholes": func() RecurrentNetwork {
+			return PrepareLSTMNet([]int{2, 4, 1}, 0.1, true)
+		},
+		"gru":     func() RecurrentNetwork { return PrepareGRUNet([]int{2, 4, 1}, 0.1) },
+		"stacked": func() RecurrentNetwork { return PrepareStackedNet([]int{2, 4, 3, 1}, 0.1, "tanh") },
+		"jordan":  func() RecurrentNetwork { return PrepareJordanNet(2, 4, 1, 0.1, "tanh") },
+		"elman":   func() RecurrentNetwork { return PrepareElmanNet(2, 4, 1, 0.1, "tanh").RecurrentNetwork },
+	}
+	for name, prepare := range networks {
+		t.Run(name, func(t *testing.T) {
+			rand.Seed(1)
+			network := prepare()
+			for _, sequence := range append(CreateAddingProblemSequences(6, 2), CreateBinaryAdditionSequences(4, 2)...) {
+				difference, errorValue := GradientCheck(&network, &sequence, 1e-5)
+				if errorValue != nil {
+					t.Fatalf("GradientCheck on sequence %s: %v", sequence.ID, errorValue)
+				}
+				if difference >= 1e-6 {
+					t.Errorf("GradientCheck on sequence %s = %g, want below 1e-6", sequence.ID, difference)
+				}
+			}
+		})
+	}
+}
+
+func TestAddingProblem(t *testing.T) {
+	rand.Seed(1)
+	train := CreateAddingProblemSequences(10, 300)
+	test := CreateAddingProblemSequences(10, 100)
+	network := PrepareGRUNet([]int{2, 8, 1}, 0.5)
+	network.OutputTransfer = "linear"
+	network.BatchSize, network.GradientClip = 20, 1.0
+	if errorValue := network.FitSequences(train, nil, 40); errorValue != nil {
+		t.Fatal(errorValue)
+	}
+
+	squares := 0.0
+	for s := range test {
+		output := finalOutput(t, &network, &test[s])
+		expected := test[s].Steps[len(test[s].Steps)-1].MultipleExpectation[0]
+		squares += (expected - output[0]) * (expected - output[0])
+	}
+	// predicting 0.5 for every sequence gives about 1/24
+	if mse := squares / float64(len(test)); mse >= 1.0/24.0 {
+		t.Errorf("mean squared error = %g, want below the baseline 1/24", mse)
+	}
+}
+
+func TestDelayedRecall(t *testing.T) {
+	rand.Seed(1)
+	train := CreateDelayedRecallSequences(4, 10, 200)
+	test := CreateDelayedRecallSequences(4, 10, 100)
+	network := PrepareStackedNet([]int{5, 8, 4}, 0.1, "tanh")
+	network.BatchSize, network.GradientClip = 10, 1.0
+	if errorValue := network.FitSequences(train, []string{"a", "b", "c", "d"}, 30); errorValue != nil {
+		t.Fatal(errorValue)
+	}
+
+	correct := 0
+	for s := range test {
+		output := finalOutput(t, &network, &test[s])
+		best := 0
+		for j := range output {
+			if output[j] > output[best] {
+				best = j
+			}
+		}
+		if float64(best) == test[s].Steps[len(test[s].Steps)-1].SingleExpectation {
+			correct++
+		}
+	}
+	// guessing a symbol recalls one sequence out of 4
+	if accuracy := float64(correct) / float64(len(test)); accuracy <= 0.25 {
+		t.Errorf("accuracy = %g, want above the baseline 0.25", accuracy)
+	}
+}
+
+func TestCreateAddingProblemSequencesShortLength(t *testing.T) {
+	for _, length := range []int{-1, 0, 1} {
+		if sequences := CreateAddingProblemSequences(length, 1); sequences != nil {
+			t.Errorf("CreateAddingProblemSequences(%d, 1) = %d sequences, want nil", length, len(sequences))
+		}
+	}
+}
+
+// finalOutput executes network on the steps of sequence from a reset state, returning the outputs of the last step.
+func finalOutput(t *testing.T, network *RecurrentNetwork, sequence *Sequence) []float64 {
+	t.Helper()
+	network.ResetState()
+	var output []float64
+	for step := range sequence.Steps {
+		var errorValue error
+		if output, errorValue = network.Step(&sequence.Steps[step]); errorValue != nil {
+			t.Fatalf("Step %d of sequence %s: %v", step, sequence.ID, errorValue)
+		}
+	}
+	if math.IsNaN(output[0]) {
+		t.Fatalf("output of sequence %s is NaN", sequence.ID)
+	}
+	return output
+}
@@ -0,0 +1,83 @@
+package neural
+
+import (
+	"math"
+)
+
+// GradientCheck compares the gradients computed by backpropagation through time on sequence, toward
+// MultipleExpectation of its steps, with numerical gradients of the loss ½Σ(expected-output)² by central
+// differences of step epsilon, for every weight and bias of the network. Truncation, GradientClip,
+// Regularization and loss functions are ignored, and the network is left with its parameters unchanged and its state reset.
+// It returns the largest difference between analytic and numerical gradient, relative to the largest
+// of their absolute values when it exceeds 1: values around 1e-7 tell the gradients are right.
+// It returns a ConfigError, a ShapeError or an error wrapping ErrEmptyDataset as RecurrentTrainSequences.
+func GradientCheck(network *RecurrentNetwork, sequence *Sequence, epsilon float64) (float64, error) {
+	if errorValue := checkRecurrentNet(network); errorValue != nil {
+		return 0.0, errorValue
+	}
+	if sequence.Length() == 0 {
+		return 0.0, emptyDatasetError("GradientCheck")
+	}
+
+	parameters, gradients := network.parameters()
+	scaleRows(gradients, 0.0)
+	network.ResetState()
+	if errorValue := recurrentForward(network, sequence, 0, len(sequence.Steps), regressionTarget); errorValue != nil {
+		network.truncate()
+		return 0.0, errorValue
+	}
+	// the default error signal, expected minus actual, is the one of ½ squared error
+	lossGradient := network.LossFunctionGradient
+	network.LossFunctionGradient = nil
+	_, _, errorValue := recurrentWindow(network)
+	network.LossFunctionGradient = lossGradient
+	if errorValue != nil {
+		return 0.0, errorValue
+	}
+	defer scaleRows(gradients, 0.0)
+	defer network.ResetState()
+
+	maxDifference := 0.0
+	for i, row := range parameters {
+		for k, value := range row {
+			row[k] = value + epsilon
+			plus, errorValue := sequenceLoss(network, sequence)
+			if errorValue != nil {
+				row[k] = value
+				return 0.0, errorValue
+			}
+			row[k] = value - epsilon
+			minus, _ := sequenceLoss(network, sequence)
+			row[k] = value
+
+			// gradients are descent directions, opposite to the derivative of the loss
+			numerical := -(plus - minus) / (2.0 * epsilon)
+			difference := math.Abs(numerical-gradients[i][k]) / math.Max(1.0, math.Max(math.Abs(numerical), math.Abs(gradients[i][k])))
+			maxDifference = math.Max(maxDifference, difference)
+		}
+	}
+	return maxDifference, nil
+}
+
+// sequenceLoss returns ½Σ(expected-output)² over the steps of sequence with a target, executed from a reset state.
+func sequenceLoss(network *RecurrentNetwork, sequence *Sequence) (float64, error) {
+	network.ResetState()
+	last := sequence.lastStep()
+	loss := 0.0
+	for t := range sequence.Steps {
+		if sequence.Masked(t) {
+			continue
+		}
+		output, errorValue := network.step(&sequence.Steps[t], false)
+		if errorValue != nil {
+			return 0.0, errorValue
+		}
+		if (sequence.FinalTarget && t != last) || sequence.Steps[t].MultipleExpectation == nil {
+			continue
+		}
+		for j, expected := range sequence.Steps[t].MultipleExpectation {
+			loss += 0.5 * (expected - output[j]) * (expected - output[j])
+		}
+	}
+	return loss, nil
+}
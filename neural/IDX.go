@@ -0,0 +1,263 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// IDXOptions tells LoadPatternsFromIDXFiles how to scale pixels and which patterns to keep.
+type IDXOptions struct {
+	// factor multiplying each pixel (if 0, 1/255 for unsigned byte images and 1 otherwise)
+	Scale float64
+	// number of patterns drawn at random, stratified by class, keeping file order (0 for all)
+	Subsample int
+	// seed of subsampling
+	Seed int64
+	// logger and levels of loading, instead of the ones set by logging.Configure (nil to use them)
+	Logging *logging.Options
+}
+
+// idxArray is a multi-dimensional array read from an IDX file, with values in big-endian raw bytes.
+type idxArray struct {
+	// IDX type code: 0x08 ubyte, 0x09 byte, 0x0B int16, 0x0C int32, 0x0D float32, 0x0E float64
+	kind byte
+	dims []int
+	data []byte
+}
+
+// LoadPatternsFromIDXFiles load an image dataset stored as IDX files (such as MNIST or Fashion-MNIST),
+// gzipped or not, into an array of Pattern with flattened, scaled pixels as features.
+// [imagesPath:string] is the IDX file of images, [labelsPath:string] the IDX file of their labels.
+// Classes are the label numbers, so that mapped[k] is strconv.Itoa(k).
+// It returns patterns and the class of each mapped value, or an error wrapping ErrEmptyDataset without images.
+func LoadPatternsFromIDXFiles(imagesPath string, labelsPath string, options IDXOptions) ([]Pattern, error, []string) {
+	log := loadingLog.With(options.Logging)
+	images, errorValue := readIDXFile(imagesPath, log)
+	if errorValue != nil {
+		return nil, errorValue, nil
+	}
+	labels, errorValue := readIDXFile(labelsPath, log)
+	if errorValue != nil {
+		return nil, errorValue, nil
+	}
+
+	patterns, errorValue, mapped := idxPatterns(images, labels, options)
+	if errorValue != nil {
+		return nil, errorValue, nil
+	}
+	if len(patterns) == 0 {
+		return nil, emptyDatasetError("LoadPatternsFromIDXFiles"), nil
+	}
+
+	log.WithFields(logging.Fields{
+		"level":    "info",
+		"place":    "patterns",
+		"method":   "LoadPatternsFromIDXFiles",
+		"readData": len(patterns),
+		"features": len(patterns[0].Features),
+		"classes":  len(mapped),
+	}).Info("File reading completed.")
+
+	return patterns, nil, mapped
+}
+
+// ReadIDX reads an IDX file, gzipped or not.
+// It returns the dimensions of the array and its values in row-major order.
+func ReadIDX(r io.Reader) ([]int, []float64, error) {
+	array, errorValue := readIDX(r)
+	if errorValue != nil {
+		return nil, nil, errorValue
+	}
+	values := make([]float64, array.length())
+	for index := range values {
+		values[index] = array.value(index)
+	}
+	return array.dims, values, nil
+}
+
+// readIDXFile reads the IDX file at filePath, adding the path to errors and logging them on log.
+func readIDXFile(filePath string, log logging.Channel) (idxArray, error) {
+	file, errorValue := os.Open(filePath)
+	if errorValue != nil {
+		log.WithFields(logging.Fields{
+			"level":      "error",
+			"place":      "patterns",
+			"method":     "LoadPatternsFromIDXFiles",
+			"msg":        "reading file in specific path",
+			"filePath":   filePath,
+			"errorValue": errorValue,
+		}).Error("Failed to read file in specified path.")
+		return idxArray{}, errorValue
+	}
+	defer file.Close()
+	array, errorValue := readIDX(file)
+	if errorValue != nil {
+		return array, fmt.Errorf("%s: %w", filePath, errorValue)
+	}
+	return array, nil
+}
+
+// readIDX reads the header and the raw data of an IDX array, decompressing gzipped input.
+func readIDX(r io.Reader) (idxArray, error) {
+	var array idxArray
+	reader := bufio.NewReader(r)
+	if magic, _ := reader.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		decompressed, errorValue := gzip.NewReader(reader)
+		if errorValue != nil {
+			return array, errorValue
+		}
+		defer decompressed.Close()
+		reader = bufio.NewReader(decompressed)
+	}
+
+	header := make([]byte, 4)
+	if _, errorValue := io.ReadFull(reader, header); errorValue != nil {
+		return array, fmt.Errorf("reading IDX header: %w", errorValue)
+	}
+	if header[0] != 0 || header[1] != 0 {
+		return array, fmt.Errorf("invalid IDX magic number %x", header)
+	}
+	array.kind = header[2]
+	if array.size() == 0 {
+		return array, fmt.Errorf("unknown IDX data type 0x%02x", array.kind)
+	}
+
+	array.dims = make([]int, header[3])
+	for d := range array.dims {
+		var dim uint32
+		if errorValue := binary.Read(reader, binary.BigEndian, &dim); errorValue != nil {
+			return array, fmt.Errorf("reading IDX dimension %d: %w", d, errorValue)
+		}
+		array.dims[d] = int(dim)
+	}
+	array.data = make([]byte, array.length()*array.size())
+	if _, errorValue := io.ReadFull(reader, array.data); errorValue != nil {
+		return array, fmt.Errorf("reading IDX data of %v values: %w", array.dims, errorValue)
+	}
+	return array, nil
+}
+
+// size returns the number of bytes of each value of the array (0 for unknown types).
+func (array idxArray) size() int {
+	switch array.kind {
+	case 0x08, 0x09:
+		return 1
+	case 0x0B:
+		return 2
+	case 0x0C, 0x0D:
+		return 4
+	case 0x0E:
+		return 8
+	}
+	return 0
+}
+
+// length returns the number of values of the array.
+func (array idxArray) length() int {
+	length := 1
+	for _, dim := range array.dims {
+		length *= dim
+	}
+	return length
+}
+
+// value returns the value at position index of the array.
+func (array idxArray) value(index int) float64 {
+	raw := array.data[index*array.size():]
+	switch array.kind {
+	case 0x08:
+		return float64(raw[0])
+	case 0x09:
+		return float64(int8(raw[0]))
+	case 0x0B:
+		return float64(int16(binary.BigEndian.Uint16(raw)))
+	case 0x0C:
+		return float64(int32(binary.BigEndian.Uint32(raw)))
+	case 0x0D:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw)))
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(raw))
+}
+
+// idxPatterns builds patterns from images and labels arrays, subsampled and scaled as described by options.
+func idxPatterns(images idxArray, labels idxArray, options IDXOptions) ([]Pattern, error, []string) {
+	if len(images.dims) == 0 || len(labels.dims) != 1 || images.dims[0] != labels.dims[0] {
+		return nil, fmt.Errorf("images %v and labels %v don't match", images.dims, labels.dims), nil
+	}
+	count := images.dims[0]
+	features := images.length() / int(math.Max(float64(count), 1.0))
+	scale := options.Scale
+	if scale == 0.0 {
+		scale = 1.0
+		if images.kind == 0x08 {
+			scale = 1.0 / 255.0
+		}
+	}
+
+	classes := 0
+	for index := 0; index < count; index++ {
+		label := labels.value(index)
+		if label < 0 || label != math.Trunc(label) {
+			return nil, fmt.Errorf("label %v of image %d is not a class number", label, index), nil
+		}
+		classes = int(math.Max(float64(classes), label+1))
+	}
+	mapped := make([]string, classes)
+	for k := range mapped {
+		mapped[k] = strconv.Itoa(k)
+	}
+
+	selected := idxSubsample(labels, classes, options)
+	patterns := make([]Pattern, len(selected))
+	for p, index := range selected {
+		patterns[p].Features = make([]float64, features)
+		for f := range patterns[p].Features {
+			patterns[p].Features[f] = images.value(index*features+f) * scale
+		}
+		patterns[p].SingleExpectation = labels.value(index)
+		patterns[p].SingleRawExpectation = mapped[int(patterns[p].SingleExpectation)]
+	}
+	return patterns, nil, mapped
+}
+
+// idxSubsample returns the sorted indexes of patterns selected by options: all of them, or Subsample
+// patterns drawn at random from each class in proportion to its size.
+func idxSubsample(labels idxArray, classes int, options IDXOptions) []int {
+	count := labels.dims[0]
+	if options.Subsample <= 0 || options.Subsample >= count {
+		selected := make([]int, count)
+		for index := range selected {
+			selected[index] = index
+		}
+		return selected
+	}
+
+	byClass := make([][]int, classes)
+	for index := 0; index < count; index++ {
+		label := int(labels.value(index))
+		byClass[label] = append(byClass[label], index)
+	}
+	random := rand.New(rand.NewSource(options.Seed))
+	var selected []int
+	remaining, remainingCount := options.Subsample, count
+	for _, members := range byClass {
+		if len(members) == 0 {
+			continue
+		}
+		take := int(math.Round(float64(remaining) * float64(len(members)) / float64(remainingCount)))
+		remaining, remainingCount = remaining-take, remainingCount-len(members)
+		random.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+		selected = append(selected, members[:take]...)
+	}
+	sort.Ints(selected)
+	return selected
+}
@@ -0,0 +1,217 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/util"
+	"math"
+	"sort"
+)
+
+type ImputationStrategy string
+
+const (
+	// MeanImputation replaces missing values with the training mean of the feature
+	MeanImputation ImputationStrategy = "mean"
+	// MedianImputation replaces missing values with the training median of the feature
+	MedianImputation ImputationStrategy = "median"
+	// MostFrequentImputation replaces missing values with the most frequent training value of the feature
+	MostFrequentImputation ImputationStrategy = "frequent"
+	// ConstantImputation replaces missing values with Constant
+	ConstantImputation ImputationStrategy = "constant"
+	// KNNImputation replaces missing values with the mean of the feature over the nearest training patterns
+	KNNImputation ImputationStrategy = "knn"
+)
+
+type Imputer struct {
+	// imputation strategy
+	Strategy ImputationStrategy
+	// value used by ConstantImputation, and for features never observed in training
+	Constant float64
+	// number of neighbors used by KNNImputation
+	Neighbors int
+	// append a 0/1 missing indicator feature for each feature in MissingFeatures
+	Indicators bool
+	// indexes of features which can be missing, filled by the CSV loader
+	MissingFeatures []int
+	// replacement value of each feature
+	Values []float64
+	// complete training features (without missing values) used by KNNImputation
+	Reference [][]float64
+}
+
+// NewImputer creates an unfitted Imputer with passed strategy.
+func NewImputer(strategy ImputationStrategy) *Imputer {
+	return &Imputer{Strategy: strategy, Neighbors: 5}
+}
+
+// IsMissing tells whether a feature value is missing.
+func IsMissing(value float64) bool {
+	return math.IsNaN(value)
+}
+
+// FitImputer computes the replacement value of each feature from patterns passed (training set),
+// ignoring missing values. KNNImputation also keeps complete training features as reference.
+// It returns a ShapeError if patterns don't have the same number of features.
+func FitImputer(imputer *Imputer, patterns []Pattern) error {
+	imputer.Values, imputer.Reference = nil, nil
+	if len(patterns) == 0 {
+		return nil
+	}
+	if errorValue := checkFeatures(patterns); errorValue != nil {
+		return errorValue
+	}
+	dim := len(patterns[0].Features)
+	imputer.Values = make([]float64, dim)
+
+	for f := 0; f < dim; f++ {
+		var column []float64
+		for index := range patterns {
+			if value := patterns[index].Features[f]; !IsMissing(value) {
+				column = append(column, value)
+			}
+		}
+		if len(column) == 0 || imputer.Strategy == ConstantImputation {
+			imputer.Values[f] = imputer.Constant
+			continue
+		}
+		switch imputer.Strategy {
+		case MedianImputation:
+			imputer.Values[f] = util.Percentile(column, 50.0)
+		case MostFrequentImputation:
+			imputer.Values[f] = mostFrequent(column)
+		default:
+			imputer.Values[f] = util.Mean(column)
+		}
+	}
+
+	if imputer.Strategy == KNNImputation {
+		for index := range patterns {
+			complete := true
+			for _, value := range patterns[index].Features {
+				complete = complete && !IsMissing(value)
+			}
+			if complete {
+				imputer.Reference = append(imputer.Reference, append([]float64{}, patterns[index].Features...))
+			}
+		}
+	}
+
+	trainingLog.WithFields(logging.Fields{
+		"level":    "debug",
+		"place":    "imputer",
+		"method":   "FitImputer",
+		"strategy": imputer.Strategy,
+		"features": dim,
+		"patterns": len(patterns),
+	}).Debug("Complete imputer fitting.")
+	return nil
+}
+
+// ImputeFeatures replaces missing values of a features vector, then appends missing indicators if enabled.
+// It returns a new slice, leaving passed features unchanged. An unfitted imputer replaces missing values with Constant.
+func ImputeFeatures(imputer *Imputer, features []float64) []float64 {
+	imputed := make([]float64, len(features), len(features)+len(imputer.MissingFeatures))
+	copy(imputed, features)
+
+	var neighbors [][]float64
+	for f, value := range features {
+		if !IsMissing(value) {
+			continue
+		}
+		if imputer.Strategy == KNNImputation && neighbors == nil {
+			neighbors = nearestReferences(imputer, features)
+		}
+		imputed[f] = imputedValue(imputer, f, neighbors)
+	}
+
+	if imputer.Indicators {
+		for _, f := range imputer.MissingFeatures {
+			indicator := 0.0
+			if f < len(features) && IsMissing(features[f]) {
+				indicator = 1.0
+			}
+			imputed = append(imputed, indicator)
+		}
+	}
+	return imputed
+}
+
+// ImputePatterns applies imputer to features of each pattern.
+// It returns new patterns, leaving passed ones unchanged.
+func ImputePatterns(imputer *Imputer, patterns []Pattern) []Pattern {
+	imputed := make([]Pattern, len(patterns))
+	for index, pattern := range patterns {
+		imputed[index] = pattern
+		imputed[index].Features = ImputeFeatures(imputer, pattern.Features)
+	}
+	return imputed
+}
+
+// IndicatorSize returns the number of missing indicator features appended by imputer.
+func IndicatorSize(imputer *Imputer) int {
+	if !imputer.Indicators {
+		return 0
+	}
+	return len(imputer.MissingFeatures)
+}
+
+// imputedValue returns the replacement of feature f: the mean over neighbors for KNNImputation,
+// the fitted value otherwise.
+func imputedValue(imputer *Imputer, f int, neighbors [][]float64) float64 {
+	var sum, count = 0.0, 0.0
+	for _, neighbor := range neighbors {
+		if f < len(neighbor) {
+			sum += neighbor[f]
+			count++
+		}
+	}
+	if count > 0 {
+		return sum / count
+	}
+	if f < len(imputer.Values) {
+		return imputer.Values[f]
+	}
+	return imputer.Constant
+}
+
+// nearestReferences returns the Neighbors reference patterns closest to features, measuring the
+// euclidean distance on observed features and scaling it up by the share of compared features.
+func nearestReferences(imputer *Imputer, features []float64) [][]float64 {
+	type neighbor struct {
+		distance float64
+		features []float64
+	}
+	var candidates []neighbor
+	for _, reference := range imputer.Reference {
+		sum, present := 0.0, 0
+		for f, value := range features {
+			if f < len(reference) && !IsMissing(value) {
+				sum += (value - reference[f]) * (value - reference[f])
+				present++
+			}
+		}
+		if present > 0 {
+			candidates = append(candidates, neighbor{math.Sqrt(sum * float64(len(features)) / float64(present)), reference})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+
+	var nearest [][]float64
+	for index := 0; index < len(candidates) && index < imputer.Neighbors; index++ {
+		nearest = append(nearest, candidates[index].features)
+	}
+	return nearest
+}
+
+// mostFrequent returns the most frequent value of v, the smallest one among ties.
+func mostFrequent(v []float64) float64 {
+	counts := make(map[float64]int)
+	best, bestCount := math.Inf(1), 0
+	for _, value := range v {
+		counts[value]++
+		if count := counts[value]; count > bestCount || (count == bestCount && value < best) {
+			best, bestCount = value, count
+		}
+	}
+	return best
+}
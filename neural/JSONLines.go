@@ -0,0 +1,195 @@
+package neural
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LoadPatternsFromJSONLinesFile load a JSON Lines dataset into an array of Pattern.
+// See ReadPatternsFromJSONLines for options.
+func LoadPatternsFromJSONLinesFile(filePath string, options CSVOptions) ([]Pattern, error, []string) {
+	return loadPatternsFile(filePath, "LoadPatternsFromJSONLinesFile", loadingLog.With(options.Logging), func(r io.Reader) ([]Pattern, error, []string) {
+		return ReadPatternsFromJSONLines(r, options)
+	})
+}
+
+// ReadPatternsFromJSONLines reads a JSON Lines dataset, one flat object for each line, into an array of Pattern.
+// Keys of the first object are the columns, selected by options as CSV header columns (target is the last key
+// by default). Arrays of numbers are expanded to columns named key[0], key[1], ...
+// String features are categorical (so options need an Encoder) and null or absent values are missing.
+// It returns patterns and the class of each mapped value (nil for regression).
+func ReadPatternsFromJSONLines(r io.Reader, options CSVOptions) ([]Pattern, error, []string) {
+	var header []string
+	var columns map[string]int
+	var records [][]string
+	var lines []int
+	var categorical []bool
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		keys, values, isString, errorValue := jsonLineFields(text)
+		if errorValue != nil {
+			return nil, fmt.Errorf("line %d: %w", line, errorValue), nil
+		}
+		if header == nil {
+			header, columns = keys, make(map[string]int)
+			categorical = make([]bool, len(keys))
+			for index, key := range keys {
+				columns[key] = index
+			}
+		}
+
+		record := make([]string, len(header))
+		for index, key := range keys {
+			column, found := columns[key]
+			if !found {
+				return nil, fmt.Errorf("line %d: key %q not in first object", line, key), nil
+			}
+			record[column] = values[index]
+			categorical[column] = categorical[column] || isString[index]
+		}
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	if errorValue := scanner.Err(); errorValue != nil {
+		return nil, errorValue, nil
+	}
+
+	options.Header = true
+	options.MissingValues = append(append([]string{}, options.MissingValues...), "")
+	types := make(map[int]ColumnType)
+	for index, columnType := range options.ColumnTypes {
+		types[index] = columnType
+	}
+	for index, isString := range categorical {
+		if _, declared := types[index]; isString && !declared {
+			types[index] = CategoricalColumn
+		}
+	}
+	options.ColumnTypes = types
+
+	schema := &csvSchema{options: options, header: header}
+	return schema.patterns(records, lines)
+}
+
+// jsonLineFields decodes a flat JSON object keeping the order of its keys.
+// It returns keys, values as text ("" for null), and whether each value is a string.
+func jsonLineFields(text []byte) ([]string, []string, []bool, error) {
+	var keys, values []string
+	var isString []bool
+	decoder := json.NewDecoder(bytes.NewReader(text))
+	decoder.UseNumber()
+	if token, errorValue := decoder.Token(); errorValue != nil || token != json.Delim('{') {
+		return nil, nil, nil, fmt.Errorf("expected a JSON object")
+	}
+	for decoder.More() {
+		token, errorValue := decoder.Token()
+		if errorValue != nil {
+			return nil, nil, nil, errorValue
+		}
+		key := token.(string)
+		var value interface{}
+		if errorValue = decoder.Decode(&value); errorValue != nil {
+			return nil, nil, nil, fmt.Errorf("key %q: %w", key, errorValue)
+		}
+		if array, ok := value.([]interface{}); ok {
+			for index, element := range array {
+				text, _, errorValue := jsonScalar(element)
+				if errorValue != nil {
+					return nil, nil, nil, fmt.Errorf("key %q[%d]: %w", key, index, errorValue)
+				}
+				keys, values, isString = append(keys, fmt.Sprintf("%s[%d]", key, index)), append(values, text), append(isString, false)
+			}
+			continue
+		}
+		text, stringValue, errorValue := jsonScalar(value)
+		if errorValue != nil {
+			return nil, nil, nil, fmt.Errorf("key %q: %w", key, errorValue)
+		}
+		keys, values, isString = append(keys, key), append(values, text), append(isString, stringValue)
+	}
+	return keys, values, isString, nil
+}
+
+// jsonScalar converts a decoded JSON scalar to text: numbers as written, booleans as 1 or 0, null as "".
+func jsonScalar(value interface{}) (string, bool, error) {
+	switch typed := value.(type) {
+	case nil:
+		return "", false, nil
+	case json.Number:
+		return typed.String(), false, nil
+	case bool:
+		if typed {
+			return "1", false, nil
+		}
+		return "0", false, nil
+	case string:
+		return typed, true, nil
+	}
+	return "", false, fmt.Errorf("nested values are not supported")
+}
+
+// WritePatternsToJSONLines writes patterns as JSON Lines, one object for each pattern with keys
+// feature1, feature2, ..., the categorical columns of encoder (category1, ... if nil) and class,
+// or target1, target2, ... if mapped is nil. Missing values are written as null.
+// It returns an error wrapping ErrEmptyDataset without patterns and a ClassError for classes not in mapped.
+func WritePatternsToJSONLines(w io.Writer, patterns []Pattern, mapped []string, encoder *Encoder) error {
+	if len(patterns) == 0 {
+		return emptyDatasetError("WritePatternsToJSONLines")
+	}
+	writer := bufio.NewWriter(w)
+	columns := writtenCategoricalColumns(patterns, encoder)
+	for index, pattern := range patterns {
+		var fields []string
+		for f, value := range pattern.Features {
+			fields = append(fields, fmt.Sprintf("%s:%s", jsonString(fmt.Sprintf("feature%d", f+1)), jsonNumber(value)))
+		}
+		for c, column := range columns {
+			value := "null"
+			if category := categoryAt(&pattern, c); category != "" {
+				value = jsonString(category)
+			}
+			fields = append(fields, jsonString(column.Name)+":"+value)
+		}
+		if mapped != nil {
+			class := int(pattern.SingleExpectation)
+			if class < 0 || class >= len(mapped) {
+				return fmt.Errorf("pattern %d: %w", index, &ClassError{Class: pattern.SingleExpectation, Classes: len(mapped)})
+			}
+			fields = append(fields, `"class":`+jsonString(mapped[class]))
+		} else if pattern.MultipleExpectation != nil {
+			for t, value := range pattern.MultipleExpectation {
+				fields = append(fields, fmt.Sprintf(`"target%d":%s`, t+1, jsonNumber(value)))
+			}
+		} else {
+			fields = append(fields, `"target1":`+jsonNumber(pattern.SingleExpectation))
+		}
+		writer.WriteString("{" + strings.Join(fields, ",") + "}\n")
+	}
+	return writer.Flush()
+}
+
+// jsonString encodes a string as JSON.
+func jsonString(value string) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// jsonNumber encodes a number as JSON, null if missing or infinite.
+func jsonNumber(value float64) string {
+	if IsMissing(value) || math.IsInf(value, 0) {
+		return "null"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
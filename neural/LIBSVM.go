@@ -0,0 +1,135 @@
+package neural
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LoadPatternsFromLIBSVMFile load a LIBSVM/SVMlight sparse dataset into an array of Pattern.
+// See ReadPatternsFromLIBSVM for arguments.
+func LoadPatternsFromLIBSVMFile(filePath string, features int, regression bool) ([]Pattern, error, []string) {
+	return loadPatternsFile(filePath, "LoadPatternsFromLIBSVMFile", loadingLog, func(r io.Reader) ([]Pattern, error, []string) {
+		return ReadPatternsFromLIBSVM(r, features, regression)
+	})
+}
+
+// ReadPatternsFromLIBSVM reads a LIBSVM/SVMlight sparse dataset, with lines as "<label> <index>:<value> ...",
+// 1-based indexes and comments after '#'. Features not listed in a line are 0.
+// [features:int] is the number of features (0 to use the largest index found).
+// [regression:bool] parses labels as continuous targets stored in MultipleExpectation instead of classes.
+// It returns patterns and the class of each mapped value (nil for regression).
+func ReadPatternsFromLIBSVM(r io.Reader, features int, regression bool) ([]Pattern, error, []string) {
+	var patterns []Pattern
+	var entries [][]int
+	var values [][]float64
+	largest := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if comment := strings.IndexByte(text, '#'); comment >= 0 {
+			text = text[:comment]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var pattern Pattern
+		if regression {
+			target, errorValue := strconv.ParseFloat(fields[0], 64)
+			if errorValue != nil {
+				return nil, fmt.Errorf("line %d: cannot parse label %q as float: %w", line, fields[0], errorValue), nil
+			}
+			pattern.SingleExpectation, pattern.MultipleExpectation = target, []float64{target}
+		} else {
+			pattern.SingleRawExpectation = fields[0]
+		}
+
+		indexes, row := make([]int, 0, len(fields)-1), make([]float64, 0, len(fields)-1)
+		for _, field := range fields[1:] {
+			separator := strings.IndexByte(field, ':')
+			if separator < 0 {
+				return nil, fmt.Errorf("line %d: expected <index>:<value>, got %q", line, field), nil
+			}
+			index, errorValue := strconv.Atoi(field[:separator])
+			if errorValue != nil || index < 1 {
+				return nil, fmt.Errorf("line %d: invalid feature index in %q", line, field), nil
+			}
+			value, errorValue := strconv.ParseFloat(field[separator+1:], 64)
+			if errorValue != nil {
+				return nil, fmt.Errorf("line %d, feature %d: cannot parse %q as float: %w", line, index, field[separator+1:], errorValue), nil
+			}
+			if features > 0 && index > features {
+				return nil, fmt.Errorf("line %d: feature index %d greater than %d features", line, index, features), nil
+			}
+			if index > largest {
+				largest = index
+			}
+			indexes, row = append(indexes, index-1), append(row, value)
+		}
+		patterns = append(patterns, pattern)
+		entries, values = append(entries, indexes), append(values, row)
+	}
+	if errorValue := scanner.Err(); errorValue != nil {
+		return nil, errorValue, nil
+	}
+
+	if features <= 0 {
+		features = largest
+	}
+	for index := range patterns {
+		patterns[index].Features = make([]float64, features)
+		for e, feature := range entries[index] {
+			patterns[index].Features[feature] = values[index][e]
+		}
+	}
+
+	if regression {
+		return patterns, nil, nil
+	}
+	return patterns, nil, RawExpectedConversion(patterns)
+}
+
+// WritePatternsToLIBSVM writes patterns in LIBSVM/SVMlight sparse format, omitting zero features.
+// Labels are the class names in mapped, or SingleExpectation values if mapped is nil.
+// It returns an error wrapping ErrEmptyDataset without patterns, a ClassError for classes not in mapped
+// and an error for patterns with missing values or categorical features, which LIBSVM can't represent.
+func WritePatternsToLIBSVM(w io.Writer, patterns []Pattern, mapped []string) error {
+	if len(patterns) == 0 {
+		return emptyDatasetError("WritePatternsToLIBSVM")
+	}
+	writer := bufio.NewWriter(w)
+	for index, pattern := range patterns {
+		if len(pattern.Categories) > 0 {
+			return fmt.Errorf("pattern %d: categorical features can't be written in LIBSVM format", index)
+		}
+		label := strconv.FormatFloat(pattern.SingleExpectation, 'g', -1, 64)
+		if mapped != nil {
+			class := int(pattern.SingleExpectation)
+			if class < 0 || class >= len(mapped) {
+				return fmt.Errorf("pattern %d: %w", index, &ClassError{Class: pattern.SingleExpectation, Classes: len(mapped)})
+			}
+			label = mapped[class]
+		}
+		if strings.ContainsAny(label, " \t#") {
+			return fmt.Errorf("pattern %d: label %q can't be written in LIBSVM format", index, label)
+		}
+		writer.WriteString(label)
+		for f, value := range pattern.Features {
+			if IsMissing(value) || math.IsInf(value, 0) {
+				return fmt.Errorf("pattern %d, feature %d: value %v can't be written in LIBSVM format", index, f+1, value)
+			}
+			if value != 0.0 {
+				fmt.Fprintf(writer, " %d:%s", f+1, strconv.FormatFloat(value, 'g', -1, 64))
+			}
+		}
+		writer.WriteByte('\n')
+	}
+	return writer.Flush()
+}
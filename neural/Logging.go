@@ -0,0 +1,12 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+)
+
+var (
+	// loadingLog writes entries of pattern, dataset and model loading
+	loadingLog = logging.For(logging.Loading)
+	// trainingLog writes entries of model training and execution
+	trainingLog = logging.For(logging.Training)
+)
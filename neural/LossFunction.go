@@ -0,0 +1,42 @@
+package neural
+
+import (
+	"math"
+)
+
+type lossFunction func(expected float64, predicted float64) float64
+
+// MeanSquaredLoss returns the squared error between expected and predicted value.
+func MeanSquaredLoss(expected float64, predicted float64) float64 {
+	return math.Pow(expected-predicted, 2)
+}
+
+// MeanSquaredLossGradient returns the error signal of squared error loss.
+func MeanSquaredLossGradient(expected float64, predicted float64) float64 {
+	return expected - predicted
+}
+
+// HuberLoss returns a Huber loss function, quadratic for errors up to delta and linear beyond.
+func HuberLoss(delta float64) lossFunction {
+	return func(expected float64, predicted float64) float64 {
+		e := math.Abs(expected - predicted)
+		if e <= delta {
+			return 0.5 * e * e
+		}
+		return delta * (e - 0.5*delta)
+	}
+}
+
+// HuberLossGradient returns the error signal of Huber loss, clipped to [-delta, delta].
+func HuberLossGradient(delta float64) lossFunction {
+	return func(expected float64, predicted float64) float64 {
+		e := expected - predicted
+		if e > delta {
+			return delta
+		}
+		if e < -delta {
+			return -delta
+		}
+		return e
+	}
+}
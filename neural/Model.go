@@ -0,0 +1,210 @@
+package neural
+
+import (
+	"MultilayerPerceptron/util"
+	"math"
+)
+
+// Model is a trainable classifier, so that validation and tuning are written once for every kind of network.
+// It is implemented by *Perceptron, *MultiLayerNetwork, *ElmanNetwork and *RecurrentNetwork.
+// A bare NeuronUnit is not a Model: Perceptron is the single neuron model, wrapping a NeuronUnit
+// together with the preprocessing and class labels that Fit and Predict need.
+type Model interface {
+	// Fit fits Imputer, Encoder and Scaler of the model (if set) on patterns, then trains the model for epochs
+	// toward the class of each pattern, an index of classes in SingleExpectation.
+	// With nil classes, multi output networks are trained toward MultipleExpectation instead.
+	Fit(patterns []Pattern, classes []string, epochs int) error
+	// Predict returns the class index predicted for pattern.
+	Predict(pattern *Pattern) (float64, error)
+	// PredictProba returns the probability of each class for pattern. Multi output networks
+	// trained without classes return the independent probability of each output.
+	PredictProba(pattern *Pattern) ([]float64, error)
+	// Clone returns an untrained model with the same configuration.
+	Clone() Model
+	// Reset reinitializes the learned parameters, so that the model can be trained again from scratch.
+	Reset()
+}
+
+// SequenceModel is a recurrent model trained on whole sequences, so that sequence evaluation
+// is written once for every recurrent network. It is implemented by *ElmanNetwork and *RecurrentNetwork, whatever its layers.
+type SequenceModel interface {
+	Model
+	// FitSequences fits Imputer, Encoder and Scaler of the model (if set) on the steps of sequences holding data,
+	// then trains the model for epochs toward classes as Fit does. The state is reset after training.
+	FitSequences(sequences []Sequence, classes []string, epochs int) error
+	// Step executes the model on pattern, the next step of the current sequence, returning its outputs.
+	Step(pattern *Pattern) ([]float64, error)
+	// ResetState starts a new sequence.
+	ResetState()
+}
+
+// every model of the package satisfies the interfaces above
+var (
+	_ Model         = (*Perceptron)(nil)
+	_ Model         = (*MultiLayerNetwork)(nil)
+	_ SequenceModel = (*ElmanNetwork)(nil)
+	_ SequenceModel = (*RecurrentNetwork)(nil)
+)
+
+// Fit trains the network on one-hot targets of classes with MLPTrain,
+// or on MultipleExpectation with MLPRegressionTrain if classes is nil.
+func (multiLayerPerceptron *MultiLayerNetwork) Fit(patterns []Pattern, classes []string, epochs int) error {
+	var errorValue error
+	if multiLayerPerceptron.Imputer, multiLayerPerceptron.Encoder, multiLayerPerceptron.Scaler, errorValue = FitPreprocessing(
+		multiLayerPerceptron.Imputer, multiLayerPerceptron.Encoder, multiLayerPerceptron.Scaler, patterns); errorValue != nil {
+		return errorValue
+	}
+	if classes == nil {
+		return MLPRegressionTrain(multiLayerPerceptron, patterns, epochs)
+	}
+	return MLPTrain(multiLayerPerceptron, patterns, classes, epochs)
+}
+
+// Predict returns the index of the max output of the network.
+func (multiLayerPerceptron *MultiLayerNetwork) Predict(pattern *Pattern) (float64, error) {
+	output, errorValue := Execute(multiLayerPerceptron, pattern)
+	if errorValue != nil {
+		return 0.0, errorValue
+	}
+	_, indexMaxOut := util.MaxInSlice(output)
+	return float64(indexMaxOut), nil
+}
+
+// PredictProba returns the outputs of the network, normalized to sum 1 if the network was trained on classes.
+func (multiLayerPerceptron *MultiLayerNetwork) PredictProba(pattern *Pattern) ([]float64, error) {
+	output, errorValue := Execute(multiLayerPerceptron, pattern)
+	if errorValue != nil {
+		return nil, errorValue
+	}
+	if multiLayerPerceptron.Classes != nil {
+		normalize(output)
+	}
+	return output, nil
+}
+
+// Clone returns a network with the same layers and settings, with new random weights.
+func (multiLayerPerceptron *MultiLayerNetwork) Clone() Model {
+	clone := cloneMLPNet(multiLayerPerceptron)
+	return &clone
+}
+
+// Reset re-initializes weights and bias of every neuron with ResetMLPNet.
+func (multiLayerPerceptron *MultiLayerNetwork) Reset() {
+	ResetMLPNet(multiLayerPerceptron)
+}
+
+// Fit trains the recurrent network with RecurrentTrainSequences, feeding patterns in order as a single sequence,
+// and the state is reset after training so that predictions start a new sequence.
+func (network *RecurrentNetwork) Fit(patterns []Pattern, classes []string, epochs int) error {
+	return network.FitSequences([]Sequence{{Steps: patterns}}, classes, epochs)
+}
+
+// FitSequences trains the recurrent network on sequences with RecurrentTrainSequences.
+func (network *RecurrentNetwork) FitSequences(sequences []Sequence, classes []string, epochs int) error {
+	var errorValue error
+	if network.Imputer, network.Encoder, network.Scaler, errorValue = FitPreprocessing(network.Imputer, network.Encoder, network.Scaler, sequenceSteps(sequences)); errorValue != nil {
+		return errorValue
+	}
+	network.Classes = classes
+	errorValue = RecurrentTrainSequences(network, sequences, classes, epochs)
+	network.ResetState()
+	return errorValue
+}
+
+// Predict returns the index of the max output of the network, as the next step of the current sequence.
+func (network *RecurrentNetwork) Predict(pattern *Pattern) (float64, error) {
+	output, errorValue := network.Step(pattern)
+	if errorValue != nil {
+		return 0.0, errorValue
+	}
+	_, indexMaxOut := util.MaxInSlice(output)
+	return float64(indexMaxOut), nil
+}
+
+// PredictProba returns the outputs of the network as the next step of the current sequence,
+// normalized to sum 1 if the network was trained on classes.
+func (network *RecurrentNetwork) PredictProba(pattern *Pattern) ([]float64, error) {
+	output, errorValue := network.Step(pattern)
+	if errorValue != nil {
+		return nil, errorValue
+	}
+	if network.Classes != nil {
+		normalize(output)
+	}
+	return output, nil
+}
+
+// Clone returns a recurrent network with the same layers and settings, with new random weights.
+func (network *RecurrentNetwork) Clone() Model {
+	clone := *network
+	clone.Layers = make([]RecurrentLayer, len(network.Layers))
+	for i, layer := range network.Layers {
+		clone.Layers[i] = layer.Clone()
+	}
+	clone.OutputWeights = make([][]float64, len(network.OutputWeights))
+	for j := range clone.OutputWeights {
+		clone.OutputWeights[j] = make([]float64, len(network.OutputWeights[j]))
+	}
+	clone.OutputBias = make([]float64, len(network.OutputBias))
+	clone.Classes = nil
+	clone.Reset()
+	return &clone
+}
+
+// Reset re-initializes weights and bias of every layer and of output neurons, and resets the state.
+func (network *RecurrentNetwork) Reset() {
+	for _, layer := range network.Layers {
+		layer.Reset()
+	}
+	if len(network.OutputWeights) > 0 {
+		randomizeMatrix(network.OutputWeights, len(network.OutputWeights[0]))
+	}
+	for j := range network.OutputBias {
+		network.OutputBias[j] = 0.0
+	}
+	network.outputGradients, network.biasGradients = nil, nil
+	network.truncate()
+	network.ResetState()
+}
+
+// sequenceSteps returns the steps of sequences holding data, to fit preprocessing on.
+func sequenceSteps(sequences []Sequence) []Pattern {
+	var steps []Pattern
+	for s := range sequences {
+		for t := range sequences[s].Steps {
+			if !sequences[s].Masked(t) {
+				steps = append(steps, sequences[s].Steps[t])
+			}
+		}
+	}
+	return steps
+}
+
+// cloneMLPNet returns a copy of the network with its own untrained layers.
+func cloneMLPNet(multiLayerPerceptron *MultiLayerNetwork) MultiLayerNetwork {
+	clone := *multiLayerPerceptron
+	clone.NeuralLayers = make([]NeuralLayer, len(multiLayerPerceptron.NeuralLayers))
+	for iLayer, layer := range multiLayerPerceptron.NeuralLayers {
+		clone.NeuralLayers[iLayer] = NeuralLayer{NeuronUnits: make([]NeuronUnit, len(layer.NeuronUnits)), Length: layer.Length}
+	}
+	clone.Classes = nil
+	// ResetMLPNet allocates new weights sized to the previous layer
+	ResetMLPNet(&clone)
+	return clone
+}
+
+// normalize divides values by their sum, so that they can be read as probabilities.
+// Values summing to zero become uniform.
+func normalize(values []float64) {
+	sum := 0.0
+	for _, value := range values {
+		sum += math.Max(value, 0.0)
+	}
+	for index, value := range values {
+		if sum > 0.0 {
+			values[index] = math.Max(value, 0.0) / sum
+		} else {
+			values[index] = 1.0 / float64(len(values))
+		}
+	}
+}
@@ -1,22 +1,13 @@
 package neural
 
 import (
-	"MultilayerPerceptron/util"
-	log "github.com/sirupsen/logrus"
+	"MultilayerPerceptron/logging"
+	"fmt"
+	"io"
 	"math"
-	"math/rand"
-	"os"
 	_ "os"
-	"time"
 )
 
-func init() {
-	// Output to stdout instead of the default stderr
-	log.SetOutput(os.Stdout)
-	// Only log the warning severity or above.
-	log.SetLevel(log.InfoLevel)
-}
-
 type MultiLayerNetwork struct {
 	// layer of neurons
 	NeuralLayers []NeuralLayer
@@ -24,8 +15,32 @@ type MultiLayerNetwork struct {
 	LearningRate float64
 	// transfer function
 	TransferFunction transferFunction
-	// transfer function derivative
+	// transfer function derivative, computed from the output of the transfer function
 	TransferFunctionDerivative transferFunction
+	// transfer function of output layer (if nil, TransferFunction is used)
+	OutputTransferFunction transferFunction
+	// output layer transfer function derivative (if nil, TransferFunctionDerivative is used)
+	OutputTransferFunctionDerivative transferFunction
+	// loss function used to measure delta error (if nil, mean absolute error is used)
+	LossFunction lossFunction
+	// loss function gradient used as output error signal (if nil, expected - output is used)
+	LossFunctionGradient lossFunction
+	// L2 regularization strength (weight decay) applied to weights at each update
+	Regularization float64
+	// number of patterns whose updates are averaged before changing weights (0 or 1 for online learning)
+	BatchSize int
+	// number of training epochs completed, so that training can be resumed
+	Epoch int
+	// feature scaler applied to every input before execution (nil for raw inputs)
+	Scaler *Scaler
+	// categorical feature encoder applied to every input before scaling (nil without categorical features)
+	Encoder *Encoder
+	// missing value imputer applied to every input before encoding (nil without missing values)
+	Imputer *Imputer
+	// class label of each output, set by training (nil for regression)
+	Classes []string
+	// logger and levels of execution and training, instead of the ones set by logging.Configure (nil to use them)
+	Logging *logging.Options
 }
 
 // PrepareMLPNet create a multi layer Perceptron neural network.
@@ -33,11 +48,26 @@ type MultiLayerNetwork struct {
 // [learningRate:int] is the learning rate of neural network
 // [tf:transferFunction] is a transfer function
 // [tfd:transferFunction] the respective transfer function derivative
+// If layer has less than 2 layers or a layer without neurons, the network has no layers,
+// so that Execute and training return a ConfigError.
 func PrepareMLPNet(layer []int, learningRate float64, tf transferFunction, tfd transferFunction) (multiLayerPerceptron MultiLayerNetwork) {
 	multiLayerPerceptron.LearningRate = learningRate
 	multiLayerPerceptron.TransferFunction = tf
 	multiLayerPerceptron.TransferFunctionDerivative = tfd
 
+	valid := len(layer) >= 2
+	for _, neurons := range layer {
+		valid = valid && neurons > 0
+	}
+	if !valid {
+		trainingLog.WithFields(logging.Fields{
+			"level":  "error",
+			"msg":    "multilayer perceptron init failed",
+			"layers": layer,
+		}).Error("Invalid layer sizes of Multilayer Perceptron.")
+		return
+	}
+
 	multiLayerPerceptron.NeuralLayers = make([]NeuralLayer, len(layer))
 
 	for iLayer, jLayer := range layer {
@@ -47,7 +77,7 @@ func PrepareMLPNet(layer []int, learningRate float64, tf transferFunction, tfd t
 			multiLayerPerceptron.NeuralLayers[iLayer] = PrepareLayer(jLayer, 0)
 		}
 	}
-	log.WithFields(log.Fields{
+	trainingLog.WithFields(logging.Fields{
 		"level":          "info",
 		"msg":            "multilayer perceptron init completed",
 		"layers":         len(multiLayerPerceptron.NeuralLayers),
@@ -57,90 +87,124 @@ func PrepareMLPNet(layer []int, learningRate float64, tf transferFunction, tfd t
 	return
 }
 
-// PrepareElmanNet create a recurrent neural network.
-// [inputLayer:int]
-// [hiddenLayer:int]
-// [outputLayer:int]
+// ResetMLPNet re-initialize weights and bias of every neuron of a multi layer Perceptron neural network,
+// so that the same network can be trained again from scratch.
+// [multiLayerPerceptron:MultiLayerNetwork] multilayer perceptron network pointer
+func ResetMLPNet(multiLayerPerceptron *MultiLayerNetwork) {
+	multiLayerPerceptron.Epoch = 0
+	for iLayer := range multiLayerPerceptron.NeuralLayers {
+		previousLength := 0
+		if iLayer != 0 {
+			previousLength = multiLayerPerceptron.NeuralLayers[iLayer-1].Length
+		}
+		for iNeuron := range multiLayerPerceptron.NeuralLayers[iLayer].NeuronUnits {
+			RandomNeuronInit(&multiLayerPerceptron.NeuralLayers[iLayer].NeuronUnits[iNeuron], previousLength)
+		}
+	}
+}
+
+// PrepareRegressionMLPNet create a multi layer Perceptron neural network with a linear output layer.
+// [layer:[]int] is an int array with layers neurons number [input, ..., output]
 // [learningRate:int] is the learning rate of neural network
-// [tf:transferFunction] is a transfer function
+// [tf:transferFunction] is the transfer function of hidden layers
 // [tfd:transferFunction] the respective transfer function derivative
-func PrepareElmanNet(inputLayer int, hiddenLayer int, outputLayer int, learningRate float64, tf transferFunction, tfd transferFunction) (rnn MultiLayerNetwork) {
-	rnn = PrepareMLPNet([]int{inputLayer, hiddenLayer, outputLayer}, learningRate, tf, tfd)
+// [loss:lossFunction] is the loss function (MeanSquaredLoss, HuberLoss(delta))
+// [lossGradient:lossFunction] the respective loss function gradient
+func PrepareRegressionMLPNet(layer []int, learningRate float64, tf transferFunction, tfd transferFunction, loss lossFunction, lossGradient lossFunction) (multiLayerPerceptron MultiLayerNetwork) {
+	multiLayerPerceptron = PrepareMLPNet(layer, learningRate, tf, tfd)
+	multiLayerPerceptron.OutputTransferFunction = LinearTransfer
+	multiLayerPerceptron.OutputTransferFunctionDerivative = LinearTransferDerivative
+	multiLayerPerceptron.LossFunction = loss
+	multiLayerPerceptron.LossFunctionGradient = lossGradient
 
-	log.WithFields(log.Fields{
+	trainingLog.WithFields(logging.Fields{
 		"level":          "info",
-		"msg":            "recurrent neural network init completed",
-		"inputLayer":     inputLayer,
-		"hiddenLayer":    hiddenLayer,
-		"outputLayer":    outputLayer,
-		"learningRate: ": rnn.LearningRate,
-	}).Info("Complete RNN init.")
+		"msg":            "regression multilayer perceptron init completed",
+		"layers":         len(multiLayerPerceptron.NeuralLayers),
+		"learningRate: ": multiLayerPerceptron.LearningRate,
+	}).Info("Complete regression Multilayer Perceptron init.")
+
 	return
 }
 
 // Execute a multi layer Perceptron neural network.
 // [multiLayerPerceptron:MultiLayerNetwork] multilayer perceptron network pointer,
 // [input:Pattern] input value
-// It returns output values by network
-func Execute(multiLayerPerceptron *MultiLayerNetwork, input *Pattern, options ...int) (output []float64) {
-	output = make([]float64, multiLayerPerceptron.NeuralLayers[len(multiLayerPerceptron.NeuralLayers)-1].Length)
-
-	for i := 0; i < len(input.Features); i++ {
-		multiLayerPerceptron.NeuralLayers[0].NeuronUnits[i].Value = input.Features[i]
+// It returns output values by network, a ConfigError for a network without layers or a ShapeError
+// if input features don't match the input layer.
+func Execute(multiLayerPerceptron *MultiLayerNetwork, input *Pattern) (output []float64, errorValue error) {
+	if errorValue = setInputLayer(multiLayerPerceptron, input); errorValue != nil {
+		return nil, errorValue
 	}
+	feedForward(multiLayerPerceptron)
+	return outputValues(multiLayerPerceptron), nil
+}
 
-	for i := len(input.Features); i < multiLayerPerceptron.NeuralLayers[0].Length; i++ {
-		multiLayerPerceptron.NeuralLayers[0].NeuronUnits[i].Value = 0.5
+// setInputLayer loads the preprocessed features of input into the input layer.
+// It returns a ConfigError for a network without layers or a ShapeError if features don't match the input layer.
+func setInputLayer(multiLayerPerceptron *MultiLayerNetwork, input *Pattern) error {
+	if len(multiLayerPerceptron.NeuralLayers) < 2 {
+		return &ConfigError{Field: "NeuralLayers", Reason: fmt.Sprintf("has %d layers, at least 2 needed", len(multiLayerPerceptron.NeuralLayers))}
 	}
+	features := modelFeatures(multiLayerPerceptron.Imputer, multiLayerPerceptron.Encoder, multiLayerPerceptron.Scaler, input)
+	if len(features) != multiLayerPerceptron.NeuralLayers[0].Length {
+		return &ShapeError{What: "input features", Expected: multiLayerPerceptron.NeuralLayers[0].Length, Actual: len(features)}
+	}
+	for i := 0; i < len(features); i++ {
+		multiLayerPerceptron.NeuralLayers[0].NeuronUnits[i].Value = features[i]
+	}
+	return nil
+}
+
+// feedForward computes the values of hidden and output layers from the values of the input layer.
+func feedForward(multiLayerPerceptron *MultiLayerNetwork) {
+	// entries of single neurons are only built when tracing
+	tracing := multiLayerPerceptron.log().Enabled(logging.TraceLevel)
 
 	// todo: reduce time complexity
 	for i := 1; i < len(multiLayerPerceptron.NeuralLayers); i++ {
+		tf := multiLayerPerceptron.TransferFunction
+		if i == len(multiLayerPerceptron.NeuralLayers)-1 && multiLayerPerceptron.OutputTransferFunction != nil {
+			tf = multiLayerPerceptron.OutputTransferFunction
+		}
 		for j := 0; j < multiLayerPerceptron.NeuralLayers[i].Length; j++ {
 			newValue := 0.0
 			for k := 0; k < multiLayerPerceptron.NeuralLayers[i-1].Length; k++ {
 				newValue += multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j].Weights[k] * multiLayerPerceptron.NeuralLayers[i-1].NeuronUnits[k].Value
-				log.WithFields(log.Fields{
-					"level":                 "debug",
-					"msg":                   "multilayer perceptron execution",
+				if tracing {
+					multiLayerPerceptron.log().WithFields(logging.Fields{
+						"level":                 "trace",
+						"msg":                   "multilayer perceptron execution",
+						"len(mlp.NeuralLayers)": len(multiLayerPerceptron.NeuralLayers),
+						"layer:  ":              i,
+						"neuron: ":              j,
+						"previous neuron: ":     k,
+					}).Trace("Compute output propagation.")
+				}
+			}
+			newValue += multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j].Bias
+			multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j].Value = tf(newValue)
+			if tracing {
+				multiLayerPerceptron.log().WithFields(logging.Fields{
+					"level":                 "trace",
+					"msg":                   "setup new neuron output value after transfer function application",
 					"len(mlp.NeuralLayers)": len(multiLayerPerceptron.NeuralLayers),
 					"layer:  ":              i,
 					"neuron: ":              j,
-					"previous neuron: ":     k,
-				}).Debug("Compute output propagation.")
-			}
-			newValue += multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j].Bias
-			multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j].Value = multiLayerPerceptron.TransferFunction(newValue)
-			if i == 1 && len(options) > 0 && options[0] == 1 {
-				for j := len(input.Features); j < multiLayerPerceptron.NeuralLayers[0].Length; j++ {
-					log.WithFields(log.Fields{
-						"level":                               "debug",
-						"len z":                               j,
-						"s.Features":                          input.Features,
-						"len(s.Features)":                     len(input.Features),
-						"len mlp.NeuralLayers[0].NeuronUnits": len(multiLayerPerceptron.NeuralLayers[0].NeuronUnits),
-						"len mlp.NeuralLayers[k].NeuronUnits": len(multiLayerPerceptron.NeuralLayers[i].NeuronUnits),
-					}).Debug("Save output of hidden layer to context.")
-
-					multiLayerPerceptron.NeuralLayers[0].NeuronUnits[j].Value = multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j-len(input.Features)].Value
-
-				}
-
+					"output-value":          multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j].Value,
+				}).Trace("Setup new neuron output value after transfer function application.")
 			}
-			log.WithFields(log.Fields{
-				"level":                 "debug",
-				"msg":                   "setup new neuron output value after transfer function application",
-				"len(mlp.NeuralLayers)": len(multiLayerPerceptron.NeuralLayers),
-				"layer:  ":              i,
-				"neuron: ":              j,
-				"output-value":          multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j].Value,
-			}).Debug("Setup new neuron output value after transfer function application.")
 		}
 	}
+}
 
-	for i := 0; i < multiLayerPerceptron.NeuralLayers[len(multiLayerPerceptron.NeuralLayers)-1].Length; i++ {
-		output[i] = multiLayerPerceptron.NeuralLayers[len(multiLayerPerceptron.NeuralLayers)-1].NeuronUnits[i].Value
+// outputValues returns a copy of the values of the output layer.
+func outputValues(multiLayerPerceptron *MultiLayerNetwork) []float64 {
+	outputLayer := multiLayerPerceptron.NeuralLayers[len(multiLayerPerceptron.NeuralLayers)-1]
+	output := make([]float64, outputLayer.Length)
+	for i := range output {
+		output[i] = outputLayer.NeuronUnits[i].Value
 	}
-
 	return output
 }
 
@@ -149,117 +213,381 @@ func Execute(multiLayerPerceptron *MultiLayerNetwork, input *Pattern, options ..
 // [input:Pattern] input value (scaled between 0 and 1)
 // [expectedOutput:[]float64] expected output value (scaled between 0 and 1)
 // return [deltaError:float64] delta error between generated output and expected output
-func BackPropagate(multiLayerPerceptron *MultiLayerNetwork, input *Pattern, expectedOutput []float64, options ...int) (deltaError float64) {
-	var newExpectedOutput []float64
-	if len(options) == 1 {
-		newExpectedOutput = Execute(multiLayerPerceptron, input, options[0])
-	} else {
-		newExpectedOutput = Execute(multiLayerPerceptron, input, options[0])
+// return [errorValue:error] errors of Execute, or a ShapeError if expectedOutput doesn't match the output layer
+func BackPropagate(multiLayerPerceptron *MultiLayerNetwork, input *Pattern, expectedOutput []float64) (deltaError float64, errorValue error) {
+	if deltaError, errorValue = propagateDeltas(multiLayerPerceptron, input, expectedOutput); errorValue != nil {
+		return
 	}
+	updateWeights(multiLayerPerceptron)
+	return
+}
 
-	errorValue := 0.0
-	for i := 0; i < multiLayerPerceptron.NeuralLayers[len(multiLayerPerceptron.NeuralLayers)-1].Length; i++ {
-		errorValue = expectedOutput[i] - newExpectedOutput[i]
-		multiLayerPerceptron.NeuralLayers[len(multiLayerPerceptron.NeuralLayers)-1].NeuronUnits[i].Delta = errorValue * multiLayerPerceptron.TransferFunctionDerivative(newExpectedOutput[i])
+// updateWeights moves weights and bias of each neuron along its Delta and the values of the previous layer.
+func updateWeights(multiLayerPerceptron *MultiLayerNetwork) {
+	// todo: reduce time complexity
+	for i := 1; i < len(multiLayerPerceptron.NeuralLayers); i++ {
+		for j := 0; j < multiLayerPerceptron.NeuralLayers[i].Length; j++ {
+			neuron := &multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j]
+			for k := 0; k < multiLayerPerceptron.NeuralLayers[i-1].Length; k++ {
+				neuron.Weights[k] += multiLayerPerceptron.LearningRate * (neuron.Delta*multiLayerPerceptron.NeuralLayers[i-1].NeuronUnits[k].Value - multiLayerPerceptron.Regularization*neuron.Weights[k])
+			}
+			neuron.Bias += multiLayerPerceptron.LearningRate * neuron.Delta
+		}
+	}
+}
+
+// propagateDeltas executes the network on input and propagates backward the error signal,
+// storing it in Delta of each neuron without updating weights.
+// It returns the delta error between generated output and expected output.
+func propagateDeltas(multiLayerPerceptron *MultiLayerNetwork, input *Pattern, expectedOutput []float64) (deltaError float64, errorValue error) {
+	output, errorValue := Execute(multiLayerPerceptron, input)
+	if errorValue != nil {
+		return
 	}
+	return backwardDeltas(multiLayerPerceptron, output, expectedOutput)
+}
 
+// backwardDeltas propagates backward the error signal between output, the values of the output layer,
+// and expectedOutput, storing it in Delta of each neuron.
+// It returns the delta error between output and expected output, or a ShapeError if expectedOutput doesn't match the output layer.
+func backwardDeltas(multiLayerPerceptron *MultiLayerNetwork, output []float64, expectedOutput []float64) (deltaError float64, errorValue error) {
+	deltas, deltaError, errorValue := outputDeltas(multiLayerPerceptron, output, expectedOutput)
+	if errorValue != nil {
+		return 0.0, errorValue
+	}
+	for i, delta := range deltas {
+		multiLayerPerceptron.NeuralLayers[len(multiLayerPerceptron.NeuralLayers)-1].NeuronUnits[i].Delta = delta
+	}
+
+	signal := 0.0
 	// todo: reduce time complexity
 	for i := len(multiLayerPerceptron.NeuralLayers) - 2; i >= 0; i-- {
 		for j := 0; j < multiLayerPerceptron.NeuralLayers[i].Length; j++ {
-			errorValue = 0.0
+			signal = 0.0
 			for k := 0; k < multiLayerPerceptron.NeuralLayers[i+1].Length; k++ {
-				errorValue += multiLayerPerceptron.NeuralLayers[i+1].NeuronUnits[k].Delta * multiLayerPerceptron.NeuralLayers[i+1].NeuronUnits[k].Weights[j]
+				signal += multiLayerPerceptron.NeuralLayers[i+1].NeuronUnits[k].Delta * multiLayerPerceptron.NeuralLayers[i+1].NeuronUnits[k].Weights[j]
 			}
-			multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j].Delta = errorValue * multiLayerPerceptron.TransferFunctionDerivative(multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j].Value)
-		}
-		for j := 0; j < multiLayerPerceptron.NeuralLayers[i+1].Length; j++ {
-			for k := 0; k < multiLayerPerceptron.NeuralLayers[i].Length; k++ {
-				multiLayerPerceptron.NeuralLayers[i+1].NeuronUnits[j].Weights[k] += multiLayerPerceptron.LearningRate * multiLayerPerceptron.NeuralLayers[i+1].NeuronUnits[j].Delta * multiLayerPerceptron.NeuralLayers[i].NeuronUnits[k].Value
-			}
-			multiLayerPerceptron.NeuralLayers[i+1].NeuronUnits[j].Bias += multiLayerPerceptron.LearningRate * multiLayerPerceptron.NeuralLayers[i+1].NeuronUnits[j].Delta
+			multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j].Delta = signal * multiLayerPerceptron.TransferFunctionDerivative(multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j].Value)
 		}
 	}
-	for i := 0; i < len(expectedOutput); i++ {
-		deltaError += math.Abs(newExpectedOutput[i] - expectedOutput[i])
+	return
+}
+
+// outputDeltas returns the error signal of each neuron of the output layer, given its output and expectedOutput,
+// together with the delta error between them, or a ShapeError if expectedOutput doesn't match the output layer.
+func outputDeltas(multiLayerPerceptron *MultiLayerNetwork, output []float64, expectedOutput []float64) (deltas []float64, deltaError float64, errorValue error) {
+	if deltas, deltaError, errorValue = outputErrors(multiLayerPerceptron.LossFunction, multiLayerPerceptron.LossFunctionGradient, output, expectedOutput); errorValue != nil {
+		return
+	}
+
+	otfd := multiLayerPerceptron.TransferFunctionDerivative
+	if multiLayerPerceptron.OutputTransferFunctionDerivative != nil {
+		otfd = multiLayerPerceptron.OutputTransferFunctionDerivative
+	}
+	for i := range deltas {
+		deltas[i] *= otfd(output[i])
+	}
+	return
+}
+
+// outputErrors returns the error signal of each output toward expectedOutput with lossGradient
+// (expected minus actual if nil), before the derivative of the output transfer function, together with
+// the mean of loss over outputs (mean absolute error if nil), or a ShapeError if expectedOutput doesn't match output.
+func outputErrors(loss lossFunction, lossGradient lossFunction, output []float64, expectedOutput []float64) (signals []float64, deltaError float64, errorValue error) {
+	if len(expectedOutput) != len(output) {
+		return nil, 0.0, &ShapeError{What: "expected output", Expected: len(output), Actual: len(expectedOutput)}
+	}
+
+	signals = make([]float64, len(output))
+	for i := range output {
+		if lossGradient != nil {
+			signals[i] = lossGradient(expectedOutput[i], output[i])
+		} else {
+			signals[i] = expectedOutput[i] - output[i]
+		}
+
+		if loss != nil {
+			deltaError += loss(expectedOutput[i], output[i])
+		} else {
+			deltaError += math.Abs(output[i] - expectedOutput[i])
+		}
 	}
 	deltaError = deltaError / float64(len(expectedOutput))
 	return
 }
 
-func MLPTrain(multiLayerPerceptron *MultiLayerNetwork, patterns []Pattern, mapped []string, epochs int) {
-	epoch := 0
-	output := make([]float64, len(mapped))
-	for {
-		for _, pattern := range patterns {
-			for io := range output {
-				output[io] = 0.0
+// gradient accumulates weights and bias updates of a mini-batch of patterns.
+type gradient struct {
+	weights [][][]float64
+	bias    [][]float64
+	size    int
+}
+
+// newGradient creates an empty gradient with the shape of passed network.
+func newGradient(multiLayerPerceptron *MultiLayerNetwork) *gradient {
+	g := &gradient{
+		weights: make([][][]float64, len(multiLayerPerceptron.NeuralLayers)),
+		bias:    make([][]float64, len(multiLayerPerceptron.NeuralLayers)),
+	}
+	for i := 1; i < len(multiLayerPerceptron.NeuralLayers); i++ {
+		g.weights[i] = make([][]float64, multiLayerPerceptron.NeuralLayers[i].Length)
+		g.bias[i] = make([]float64, multiLayerPerceptron.NeuralLayers[i].Length)
+		for j := range g.weights[i] {
+			g.weights[i][j] = make([]float64, multiLayerPerceptron.NeuralLayers[i-1].Length)
+		}
+	}
+	return g
+}
+
+// accumulateGradient adds the updates given by current Delta of each neuron to g.
+func accumulateGradient(multiLayerPerceptron *MultiLayerNetwork, g *gradient) {
+	for i := 1; i < len(multiLayerPerceptron.NeuralLayers); i++ {
+		for j := 0; j < multiLayerPerceptron.NeuralLayers[i].Length; j++ {
+			delta := multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j].Delta
+			for k := 0; k < multiLayerPerceptron.NeuralLayers[i-1].Length; k++ {
+				g.weights[i][j][k] += delta * multiLayerPerceptron.NeuralLayers[i-1].NeuronUnits[k].Value
+			}
+			g.bias[i][j] += delta
+		}
+	}
+	g.size++
+}
+
+// applyGradient updates weights and bias with the mean of accumulated updates and clears g.
+func applyGradient(multiLayerPerceptron *MultiLayerNetwork, g *gradient) {
+	if g.size == 0 {
+		return
+	}
+	for i := 1; i < len(multiLayerPerceptron.NeuralLayers); i++ {
+		for j := 0; j < multiLayerPerceptron.NeuralLayers[i].Length; j++ {
+			neuron := &multiLayerPerceptron.NeuralLayers[i].NeuronUnits[j]
+			for k := range neuron.Weights {
+				neuron.Weights[k] += multiLayerPerceptron.LearningRate * (g.weights[i][j][k]/float64(g.size) - multiLayerPerceptron.Regularization*neuron.Weights[k])
+				g.weights[i][j][k] = 0.0
+			}
+			neuron.Bias += multiLayerPerceptron.LearningRate * g.bias[i][j] / float64(g.size)
+			g.bias[i][j] = 0.0
+		}
+	}
+	g.size = 0
+}
+
+// trainEpoch runs BackPropagation once over patterns, updating weights after each pattern
+// or, if BatchSize is greater than 1, after each mini-batch of BatchSize patterns.
+// [target:func] returns the expected output of a pattern
+// It returns the mean delta error over patterns, or the first error of target or BackPropagation.
+func trainEpoch(multiLayerPerceptron *MultiLayerNetwork, patterns []Pattern, target func(pattern *Pattern) ([]float64, error)) (float64, error) {
+	deltaError := 0.0
+	if multiLayerPerceptron.BatchSize <= 1 {
+		for index := range patterns {
+			expected, errorValue := target(&patterns[index])
+			if errorValue != nil {
+				return 0.0, errorValue
+			}
+			patternError, errorValue := BackPropagate(multiLayerPerceptron, &patterns[index], expected)
+			if errorValue != nil {
+				return 0.0, errorValue
+			}
+			deltaError += patternError
+		}
+	} else {
+		g := newGradient(multiLayerPerceptron)
+		for index := range patterns {
+			expected, errorValue := target(&patterns[index])
+			if errorValue != nil {
+				return 0.0, errorValue
+			}
+			patternError, errorValue := propagateDeltas(multiLayerPerceptron, &patterns[index], expected)
+			if errorValue != nil {
+				return 0.0, errorValue
+			}
+			deltaError += patternError
+			accumulateGradient(multiLayerPerceptron, g)
+			if g.size == multiLayerPerceptron.BatchSize {
+				applyGradient(multiLayerPerceptron, g)
 			}
-			output[int(pattern.SingleExpectation)] = 1.0
-			BackPropagate(multiLayerPerceptron, &pattern, output)
 		}
+		applyGradient(multiLayerPerceptron, g)
+	}
+	return deltaError / float64(len(patterns)), nil
+}
+
+// oneHotTarget returns a function encoding the class of a pattern as a one-hot expected output.
+// The function returns a ClassError for classes outside [0, classes).
+func oneHotTarget(classes int) func(pattern *Pattern) ([]float64, error) {
+	output := make([]float64, classes)
+	return func(pattern *Pattern) ([]float64, error) {
+		if !validClass(pattern.SingleExpectation, classes) {
+			return nil, &ClassError{Class: pattern.SingleExpectation, Classes: classes}
+		}
+		for io := range output {
+			output[io] = 0.0
+		}
+		output[int(pattern.SingleExpectation)] = 1.0
+		return output, nil
+	}
+}
+
+// regressionTarget returns MultipleExpectation of pattern as expected output.
+func regressionTarget(pattern *Pattern) ([]float64, error) {
+	return pattern.MultipleExpectation, nil
+}
 
-		log.WithFields(log.Fields{
+// checkTraining returns the error preventing a network from being trained on patterns:
+// a ConfigError for a network whose layers don't fit together, or an error wrapping ErrEmptyDataset.
+func checkTraining(multiLayerPerceptron *MultiLayerNetwork, patterns []Pattern, method string) error {
+	if errorValue := checkMLPNet(multiLayerPerceptron); errorValue != nil {
+		return errorValue
+	}
+	if len(patterns) == 0 {
+		return emptyDatasetError(method)
+	}
+	return nil
+}
+
+// MLPTrain train a mlp MultiLayerNetwork with BackPropagation algorithm for passed number of epochs,
+// using the class of mapped each pattern belongs to as one-hot target.
+// It returns a ConfigError, an error wrapping ErrEmptyDataset, a ClassError or a ShapeError
+// if network and patterns don't fit together, leaving the network partially trained.
+func MLPTrain(multiLayerPerceptron *MultiLayerNetwork, patterns []Pattern, mapped []string, epochs int) error {
+	if errorValue := checkTraining(multiLayerPerceptron, patterns, "MLPTrain"); errorValue != nil {
+		return errorValue
+	}
+	multiLayerPerceptron.Classes = mapped
+	oneHot := oneHotTarget(len(mapped))
+	for epoch := 0; epoch < epochs; epoch++ {
+		if _, errorValue := trainEpoch(multiLayerPerceptron, patterns, oneHot); errorValue != nil {
+			return errorValue
+		}
+		multiLayerPerceptron.Epoch++
+
+		multiLayerPerceptron.log().WithFields(logging.Fields{
 			"level":  "info",
 			"place":  "validation",
 			"method": "MLPTrain",
-			"epoch":  epoch,
+			"epoch":  multiLayerPerceptron.Epoch,
 		}).Debug("Training epoch completed.")
+	}
+	return nil
+}
 
-		if epoch > epochs {
-			break
+// MLPTrainUntil resume training of a mlp MultiLayerNetwork with BackPropagation algorithm
+// until it has completed passed number of epochs overall. Already trained networks
+// only run the missing epochs, so training budgets can be increased step by step.
+// It returns the same errors as MLPTrain.
+func MLPTrainUntil(multiLayerPerceptron *MultiLayerNetwork, patterns []Pattern, mapped []string, epochs int) error {
+	if errorValue := checkTraining(multiLayerPerceptron, patterns, "MLPTrainUntil"); errorValue != nil {
+		return errorValue
+	}
+	oneHot := oneHotTarget(len(mapped))
+	multiLayerPerceptron.Classes = mapped
+	for multiLayerPerceptron.Epoch < epochs {
+		if _, errorValue := trainEpoch(multiLayerPerceptron, patterns, oneHot); errorValue != nil {
+			return errorValue
 		}
-		epoch++
+		multiLayerPerceptron.Epoch++
+
+		multiLayerPerceptron.log().WithFields(logging.Fields{
+			"level":  "info",
+			"place":  "validation",
+			"method": "MLPTrainUntil",
+			"epoch":  multiLayerPerceptron.Epoch,
+		}).Debug("Training epoch completed.")
 	}
+	return nil
 }
 
-// ElmanTrain train a mlp MultiLayerNetwork with BackPropagation algorithm for assisted learning.
-func ElmanTrain(mlp *MultiLayerNetwork, patterns []Pattern, epochs int) {
-	epoch := 0
-	for {
-		rand.Seed(time.Now().UTC().UnixNano())
-		pIR := rand.Intn(len(patterns))
-		for pI, pattern := range patterns {
-			BackPropagate(mlp, &pattern, pattern.MultipleExpectation, 1)
-			if epoch%100 == 0 && pI == pIR {
-				oOut := Execute(mlp, &pattern, 1)
-				for oOutI, oOutV := range oOut {
-					oOut[oOutI] = util.Round(oOutV, .5, 0)
-				}
-				log.WithFields(log.Fields{
-					"SUM": "  ==========================",
-				}).Info()
-				log.WithFields(log.Fields{
-					"a_n_1": util.ConvertBinToInt(pattern.Features[0:(len(pattern.Features) / 2)]),
-					"a_n_2": pattern.Features[0:(len(pattern.Features) / 2)],
-				}).Info()
-				log.WithFields(log.Fields{
-					"b_n_1": util.ConvertBinToInt(pattern.Features[(len(pattern.Features) / 2):]),
-					"b_n_2": pattern.Features[(len(pattern.Features) / 2):],
-				}).Info()
-				log.WithFields(log.Fields{
-					"sum_1": util.ConvertBinToInt(pattern.MultipleExpectation),
-					"sum_2": pattern.MultipleExpectation,
-				}).Info()
-				log.WithFields(log.Fields{
-					"sum_1": util.ConvertBinToInt(oOut),
-					"sum_2": oOut,
-				}).Info()
-				log.WithFields(log.Fields{
-					"END": "  ==========================",
-				}).Info()
-			}
+// MLPRegressionTrain train a mlp MultiLayerNetwork with BackPropagation algorithm for passed number of epochs,
+// using continuous MultipleExpectation values of patterns as targets.
+// It returns the same errors as MLPTrain, with a ShapeError for targets not matching the output layer.
+func MLPRegressionTrain(multiLayerPerceptron *MultiLayerNetwork, patterns []Pattern, epochs int) error {
+	if errorValue := checkTraining(multiLayerPerceptron, patterns, "MLPRegressionTrain"); errorValue != nil {
+		return errorValue
+	}
+	for epoch := 0; epoch < epochs; epoch++ {
+		lossValue, errorValue := trainEpoch(multiLayerPerceptron, patterns, regressionTarget)
+		if errorValue != nil {
+			return errorValue
 		}
+		multiLayerPerceptron.Epoch++
 
-		log.WithFields(log.Fields{
+		multiLayerPerceptron.log().WithFields(logging.Fields{
 			"level":  "info",
 			"place":  "validation",
-			"method": "ElmanTrain",
-			"epoch":  epoch,
+			"method": "MLPRegressionTrain",
+			"epoch":  multiLayerPerceptron.Epoch,
+			"loss":   lossValue,
 		}).Debug("Training epoch completed.")
+	}
+	return nil
+}
+
+// MLPTrainIterator train a mlp MultiLayerNetwork with BackPropagation algorithm for passed number of epochs,
+// streaming patterns of dataset in chunks of chunkSize patterns, so that the dataset is never loaded in memory.
+// Dataset is reset at the beginning of each epoch. Mini-batches don't span chunks.
+// Imputer, Encoder and Scaler of the network can't be fitted while streaming, so they must be
+// fitted beforehand, for instance with FitPreprocessing on a sample of patterns.
+// It returns a ConfigError for unfitted preprocessing, or the first error returned by dataset or training.
+func MLPTrainIterator(multiLayerPerceptron *MultiLayerNetwork, dataset DatasetIterator, mapped []string, epochs int, chunkSize int) error {
+	multiLayerPerceptron.Classes = mapped
+	return trainIterator(multiLayerPerceptron, dataset, epochs, chunkSize, oneHotTarget(len(mapped)), "MLPTrainIterator")
+}
+
+// MLPRegressionTrainIterator train a mlp MultiLayerNetwork as MLPTrainIterator
+// using continuous MultipleExpectation values of patterns as targets.
+func MLPRegressionTrainIterator(multiLayerPerceptron *MultiLayerNetwork, dataset DatasetIterator, epochs int, chunkSize int) error {
+	return trainIterator(multiLayerPerceptron, dataset, epochs, chunkSize, regressionTarget, "MLPRegressionTrainIterator")
+}
 
-		if epoch > epochs {
-			break
+// trainIterator runs trainEpoch on each chunk of dataset, for passed number of epochs.
+func trainIterator(multiLayerPerceptron *MultiLayerNetwork, dataset DatasetIterator, epochs int, chunkSize int, target func(pattern *Pattern) ([]float64, error), method string) error {
+	if errorValue := checkMLPNet(multiLayerPerceptron); errorValue != nil {
+		return errorValue
+	}
+	if chunkSize < 1 {
+		return &ConfigError{Field: "chunkSize", Reason: fmt.Sprintf("is %d, at least 1 needed", chunkSize)}
+	}
+	if errorValue := checkFitted(multiLayerPerceptron.Imputer, multiLayerPerceptron.Encoder, multiLayerPerceptron.Scaler); errorValue != nil {
+		return fmt.Errorf("%s: %w", method, errorValue)
+	}
+	for epoch := 0; epoch < epochs; epoch++ {
+		if errorValue := dataset.Reset(); errorValue != nil {
+			return errorValue
 		}
-		epoch++
+		deltaError, seen := 0.0, 0
+		for {
+			chunk, errorValue := dataset.Next(chunkSize)
+			if errorValue == io.EOF {
+				break
+			}
+			if errorValue != nil {
+				return errorValue
+			}
+			chunkError, errorValue := trainEpoch(multiLayerPerceptron, chunk, target)
+			if errorValue != nil {
+				return errorValue
+			}
+			deltaError += chunkError * float64(len(chunk))
+			seen += len(chunk)
+		}
+		if seen == 0 {
+			return emptyDatasetError(method)
+		}
+		multiLayerPerceptron.Epoch++
+
+		multiLayerPerceptron.log().WithFields(logging.Fields{
+			"level":    "info",
+			"place":    "validation",
+			"method":   method,
+			"epoch":    multiLayerPerceptron.Epoch,
+			"patterns": seen,
+			"loss":     deltaError / float64(seen),
+		}).Debug("Training epoch completed.")
 	}
+	return nil
+}
+
+// log returns the channel of training entries of the network.
+func (multiLayerPerceptron *MultiLayerNetwork) log() logging.Channel {
+	return trainingLog.With(multiLayerPerceptron.Logging)
 }
@@ -0,0 +1,130 @@
+package neural
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestMLPTrainEpochs(t *testing.T) {
+	patterns := []Pattern{
+		{Features: []float64{0, 0}, SingleExpectation: 0, MultipleExpectation: []float64{0}},
+		{Features: []float64{0, 1}, SingleExpectation: 1, MultipleExpectation: []float64{1}},
+		{Features: []float64{1, 0}, SingleExpectation: 1, MultipleExpectation: []float64{1}},
+		{Features: []float64{1, 1}, SingleExpectation: 0, MultipleExpectation: []float64{0}},
+	}
+	trainings := map[string]func(network *MultiLayerNetwork, epochs int) error{
+		"MLPTrain": func(network *MultiLayerNetwork, epochs int) error {
+			return MLPTrain(network, patterns, []string{"0", "1"}, epochs)
+		},
+		"MLPTrainUntil": func(network *MultiLayerNetwork, epochs int) error {
+			return MLPTrainUntil(network, patterns, []string{"0", "1"}, epochs)
+		},
+		"MLPRegressionTrain": func(network *MultiLayerNetwork, epochs int) error {
+			return MLPRegressionTrain(network, patterns, epochs)
+		},
+	}
+	for name, train := range trainings {
+		for _, epochs := range []int{0, 1, 5} {
+			rand.Seed(1)
+			outputs := 2
+			if name == "MLPRegressionTrain" {
+				outputs = 1
+			}
+			network := PrepareMLPNet([]int{2, 3, outputs}, 0.1, SigmoidTransfer, SigmoidTransferDerivative)
+			if errorValue := train(&network, epochs); errorValue != nil {
+				t.Fatalf("%s(%d): %v", name, epochs, errorValue)
+			}
+			if network.Epoch != epochs {
+				t.Errorf("%s(%d) trained %d epochs", name, epochs, network.Epoch)
+			}
+		}
+	}
+}
+
+func TestMLPGradient(t *testing.T) {
+	tests := []struct {
+		transfer       string
+		outputTransfer string
+	}{
+		{"sigmoid", ""},
+		{"tanh", ""},
+		{"sigmoid", "linear"},
+		{"tanh", "sigmoid"},
+	}
+	for _, test := range tests {
+		rand.Seed(1)
+		tf, tfd, _ := TransferFunctionByName(test.transfer)
+		network := PrepareMLPNet([]int{3, 4, 2}, 0.1, tf, tfd)
+		if test.outputTransfer != "" {
+			network.OutputTransferFunction, network.OutputTransferFunctionDerivative, _ = TransferFunctionByName(test.outputTransfer)
+		}
+		for i := 1; i < len(network.NeuralLayers); i++ {
+			for j := range network.NeuralLayers[i].NeuronUnits {
+				neuron := &network.NeuralLayers[i].NeuronUnits[j]
+				for k := range neuron.Weights {
+					neuron.Weights[k] = rand.NormFloat64()
+				}
+				neuron.Bias = rand.NormFloat64()
+			}
+		}
+		pattern := Pattern{Features: []float64{0.5, -1, 0.25}}
+		expected := []float64{0.2, 0.9}
+
+		// half squared error, whose negative gradient is the default error signal of outputs
+		loss := func() float64 {
+			output, errorValue := Execute(&network, &pattern)
+			if errorValue != nil {
+				t.Fatal(errorValue)
+			}
+			sum := 0.0
+			for j := range output {
+				sum += (expected[j] - output[j]) * (expected[j] - output[j]) / 2
+			}
+			return sum
+		}
+		if _, errorValue := propagateDeltas(&network, &pattern, expected); errorValue != nil {
+			t.Fatal(errorValue)
+		}
+		var analytic []float64
+		for i := 1; i < len(network.NeuralLayers); i++ {
+			for _, neuron := range network.NeuralLayers[i].NeuronUnits {
+				for _, previous := range network.NeuralLayers[i-1].NeuronUnits {
+					analytic = append(analytic, -neuron.Delta*previous.Value)
+				}
+				analytic = append(analytic, -neuron.Delta)
+			}
+		}
+
+		const epsilon = 1e-5
+		var numeric []float64
+		for i := 1; i < len(network.NeuralLayers); i++ {
+			for j := range network.NeuralLayers[i].NeuronUnits {
+				neuron := &network.NeuralLayers[i].NeuronUnits[j]
+				parameters := make([]*float64, 0, len(neuron.Weights)+1)
+				for k := range neuron.Weights {
+					parameters = append(parameters, &neuron.Weights[k])
+				}
+				for _, parameter := range append(parameters, &neuron.Bias) {
+					value := *parameter
+					*parameter = value + epsilon
+					plus := loss()
+					*parameter = value - epsilon
+					minus := loss()
+					*parameter = value
+					numeric = append(numeric, (plus-minus)/(2*epsilon))
+				}
+			}
+		}
+
+		difference, norm := 0.0, 0.0
+		for p := range analytic {
+			difference += (analytic[p] - numeric[p]) * (analytic[p] - numeric[p])
+			norm += analytic[p]*analytic[p] + numeric[p]*numeric[p]
+		}
+		if relative := math.Sqrt(difference / norm); relative >= 1e-6 {
+			t.Errorf("%s/%s: relative difference of backpropagated and numerical gradients = %g, want below 1e-6",
+				test.transfer, test.outputTransfer, relative)
+		}
+	}
+}
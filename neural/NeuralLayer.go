@@ -1,7 +1,7 @@
 package neural
 
 import (
-	log "github.com/sirupsen/logrus"
+	"MultilayerPerceptron/logging"
 	_ "os"
 )
 
@@ -22,7 +22,7 @@ func PrepareLayer(numberOfNeuronsNeuralLayer int,
 	for i := 0; i < numberOfNeuronsNeuralLayer; i++ {
 		RandomNeuronInit(&layer.NeuronUnits[i], numberOfNeuronsPreviousNeuralLayer)
 	}
-	log.WithFields(log.Fields{
+	trainingLog.WithFields(logging.Fields{
 		"level":               "info",
 		"msg":                 "multilayer perceptron init completed",
 		"neurons":             len(layer.NeuronUnits),
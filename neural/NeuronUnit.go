@@ -1,16 +1,17 @@
 package neural
 
 import (
+	"MultilayerPerceptron/logging"
 	"MultilayerPerceptron/util"
-	log "github.com/sirupsen/logrus"
 	"math/rand"
-	"os"
 )
 
 const (
 	ScalingFactor = 0.0000000000001
 )
 
+// NeuronUnit is a single neuron, the unit of Perceptron and of the layers of MultiLayerNetwork.
+// It doesn't implement Model, use Perceptron to fit and predict with a single neuron.
 type NeuronUnit struct {
 	// the way each dimensions of the pattern is modulated
 	Weights []float64
@@ -24,11 +25,6 @@ type NeuronUnit struct {
 	Delta float64
 }
 
-func init() {
-	log.SetOutput(os.Stdout)
-	log.SetLevel(log.InfoLevel)
-}
-
 // RandomNeuronInit initialize neuron weight, bias and learning rate using NormFloat64 random value.
 func RandomNeuronInit(neuron *NeuronUnit, dim int) {
 	neuron.Weights = make([]float64, dim)
@@ -41,7 +37,7 @@ func RandomNeuronInit(neuron *NeuronUnit, dim int) {
 	neuron.Value = rand.NormFloat64() * ScalingFactor
 	neuron.Delta = rand.NormFloat64() * ScalingFactor
 
-	log.WithFields(log.Fields{
+	trainingLog.WithFields(logging.Fields{
 		"level":   "debug",
 		"place":   "neuron",
 		"func":    "RandomNeuronInit",
@@ -51,34 +47,44 @@ func RandomNeuronInit(neuron *NeuronUnit, dim int) {
 }
 
 // UpdateWeights performs update in neuron weights with respect to passed pattern.
-// It returns error of prediction before and after updating weights.
-func UpdateWeights(neuron *NeuronUnit, pattern *Pattern) (float64, float64) {
-	var predictedValue, prevError, postError = Predict(neuron, pattern), 0.0, 0.0
-	prevError = pattern.SingleExpectation - predictedValue
+// It returns error of prediction before and after updating weights, or a ShapeError
+// if pattern doesn't have as many features as weights of neuron.
+func UpdateWeights(neuron *NeuronUnit, pattern *Pattern) (float64, float64, error) {
+	predictedValue, errorValue := Predict(neuron, pattern)
+	if errorValue != nil {
+		return 0.0, 0.0, errorValue
+	}
+	prevError := pattern.SingleExpectation - predictedValue
 	neuron.Bias = neuron.Bias + neuron.LearningRate*prevError
 
 	for index := range neuron.Weights {
 		neuron.Weights[index] = neuron.Weights[index] + neuron.LearningRate*prevError*pattern.Features[index]
 	}
 
-	predictedValue = Predict(neuron, pattern)
-	postError = pattern.SingleExpectation - predictedValue
-
-	log.WithFields(log.Fields{
-		"level":   "debug",
-		"place":   "neuron",
-		"func":    "UpdateWeights",
-		"msg":     "updating weights of neuron",
-		"weights": neuron.Weights,
-	}).Debug()
+	predictedValue, _ = Predict(neuron, pattern)
+	postError := pattern.SingleExpectation - predictedValue
+
+	if trainingLog.Enabled(logging.TraceLevel) {
+		trainingLog.WithFields(logging.Fields{
+			"level":   "trace",
+			"place":   "neuron",
+			"func":    "UpdateWeights",
+			"msg":     "updating weights of neuron",
+			"weights": neuron.Weights,
+		}).Trace()
+	}
 
-	return prevError, postError
+	return prevError, postError, nil
 }
 
 // TrainNeuron trains a passed neuron with patterns passed, for specified number of epoch.
 // If init is 0, leaves weights unchanged before training.
 // If init is 1, reset weights and bias of neuron before training.
-func TrainNeuron(neuron *NeuronUnit, patterns []Pattern, epochs int, init int) {
+// It returns an error wrapping ErrEmptyDataset without patterns, or the first error of UpdateWeights.
+func TrainNeuron(neuron *NeuronUnit, patterns []Pattern, epochs int, init int) error {
+	if len(patterns) == 0 {
+		return emptyDatasetError("TrainNeuron")
+	}
 	if init == 1 {
 		neuron.Weights = make([]float64, len(patterns[0].Features))
 		neuron.Bias = 0.0
@@ -88,12 +94,15 @@ func TrainNeuron(neuron *NeuronUnit, patterns []Pattern, epochs int, init int) {
 	var squaredPrevError, squaredPostError = 0.0, 0.0
 	for epoch < epochs {
 		for _, pattern := range patterns {
-			prevError, postError := UpdateWeights(neuron, &pattern)
+			prevError, postError, errorValue := UpdateWeights(neuron, &pattern)
+			if errorValue != nil {
+				return errorValue
+			}
 			squaredPrevError = squaredPrevError + (prevError * prevError)
 			squaredPostError = squaredPostError + (postError * postError)
 		}
 
-		log.WithFields(log.Fields{
+		trainingLog.WithFields(logging.Fields{
 			"level":            "debug",
 			"place":            "error evolution in epoch",
 			"method":           "TrainNeuron",
@@ -105,30 +114,32 @@ func TrainNeuron(neuron *NeuronUnit, patterns []Pattern, epochs int, init int) {
 
 		epoch++
 	}
+	return nil
 }
 
 // Predict performs a neuron prediction to passed pattern.
-// It returns a float64 binary predicted value.
-func Predict(neuron *NeuronUnit, pattern *Pattern) float64 {
-	if util.ScalarProduct(neuron.Weights, pattern.Features)+neuron.Bias < 0.0 {
-		return 0.0
+// It returns a float64 binary predicted value, or a ShapeError if pattern doesn't have
+// as many features as weights of neuron.
+func Predict(neuron *NeuronUnit, pattern *Pattern) (float64, error) {
+	product, errorValue := util.ScalarProduct(neuron.Weights, pattern.Features)
+	if errorValue != nil {
+		return 0.0, &ShapeError{What: "input features", Expected: len(neuron.Weights), Actual: len(pattern.Features)}
+	}
+	if product+neuron.Bias < 0.0 {
+		return 0.0, nil
 	}
-	return 1.0
+	return 1.0, nil
 }
 
 // Accuracy calculate percentage of equal values between two float64 based slices.
-// It returns int number and a float64 percentage value of corrected values.
-func Accuracy(actual []float64, predicted []float64) (int, float64) {
+// It returns int number and a float64 percentage value of corrected values, a ShapeError
+// if slices have different length or an error wrapping ErrEmptyDataset if they are empty.
+func Accuracy(actual []float64, predicted []float64) (int, float64, error) {
 	if len(actual) != len(predicted) {
-		log.WithFields(log.Fields{
-			"level":        "error",
-			"place":        "neuron",
-			"method":       "Accuracy",
-			"msg":          "accuracy between actual and predicted slices of values",
-			"actualLen":    len(actual),
-			"predictedLen": len(predicted),
-		}).Error("Failed to compute accuracy between actual values and predictions: different length.")
-		return -1, -1.0
+		return 0, 0.0, &ShapeError{What: "predictions", Expected: len(actual), Actual: len(predicted)}
+	}
+	if len(actual) == 0 {
+		return 0, 0.0, emptyDatasetError("Accuracy")
 	}
 
 	var correct = 0
@@ -139,5 +150,5 @@ func Accuracy(actual []float64, predicted []float64) (int, float64) {
 		}
 	}
 
-	return correct, float64(correct) / float64(len(actual)) * 100.0
+	return correct, float64(correct) / float64(len(actual)) * 100.0, nil
 }
@@ -0,0 +1,448 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NpyArray is a NumPy array of numbers, with values in row-major (C) order.
+type NpyArray struct {
+	// dimensions of the array
+	Shape []int
+	// values of the array
+	Data []float64
+}
+
+var (
+	npyMagic      = []byte("\x93NUMPY")
+	npyDescr      = regexp.MustCompile(`'descr'\s*:\s*'([<>|=])([a-z])(\d+)'`)
+	npyFortran    = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+	npyShape      = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+	npyWeightName = regexp.MustCompile(`^([Wb])(\d+)$`)
+)
+
+// ReadNpy reads an array in NumPy .npy format, with boolean, integer or floating point values.
+func ReadNpy(r io.Reader) (NpyArray, error) {
+	var array NpyArray
+	preamble := make([]byte, 8)
+	if _, errorValue := io.ReadFull(r, preamble); errorValue != nil {
+		return array, fmt.Errorf("reading npy magic: %w", errorValue)
+	}
+	if !bytes.Equal(preamble[:6], npyMagic) {
+		return array, fmt.Errorf("invalid npy magic %q", preamble[:6])
+	}
+	var headerLength int
+	if preamble[6] == 1 {
+		var length uint16
+		if errorValue := binary.Read(r, binary.LittleEndian, &length); errorValue != nil {
+			return array, errorValue
+		}
+		headerLength = int(length)
+	} else {
+		var length uint32
+		if errorValue := binary.Read(r, binary.LittleEndian, &length); errorValue != nil {
+			return array, errorValue
+		}
+		headerLength = int(length)
+	}
+	header := make([]byte, headerLength)
+	if _, errorValue := io.ReadFull(r, header); errorValue != nil {
+		return array, fmt.Errorf("reading npy header: %w", errorValue)
+	}
+
+	descr := npyDescr.FindSubmatch(header)
+	fortran := npyFortran.FindSubmatch(header)
+	shape := npyShape.FindSubmatch(header)
+	if descr == nil || fortran == nil || shape == nil {
+		return array, fmt.Errorf("invalid npy header %q", header)
+	}
+	for _, dim := range strings.Split(string(shape[1]), ",") {
+		if dim = strings.TrimSpace(dim); dim != "" {
+			value, errorValue := strconv.Atoi(dim)
+			if errorValue != nil {
+				return array, fmt.Errorf("invalid npy shape %q", shape[1])
+			}
+			array.Shape = append(array.Shape, value)
+		}
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if descr[1][0] == '>' {
+		order = binary.BigEndian
+	}
+	kind := descr[2][0]
+	size, _ := strconv.Atoi(string(descr[3]))
+	decode, errorValue := npyDecoder(kind, size, order)
+	if errorValue != nil {
+		return array, errorValue
+	}
+
+	count := 1
+	for _, dim := range array.Shape {
+		count *= dim
+	}
+	raw := make([]byte, count*size)
+	if _, errorValue := io.ReadFull(r, raw); errorValue != nil {
+		return array, fmt.Errorf("reading npy data of shape %v: %w", array.Shape, errorValue)
+	}
+	array.Data = make([]float64, count)
+	for index := range array.Data {
+		array.Data[index] = decode(raw[index*size:])
+	}
+	if string(fortran[1]) == "True" {
+		array.Data = npyFromFortran(array.Data, array.Shape)
+	}
+	return array, nil
+}
+
+// npyDecoder returns the function decoding a value of NumPy type kind (b, i, u, f) of size bytes.
+func npyDecoder(kind byte, size int, order binary.ByteOrder) (func(raw []byte) float64, error) {
+	switch {
+	case kind == 'b' && size == 1, kind == 'u' && size == 1:
+		return func(raw []byte) float64 { return float64(raw[0]) }, nil
+	case kind == 'i' && size == 1:
+		return func(raw []byte) float64 { return float64(int8(raw[0])) }, nil
+	case kind == 'i' && size == 2:
+		return func(raw []byte) float64 { return float64(int16(order.Uint16(raw))) }, nil
+	case kind == 'u' && size == 2:
+		return func(raw []byte) float64 { return float64(order.Uint16(raw)) }, nil
+	case kind == 'i' && size == 4:
+		return func(raw []byte) float64 { return float64(int32(order.Uint32(raw))) }, nil
+	case kind == 'u' && size == 4:
+		return func(raw []byte) float64 { return float64(order.Uint32(raw)) }, nil
+	case kind == 'i' && size == 8:
+		return func(raw []byte) float64 { return float64(int64(order.Uint64(raw))) }, nil
+	case kind == 'u' && size == 8:
+		return func(raw []byte) float64 { return float64(order.Uint64(raw)) }, nil
+	case kind == 'f' && size == 4:
+		return func(raw []byte) float64 { return float64(math.Float32frombits(order.Uint32(raw))) }, nil
+	case kind == 'f' && size == 8:
+		return func(raw []byte) float64 { return math.Float64frombits(order.Uint64(raw)) }, nil
+	}
+	return nil, fmt.Errorf("unsupported npy type %c%d", kind, size)
+}
+
+// npyFromFortran reorders values of an array stored in column-major order into row-major order.
+func npyFromFortran(data []float64, shape []int) []float64 {
+	reordered := make([]float64, len(data))
+	index := make([]int, len(shape))
+	for position := range data {
+		// position is the row-major offset of index, find its column-major offset
+		offset, stride := 0, 1
+		for d := range shape {
+			offset += index[d] * stride
+			stride *= shape[d]
+		}
+		reordered[position] = data[offset]
+		for d := len(shape) - 1; d >= 0; d-- {
+			index[d]++
+			if index[d] < shape[d] {
+				break
+			}
+			index[d] = 0
+		}
+	}
+	return reordered
+}
+
+// WriteNpy writes an array in NumPy .npy format (version 1.0, little-endian float64 values).
+func WriteNpy(w io.Writer, array NpyArray) error {
+	count := 1
+	dims := make([]string, len(array.Shape))
+	for d, dim := range array.Shape {
+		count *= dim
+		dims[d] = strconv.Itoa(dim)
+	}
+	if count != len(array.Data) {
+		return fmt.Errorf("npy shape %v doesn't match %d values", array.Shape, len(array.Data))
+	}
+	shape := strings.Join(dims, ", ")
+	if len(dims) == 1 {
+		shape += ","
+	}
+	header := fmt.Sprintf("{'descr': '<f8', 'fortran_order': False, 'shape': (%s), }", shape)
+	// magic, version and length take 10 bytes, header ends with a newline and is padded to 64 bytes
+	header += strings.Repeat(" ", 63-(10+len(header))%64) + "\n"
+
+	buffer := bytes.NewBuffer(nil)
+	buffer.Write(npyMagic)
+	buffer.Write([]byte{1, 0})
+	binary.Write(buffer, binary.LittleEndian, uint16(len(header)))
+	buffer.WriteString(header)
+	if _, errorValue := w.Write(buffer.Bytes()); errorValue != nil {
+		return errorValue
+	}
+	return binary.Write(w, binary.LittleEndian, array.Data)
+}
+
+// ReadNpz reads all arrays of a NumPy .npz archive, by name without the .npy extension.
+func ReadNpz(filePath string) (map[string]NpyArray, error) {
+	archive, errorValue := zip.OpenReader(filePath)
+	if errorValue != nil {
+		return nil, errorValue
+	}
+	defer archive.Close()
+
+	arrays := make(map[string]NpyArray)
+	for _, file := range archive.File {
+		entry, errorValue := file.Open()
+		if errorValue != nil {
+			return nil, errorValue
+		}
+		array, errorValue := ReadNpy(entry)
+		entry.Close()
+		if errorValue != nil {
+			return nil, fmt.Errorf("%s: %s: %w", filePath, file.Name, errorValue)
+		}
+		arrays[strings.TrimSuffix(file.Name, ".npy")] = array
+	}
+	return arrays, nil
+}
+
+// WriteNpz writes arrays in a NumPy .npz archive, in order of name, as numpy.savez does.
+func WriteNpz(filePath string, arrays map[string]NpyArray) error {
+	file, errorValue := os.Create(filePath)
+	if errorValue != nil {
+		return errorValue
+	}
+	archive := zip.NewWriter(file)
+
+	names := make([]string, 0, len(arrays))
+	for name := range arrays {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		entry, errorValue := archive.Create(name + ".npy")
+		if errorValue == nil {
+			errorValue = WriteNpy(entry, arrays[name])
+		}
+		if errorValue != nil {
+			archive.Close()
+			file.Close()
+			return fmt.Errorf("%s: %s: %w", filePath, name, errorValue)
+		}
+	}
+	if errorValue = archive.Close(); errorValue != nil {
+		file.Close()
+		return errorValue
+	}
+	return file.Close()
+}
+
+// LoadPatternsFromNpyFiles load a dataset from a features matrix and a labels array stored in .npy files.
+// See NpyPatterns for arguments.
+func LoadPatternsFromNpyFiles(featuresPath string, labelsPath string, regression bool) ([]Pattern, error, []string) {
+	var arrays [2]NpyArray
+	for index, filePath := range []string{featuresPath, labelsPath} {
+		file, errorValue := os.Open(filePath)
+		if errorValue != nil {
+			return nil, errorValue, nil
+		}
+		arrays[index], errorValue = ReadNpy(file)
+		file.Close()
+		if errorValue != nil {
+			return nil, fmt.Errorf("%s: %w", filePath, errorValue), nil
+		}
+	}
+	return npyLoaded(arrays[0], arrays[1], regression, "LoadPatternsFromNpyFiles")
+}
+
+// LoadPatternsFromNpzFile load a dataset from the features matrix and labels array
+// named featuresName and labelsName (such as "X" and "y") of a .npz archive.
+// See NpyPatterns for arguments.
+func LoadPatternsFromNpzFile(filePath string, featuresName string, labelsName string, regression bool) ([]Pattern, error, []string) {
+	arrays, errorValue := ReadNpz(filePath)
+	if errorValue != nil {
+		return nil, errorValue, nil
+	}
+	features, found := arrays[featuresName]
+	if !found {
+		return nil, fmt.Errorf("%s: no array named %q", filePath, featuresName), nil
+	}
+	labels, found := arrays[labelsName]
+	if !found {
+		return nil, fmt.Errorf("%s: no array named %q", filePath, labelsName), nil
+	}
+	return npyLoaded(features, labels, regression, "LoadPatternsFromNpzFile")
+}
+
+// npyLoaded converts arrays to patterns, logging the outcome as method.
+func npyLoaded(features NpyArray, labels NpyArray, regression bool, method string) ([]Pattern, error, []string) {
+	patterns, errorValue, mapped := NpyPatterns(features, labels, regression)
+	if errorValue != nil {
+		return nil, errorValue, nil
+	}
+
+	loadingLog.WithFields(logging.Fields{
+		"level":    "info",
+		"place":    "patterns",
+		"method":   method,
+		"readData": len(patterns),
+		"classes":  len(mapped),
+	}).Info("File reading completed.")
+
+	return patterns, nil, mapped
+}
+
+// NpyPatterns converts a features array of shape (n, ...), flattened for each pattern, and a labels array
+// into an array of Pattern. For classification labels have shape (n,) and hold class numbers, so that
+// mapped[k] is strconv.Itoa(k). With regression labels of shape (n,) or (n, targets) are stored in MultipleExpectation.
+// It returns patterns and the class of each mapped value (nil for regression).
+func NpyPatterns(features NpyArray, labels NpyArray, regression bool) ([]Pattern, error, []string) {
+	if len(features.Shape) == 0 || len(labels.Shape) == 0 || features.Shape[0] != labels.Shape[0] {
+		return nil, fmt.Errorf("features %v and labels %v don't match", features.Shape, labels.Shape), nil
+	}
+	count := features.Shape[0]
+	if !regression && len(labels.Shape) != 1 {
+		return nil, fmt.Errorf("class labels must have shape (n,), got %v", labels.Shape), nil
+	}
+	dim, targets := 0, 0
+	if count > 0 {
+		dim, targets = len(features.Data)/count, len(labels.Data)/count
+	}
+
+	var mapped []string
+	patterns := make([]Pattern, count)
+	for index := range patterns {
+		patterns[index].Features = append([]float64{}, features.Data[index*dim:(index+1)*dim]...)
+		if regression {
+			patterns[index].MultipleExpectation = append([]float64{}, labels.Data[index*targets:(index+1)*targets]...)
+			patterns[index].SingleExpectation = patterns[index].MultipleExpectation[0]
+			continue
+		}
+		label := labels.Data[index]
+		if label < 0 || label != math.Trunc(label) {
+			return nil, fmt.Errorf("label %v of pattern %d is not a class number", label, index), nil
+		}
+		for len(mapped) <= int(label) {
+			mapped = append(mapped, strconv.Itoa(len(mapped)))
+		}
+		patterns[index].SingleExpectation = label
+		patterns[index].SingleRawExpectation = mapped[int(label)]
+	}
+	return patterns, nil, mapped
+}
+
+// PatternsToNpy converts patterns into a features matrix of shape (n, features) and a labels array,
+// of shape (n,) with class numbers for classification or (n, targets) with MultipleExpectation for regression.
+// It returns a ShapeError if patterns don't have the same number of features or targets.
+func PatternsToNpy(patterns []Pattern, regression bool) (features NpyArray, labels NpyArray, errorValue error) {
+	features.Shape, labels.Shape = []int{len(patterns), 0}, []int{len(patterns)}
+	if len(patterns) > 0 {
+		features.Shape[1] = len(patterns[0].Features)
+		if regression {
+			labels.Shape = append(labels.Shape, len(patterns[0].MultipleExpectation))
+		}
+	}
+	if errorValue = checkFeatures(patterns); errorValue != nil {
+		return
+	}
+	for index, pattern := range patterns {
+		if regression && len(pattern.MultipleExpectation) != labels.Shape[1] {
+			return features, labels, &ShapeError{What: fmt.Sprintf("targets of pattern %d", index), Expected: labels.Shape[1], Actual: len(pattern.MultipleExpectation)}
+		}
+		features.Data = append(features.Data, pattern.Features...)
+		if regression {
+			labels.Data = append(labels.Data, pattern.MultipleExpectation...)
+		} else {
+			labels.Data = append(labels.Data, pattern.SingleExpectation)
+		}
+	}
+	return
+}
+
+// ExportMLPWeights returns the weight matrix and bias vector of each layer of a multi layer Perceptron,
+// named Wi and bi for layer i (the input layer is 0 and has none). Wi has shape (inputs, outputs),
+// so that outputs of layer i are f(x Wi + bi) with x the outputs of layer i-1, as in Keras Dense layers.
+func ExportMLPWeights(multiLayerPerceptron *MultiLayerNetwork) map[string]NpyArray {
+	arrays := make(map[string]NpyArray)
+	for i := 1; i < len(multiLayerPerceptron.NeuralLayers); i++ {
+		layer := multiLayerPerceptron.NeuralLayers[i]
+		inputs := 0
+		if layer.Length > 0 {
+			inputs = len(layer.NeuronUnits[0].Weights)
+		}
+		weights := NpyArray{Shape: []int{inputs, layer.Length}, Data: make([]float64, inputs*layer.Length)}
+		bias := NpyArray{Shape: []int{layer.Length}, Data: make([]float64, layer.Length)}
+		for j, neuron := range layer.NeuronUnits {
+			for k, weight := range neuron.Weights {
+				weights.Data[k*layer.Length+j] = weight
+			}
+			bias.Data[j] = neuron.Bias
+		}
+		arrays[fmt.Sprintf("W%d", i)] = weights
+		arrays[fmt.Sprintf("b%d", i)] = bias
+	}
+	return arrays
+}
+
+// ImportMLPWeights sets weights and biases of a multi layer Perceptron from arrays named as by ExportMLPWeights.
+// Every layer must be present with the shape of the network, which is left unchanged on error.
+func ImportMLPWeights(multiLayerPerceptron *MultiLayerNetwork, arrays map[string]NpyArray) error {
+	for name := range arrays {
+		if match := npyWeightName.FindStringSubmatch(name); match != nil {
+			if i, _ := strconv.Atoi(match[2]); i < 1 || i >= len(multiLayerPerceptron.NeuralLayers) {
+				return fmt.Errorf("array %s has no matching layer in a network of %d layers", name, len(multiLayerPerceptron.NeuralLayers))
+			}
+		}
+	}
+	for i := 1; i < len(multiLayerPerceptron.NeuralLayers); i++ {
+		layer := multiLayerPerceptron.NeuralLayers[i]
+		inputs := 0
+		if layer.Length > 0 {
+			inputs = len(layer.NeuronUnits[0].Weights)
+		}
+		weights, foundWeights := arrays[fmt.Sprintf("W%d", i)]
+		bias, foundBias := arrays[fmt.Sprintf("b%d", i)]
+		if !foundWeights || !foundBias {
+			return fmt.Errorf("missing W%d or b%d", i, i)
+		}
+		if len(weights.Shape) != 2 || weights.Shape[0] != inputs || weights.Shape[1] != layer.Length {
+			return fmt.Errorf("W%d has shape %v, layer needs (%d, %d)", i, weights.Shape, inputs, layer.Length)
+		}
+		if len(bias.Shape) != 1 || bias.Shape[0] != layer.Length {
+			return fmt.Errorf("b%d has shape %v, layer needs (%d,)", i, bias.Shape, layer.Length)
+		}
+	}
+
+	for i := 1; i < len(multiLayerPerceptron.NeuralLayers); i++ {
+		layer := multiLayerPerceptron.NeuralLayers[i]
+		weights, bias := arrays[fmt.Sprintf("W%d", i)], arrays[fmt.Sprintf("b%d", i)]
+		for j := range layer.NeuronUnits {
+			neuron := &layer.NeuronUnits[j]
+			for k := range neuron.Weights {
+				neuron.Weights[k] = weights.Data[k*layer.Length+j]
+			}
+			neuron.Bias = bias.Data[j]
+		}
+	}
+	return nil
+}
+
+// SaveMLPWeightsNpz writes weights and biases of a multi layer Perceptron in a .npz archive, as ExportMLPWeights.
+func SaveMLPWeightsNpz(multiLayerPerceptron *MultiLayerNetwork, filePath string) error {
+	return WriteNpz(filePath, ExportMLPWeights(multiLayerPerceptron))
+}
+
+// LoadMLPWeightsNpz sets weights and biases of a multi layer Perceptron from a .npz archive, as ImportMLPWeights.
+func LoadMLPWeightsNpz(multiLayerPerceptron *MultiLayerNetwork, filePath string) error {
+	arrays, errorValue := ReadNpz(filePath)
+	if errorValue != nil {
+		return errorValue
+	}
+	if errorValue = ImportMLPWeights(multiLayerPerceptron, arrays); errorValue != nil {
+		return fmt.Errorf("%s: %w", filePath, errorValue)
+	}
+	return nil
+}
@@ -1,20 +1,11 @@
 package neural
 
 import (
+	"MultilayerPerceptron/logging"
 	"MultilayerPerceptron/util"
-	"encoding/csv"
-	log "github.com/sirupsen/logrus"
-	"io"
-	ioutil "io/ioutil"
-	"os"
 	"strings"
 )
 
-func init() {
-	log.SetOutput(os.Stdout)
-	log.SetLevel(log.InfoLevel)
-}
-
 type Pattern struct {
 	// input dimension
 	Features []float64
@@ -24,63 +15,27 @@ type Pattern struct {
 	SingleExpectation float64
 	// multiple class classification problems
 	MultipleExpectation []float64
+	// raw values of categorical features, encoded by the Encoder of the model
+	Categories []string
 }
 
 // LoadPatternsFromCSVFile load a CSV dataset into an array of Pattern.
+// The file has no header, features are numeric and the class label is in the last column.
 func LoadPatternsFromCSVFile(filePath string) ([]Pattern, error, []string) {
-	var patterns []Pattern
-	fileContent, errorValue := ioutil.ReadFile(filePath)
+	return LoadPatternsFromCSVFileWithOptions(filePath, CSVOptions{})
+}
 
-	if errorValue != nil {
-		log.WithFields(log.Fields{
-			"level":      "fatal",
-			"place":      "patterns",
-			"method":     "LoadPatternsFromCSVFile",
-			"msg":        "reading file in specific path",
-			"filePath":   filePath,
-			"errorValue": errorValue,
-		}).Fatal("Failed to read file in specified path.")
-		return patterns, errorValue, nil
+// LoadRegressionPatternsFromCSVFile load a CSV dataset with continuous targets into an array of Pattern.
+// The last [targets:int] columns are parsed as float64 targets and stored in MultipleExpectation,
+// the first of them is also stored in SingleExpectation.
+// It returns an error if any value can't be parsed as float64.
+func LoadRegressionPatternsFromCSVFile(filePath string, targets int) ([]Pattern, error) {
+	var options = CSVOptions{Regression: true, TargetColumns: make([]int, targets)}
+	for t := range options.TargetColumns {
+		options.TargetColumns[t] = t - targets
 	}
-	pointer := csv.NewReader(strings.NewReader(string(fileContent)))
-	var lineCounter = 0
-	for {
-		line, errorValue := pointer.Read()
-		log.WithFields(log.Fields{
-			"level":  "debug",
-			"place":  "patterns",
-			"method": "LoadPatternsFromCSVFile",
-			"line":   line,
-		}).Debug()
-		if errorValue == io.EOF {
-			log.WithFields(log.Fields{
-				"level":    "info",
-				"place":    "patterns",
-				"method":   "LoadPatternsFromCSVFile",
-				"readData": len(patterns),
-				"msg":      "File reading completed.",
-			}).Info("File reading completed.")
-			break
-		}
-		if errorValue != nil {
-			log.WithFields(log.Fields{
-				"level":       "errorValue",
-				"place":       "patterns",
-				"method":      "LoadPatternsFromCSVFile",
-				"msg":         "parsing file in specific line number",
-				"lineCounter": lineCounter,
-				"errorValue":  errorValue,
-			}).Error("Failed to parse line.")
-			return patterns, errorValue, nil
-		}
-		var floatingValues = util.StringToFloat(line, 1, -1.0)
-		patterns = append(
-			patterns,
-			Pattern{Features: floatingValues, SingleRawExpectation: line[len(line)-1]})
-		lineCounter = lineCounter + 1
-	}
-	mapped := RawExpectedConversion(patterns)
-	return patterns, nil, mapped
+	patterns, errorValue, _ := LoadPatternsFromCSVFileWithOptions(filePath, options)
+	return patterns, errorValue
 }
 
 // RawExpectedConversion converts (string) raw expected values in patterns
@@ -93,15 +48,17 @@ func RawExpectedConversion(patterns []Pattern) []string {
 		if !check {
 			rawExpectedValues = append(rawExpectedValues, pattern.SingleRawExpectation)
 		}
-		log.WithFields(log.Fields{
-			"level":            "debug",
-			"place":            "patterns",
-			"msg":              "raw class extraction",
-			"rawExpectedAdded": pattern.SingleRawExpectation,
-		}).Debug()
+		if loadingLog.Enabled(logging.TraceLevel) {
+			loadingLog.WithFields(logging.Fields{
+				"level":            "trace",
+				"place":            "patterns",
+				"msg":              "raw class extraction",
+				"rawExpectedAdded": pattern.SingleRawExpectation,
+			}).Trace()
+		}
 	}
 
-	log.WithFields(log.Fields{
+	loadingLog.WithFields(logging.Fields{
 		"level":             "info",
 		"place":             "patterns",
 		"msg":               "raw class extraction completed",
@@ -127,14 +84,16 @@ func CreateRandomPatternArray(d int, k int) []Pattern {
 		a := util.GenerateRandomIntWithBinaryDim(d)
 		b := util.GenerateRandomIntWithBinaryDim(d)
 		c := a + b
-		log.WithFields(log.Fields{
-			"ai": a,
-			"as": util.ConvertIntToBinary(a, d),
-			"bi": b,
-			"bs": util.ConvertIntToBinary(b, d),
-			"ci": c,
-			"cs": util.ConvertIntToBinary(c, d+1),
-		}).Debug()
+		if loadingLog.Enabled(logging.TraceLevel) {
+			loadingLog.WithFields(logging.Fields{
+				"ai": a,
+				"as": util.ConvertIntToBinary(a, d),
+				"bi": b,
+				"bs": util.ConvertIntToBinary(b, d),
+				"ci": c,
+				"cs": util.ConvertIntToBinary(c, d+1),
+			}).Trace()
+		}
 		ab := util.ConvertIntToBinary(a, d)
 		bb := util.ConvertIntToBinary(b, d)
 		for _, v := range bb {
@@ -0,0 +1,86 @@
+package neural
+
+import (
+	"MultilayerPerceptron/util"
+)
+
+// Perceptron is a single layer perceptron: a NeuronUnit classifying binary patterns, together with
+// the preprocessing applied to features before they reach the neuron.
+type Perceptron struct {
+	// neuron trained on preprocessed features
+	NeuronUnit
+	// feature scaler applied to every pattern before prediction (nil for raw features)
+	Scaler *Scaler
+	// categorical feature encoder applied to every pattern before scaling (nil without categorical features)
+	Encoder *Encoder
+	// missing value imputer applied to every pattern before encoding (nil without missing values)
+	Imputer *Imputer
+	// class labels of predictions 0 and 1 (nil if unknown)
+	Classes []string
+}
+
+// Fit fits the preprocessing of the perceptron on patterns, then trains the neuron on binary classes
+// with TrainNeuron, sizing Weights to the preprocessed features if they don't match.
+func (perceptron *Perceptron) Fit(patterns []Pattern, classes []string, epochs int) error {
+	if len(patterns) == 0 {
+		return emptyDatasetError("Perceptron.Fit")
+	}
+	var errorValue error
+	if perceptron.Imputer, perceptron.Encoder, perceptron.Scaler, errorValue = FitPreprocessing(perceptron.Imputer, perceptron.Encoder, perceptron.Scaler, patterns); errorValue != nil {
+		return errorValue
+	}
+	perceptron.Classes = classes
+
+	train := make([]Pattern, len(patterns))
+	for i := range patterns {
+		train[i] = perceptron.transform(&patterns[i])
+	}
+	init := 0
+	if len(perceptron.Weights) != len(train[0].Features) {
+		init = 1
+	}
+	return TrainNeuron(&perceptron.NeuronUnit, train, epochs, init)
+}
+
+// Predict returns the binary class predicted by the neuron for the preprocessed features of pattern.
+func (perceptron *Perceptron) Predict(pattern *Pattern) (float64, error) {
+	transformed := perceptron.transform(pattern)
+	return Predict(&perceptron.NeuronUnit, &transformed)
+}
+
+// PredictProba returns the probabilities of classes 0 and 1, from the sigmoid of the neuron activation.
+func (perceptron *Perceptron) PredictProba(pattern *Pattern) ([]float64, error) {
+	features := modelFeatures(perceptron.Imputer, perceptron.Encoder, perceptron.Scaler, pattern)
+	product, errorValue := util.ScalarProduct(perceptron.Weights, features)
+	if errorValue != nil {
+		return nil, &ShapeError{What: "input features", Expected: len(perceptron.Weights), Actual: len(features)}
+	}
+	positive := SigmoidTransfer(product + perceptron.Bias)
+	return []float64{1.0 - positive, positive}, nil
+}
+
+// Clone returns an untrained perceptron with the same settings and preprocessing.
+func (perceptron *Perceptron) Clone() Model {
+	clone := *perceptron
+	clone.Weights = make([]float64, len(perceptron.Weights))
+	clone.Classes = nil
+	clone.Reset()
+	return &clone
+}
+
+// Reset sets weights and bias of the neuron to zero.
+func (perceptron *Perceptron) Reset() {
+	for index := range perceptron.Weights {
+		perceptron.Weights[index] = 0.0
+	}
+	perceptron.Bias = 0.0
+	perceptron.Value = 0.0
+	perceptron.Delta = 0.0
+}
+
+// transform returns a copy of pattern whose features are the ones the neuron sees.
+func (perceptron *Perceptron) transform(pattern *Pattern) Pattern {
+	transformed := *pattern
+	transformed.Features = modelFeatures(perceptron.Imputer, perceptron.Encoder, perceptron.Scaler, pattern)
+	return transformed
+}
@@ -0,0 +1,289 @@
+package neural
+
+import (
+	"MultilayerPerceptron/util"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// RecurrentLayer is a hidden layer of a RecurrentNetwork, whose outputs at each step depend on a state
+// left by the previous step. Error signals follow the convention of Delta: expected minus actual,
+// so that gradients are the direction parameters are moved along.
+// It is implemented by *SimpleRecurrentLayer, *LSTMLayer and *GRULayer.
+type RecurrentLayer interface {
+	// Inputs returns the number of inputs of each step.
+	Inputs() int
+	// Size returns the number of outputs of each step.
+	Size() int
+	// Check returns a ConfigError if the layer can't be executed.
+	Check() error
+	// Forward executes a step on input and updates the state, returning the outputs of the layer.
+	// If record is true, the step is kept for Backward.
+	Forward(input []float64, record bool) []float64
+	// Backward takes the error signal of the outputs of the last recorded step not yet propagated back,
+	// adds the gradients of the step to the gradients of the layer and returns the error signal of its inputs.
+	// The error signal of the state is carried to the previous step.
+	Backward(delta []float64) []float64
+	// Truncate forgets recorded steps and the error signal carried through the state, at the beginning of a window.
+	Truncate()
+	// State returns a copy of the state reached by the last step.
+	State() []float64
+	// SetState restores a state returned by State, or returns a ShapeError.
+	SetState(state []float64) error
+	// ResetState sets the state of the beginning of a sequence.
+	ResetState()
+	// Parameters returns weights and bias of the layer as rows, shared with the layer.
+	Parameters() [][]float64
+	// Gradients returns the gradients accumulated by Backward, with the shape of Parameters.
+	Gradients() [][]float64
+	// Reset initializes parameters with random values and clears gradients, state and recorded steps.
+	Reset()
+	// Clone returns a layer with the same shape and settings, with new random parameters.
+	Clone() RecurrentLayer
+}
+
+// SimpleRecurrentLayer is a layer of neurons receiving the inputs of the step and, if recurrent,
+// their own outputs at the previous step, as the hidden layer of an Elman network.
+type SimpleRecurrentLayer struct {
+	// InputWeights[j][k] connects input k to neuron j
+	InputWeights [][]float64
+	// RecurrentWeights[j][k] connects the previous output of neuron k to neuron j (nil without recurrence)
+	RecurrentWeights [][]float64
+	// bias of each neuron
+	Bias []float64
+	// transfer function of neurons by name (see TransferFunctionByName)
+	Transfer string
+	// initialization of the outputs of the previous step at the beginning of each sequence ("" is ZeroContext)
+	ContextInit ContextInit
+	// value of previous outputs with ConstantContext, bound of their values with RandomContext
+	ContextValue float64
+
+	// outputs of the previous step
+	state []float64
+	// steps recorded for Backward
+	history []simpleRecurrentStep
+	// error signal of the state, carried to the previous step
+	carry []float64
+	// gradients of InputWeights, RecurrentWeights and Bias
+	inputGradients     [][]float64
+	recurrentGradients [][]float64
+	biasGradients      []float64
+}
+
+// simpleRecurrentStep holds what Backward needs of a step executed by a SimpleRecurrentLayer.
+type simpleRecurrentStep struct {
+	input    []float64
+	previous []float64
+	output   []float64
+}
+
+// NewSimpleRecurrentLayer creates a layer of size neurons with random weights for inputs inputs,
+// and recurrent weights if recurrent is true. Transfer is the name of the transfer function of neurons.
+func NewSimpleRecurrentLayer(inputs int, size int, transfer string, recurrent bool) *SimpleRecurrentLayer {
+	layer := &SimpleRecurrentLayer{InputWeights: make([][]float64, size), Bias: make([]float64, size), Transfer: transfer}
+	for j := range layer.InputWeights {
+		layer.InputWeights[j] = make([]float64, inputs)
+	}
+	if recurrent {
+		layer.RecurrentWeights = make([][]float64, size)
+		for j := range layer.RecurrentWeights {
+			layer.RecurrentWeights[j] = make([]float64, size)
+		}
+	}
+	layer.Reset()
+	return layer
+}
+
+func (layer *SimpleRecurrentLayer) Inputs() int {
+	if len(layer.InputWeights) == 0 {
+		return 0
+	}
+	return len(layer.InputWeights[0])
+}
+
+func (layer *SimpleRecurrentLayer) Size() int {
+	return len(layer.InputWeights)
+}
+
+func (layer *SimpleRecurrentLayer) Check() error {
+	if layer.Size() == 0 || layer.Inputs() == 0 {
+		return &ConfigError{Field: "InputWeights", Reason: fmt.Sprintf("has shape %dx%d, no neurons or inputs", layer.Size(), layer.Inputs())}
+	}
+	if _, _, found := TransferFunctionByName(layer.Transfer); !found {
+		return &ConfigError{Field: "Transfer", Reason: fmt.Sprintf("%q is not a known transfer function", layer.Transfer)}
+	}
+	if errorValue := checkMatrix("InputWeights", layer.InputWeights, layer.Size(), layer.Inputs()); errorValue != nil {
+		return errorValue
+	}
+	if layer.RecurrentWeights != nil {
+		if errorValue := checkMatrix("RecurrentWeights", layer.RecurrentWeights, layer.Size(), layer.Size()); errorValue != nil {
+			return errorValue
+		}
+	}
+	if len(layer.Bias) != layer.Size() {
+		return &ConfigError{Field: "Bias", Reason: fmt.Sprintf("has %d values for %d neurons", len(layer.Bias), layer.Size())}
+	}
+	return nil
+}
+
+func (layer *SimpleRecurrentLayer) Forward(input []float64, record bool) []float64 {
+	if len(layer.state) != layer.Size() {
+		layer.ResetState()
+	}
+	tf, _, _ := TransferFunctionByName(layer.Transfer)
+	output := make([]float64, layer.Size())
+	for j := range output {
+		// shapes are verified by Check
+		value, _ := util.ScalarProduct(layer.InputWeights[j], input)
+		if layer.RecurrentWeights != nil {
+			recurrent, _ := util.ScalarProduct(layer.RecurrentWeights[j], layer.state)
+			value += recurrent
+		}
+		output[j] = tf(layer.Bias[j] + value)
+	}
+	if record {
+		layer.history = append(layer.history, simpleRecurrentStep{input: append([]float64(nil), input...), previous: layer.state, output: output})
+	}
+	layer.state = append([]float64(nil), output...)
+	return output
+}
+
+func (layer *SimpleRecurrentLayer) Backward(delta []float64) []float64 {
+	layer.allocateGradients()
+	step := layer.history[len(layer.history)-1]
+	layer.history = layer.history[:len(layer.history)-1]
+	_, tfd, _ := TransferFunctionByName(layer.Transfer)
+
+	signal := make([]float64, layer.Size())
+	for j := range signal {
+		signal[j] = delta[j]
+		if layer.carry != nil {
+			signal[j] += layer.carry[j]
+		}
+		signal[j] *= tfd(step.output[j])
+	}
+	inputDelta := make([]float64, layer.Inputs())
+	for j, s := range signal {
+		for k, value := range step.input {
+			layer.inputGradients[j][k] += s * value
+			inputDelta[k] += s * layer.InputWeights[j][k]
+		}
+		layer.biasGradients[j] += s
+	}
+	if layer.RecurrentWeights != nil {
+		layer.carry = make([]float64, layer.Size())
+		for j, s := range signal {
+			for k, value := range step.previous {
+				layer.recurrentGradients[j][k] += s * value
+				layer.carry[k] += s * layer.RecurrentWeights[j][k]
+			}
+		}
+	}
+	return inputDelta
+}
+
+func (layer *SimpleRecurrentLayer) Truncate() {
+	layer.history = nil
+	layer.carry = nil
+}
+
+func (layer *SimpleRecurrentLayer) State() []float64 {
+	state := make([]float64, layer.Size())
+	copy(state, layer.state)
+	return state
+}
+
+func (layer *SimpleRecurrentLayer) SetState(state []float64) error {
+	if len(state) != layer.Size() {
+		return &ShapeError{What: "state", Expected: layer.Size(), Actual: len(state)}
+	}
+	layer.state = append([]float64(nil), state...)
+	return nil
+}
+
+func (layer *SimpleRecurrentLayer) ResetState() {
+	layer.state = make([]float64, layer.Size())
+	for k := range layer.state {
+		switch layer.ContextInit {
+		case ConstantContext:
+			layer.state[k] = layer.ContextValue
+		case RandomContext:
+			layer.state[k] = (2.0*rand.Float64() - 1.0) * layer.ContextValue
+		}
+	}
+}
+
+func (layer *SimpleRecurrentLayer) Parameters() [][]float64 {
+	parameters := append(append([][]float64{}, layer.InputWeights...), layer.RecurrentWeights...)
+	return append(parameters, layer.Bias)
+}
+
+func (layer *SimpleRecurrentLayer) Gradients() [][]float64 {
+	layer.allocateGradients()
+	gradients := append(append([][]float64{}, layer.inputGradients...), layer.recurrentGradients...)
+	return append(gradients, layer.biasGradients)
+}
+
+func (layer *SimpleRecurrentLayer) Reset() {
+	randomizeMatrix(layer.InputWeights, layer.Inputs())
+	randomizeMatrix(layer.RecurrentWeights, layer.Size())
+	for j := range layer.Bias {
+		layer.Bias[j] = 0.0
+	}
+	layer.inputGradients, layer.recurrentGradients, layer.biasGradients = nil, nil, nil
+	layer.Truncate()
+	layer.ResetState()
+}
+
+func (layer *SimpleRecurrentLayer) Clone() RecurrentLayer {
+	clone := NewSimpleRecurrentLayer(layer.Inputs(), layer.Size(), layer.Transfer, layer.RecurrentWeights != nil)
+	clone.ContextInit, clone.ContextValue = layer.ContextInit, layer.ContextValue
+	clone.ResetState()
+	return clone
+}
+
+// allocateGradients creates the gradients of the layer if they don't have the shape of its parameters.
+func (layer *SimpleRecurrentLayer) allocateGradients() {
+	if len(layer.biasGradients) == layer.Size() {
+		return
+	}
+	layer.inputGradients = zeroMatrix(layer.Size(), layer.Inputs())
+	if layer.RecurrentWeights != nil {
+		layer.recurrentGradients = zeroMatrix(layer.Size(), layer.Size())
+	}
+	layer.biasGradients = make([]float64, layer.Size())
+}
+
+// zeroMatrix returns a rows x columns matrix of zeros.
+func zeroMatrix(rows int, columns int) [][]float64 {
+	matrix := make([][]float64, rows)
+	for i := range matrix {
+		matrix[i] = make([]float64, columns)
+	}
+	return matrix
+}
+
+// randomizeMatrix fills matrix with normal values of standard deviation 1/sqrt(fanIn), so that the sum
+// of fanIn weighted inputs neither vanishes nor saturates transfer functions.
+func randomizeMatrix(matrix [][]float64, fanIn int) {
+	scale := 1.0 / math.Sqrt(math.Max(float64(fanIn), 1.0))
+	for i := range matrix {
+		for j := range matrix[i] {
+			matrix[i][j] = rand.NormFloat64() * scale
+		}
+	}
+}
+
+// checkMatrix returns a ConfigError if matrix, the setting named field, isn't rows x columns.
+func checkMatrix(field string, matrix [][]float64, rows int, columns int) error {
+	if len(matrix) != rows {
+		return &ConfigError{Field: field, Reason: fmt.Sprintf("has %d rows, %d expected", len(matrix), rows)}
+	}
+	for i, row := range matrix {
+		if len(row) != columns {
+			return &ConfigError{Field: fmt.Sprintf("%s[%d]", field, i), Reason: fmt.Sprintf("has %d values, %d expected", len(row), columns)}
+		}
+	}
+	return nil
+}
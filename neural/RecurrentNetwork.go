@@ -0,0 +1,350 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/util"
+	"fmt"
+)
+
+// RecurrentNetwork is a recurrent network built from a stack of RecurrentLayer, followed by a dense output layer.
+// The first layer receives the features of each step, each other layer the outputs of the layer before it.
+// An Elman network has a single SimpleRecurrentLayer with recurrent weights. With OutputContext, the outputs of the previous step are fed back to the first layer next to the features,
+// as in a Jordan network.
+type RecurrentNetwork struct {
+	// recurrent hidden layers, from the input side to the output side
+	Layers []RecurrentLayer
+	// OutputWeights[j][k] connects output k of the last recurrent layer to output neuron j
+	OutputWeights [][]float64
+	// bias of each output neuron
+	OutputBias []float64
+	// transfer function of output neurons by name (see TransferFunctionByName)
+	OutputTransfer string
+	// loss function of outputs (nil for mean absolute error), reported in training logs
+	LossFunction lossFunction
+	// error signal of outputs for loss function (nil for expected minus actual, the signal of ½ squared error)
+	LossFunctionGradient lossFunction
+	// outputs of the previous step are inputs of the first layer, after the features (Jordan network)
+	OutputContext bool
+	// value of fed back outputs at the beginning of each sequence
+	OutputContextValue float64
+	// learning rate of gradient descent
+	LearningRate float64
+	// L2 regularization of every weight and bias
+	Regularization float64
+	// number of sequences trained side by side, whose updates are averaged (0 or 1 for one at a time)
+	BatchSize int
+	// number of steps of each window of truncated backpropagation through time (0 for full sequences)
+	Truncation int
+	// maximum L2 norm of the gradient of each window, larger gradients are scaled down (0 for no clipping)
+	GradientClip float64
+	// number of epochs the network was trained for
+	Epoch int
+	// preprocessing of features, fitted by Fit and FitSequences
+	Scaler  *Scaler
+	Encoder *Encoder
+	Imputer *Imputer
+	// class of each output, nil for regression
+	Classes []string
+	// logger and levels of training, instead of the ones set by logging.Configure (nil to use them)
+	Logging *logging.Options
+
+	// outputs of the previous step, fed back with OutputContext
+	context []float64
+	// steps recorded for backpropagation through time
+	history []recurrentStep
+	// gradients of OutputWeights and OutputBias
+	outputGradients [][]float64
+	biasGradients   []float64
+}
+
+// recurrentStep holds what backpropagation through time needs of a step executed by a RecurrentNetwork.
+type recurrentStep struct {
+	// outputs of the last recurrent layer
+	hidden []float64
+	// outputs of the network
+	output []float64
+	// expected output of the step (nil for steps without target)
+	expected []float64
+}
+
+// NewRecurrentNetwork creates a network stacking layers, followed by outputLayer sigmoid output neurons
+// with random weights. Inputs of each layer must match the outputs of the layer before it.
+func NewRecurrentNetwork(layers []RecurrentLayer, outputLayer int, learningRate float64) (network RecurrentNetwork) {
+	network.Layers = layers
+	network.LearningRate = learningRate
+	network.OutputTransfer = "sigmoid"
+	if len(layers) == 0 || outputLayer < 1 {
+		trainingLog.WithFields(logging.Fields{
+			"level":       "error",
+			"msg":         "recurrent network init failed",
+			"layers":      len(layers),
+			"outputLayer": outputLayer,
+		}).Error("Invalid layers of recurrent network.")
+		return
+	}
+	network.OutputWeights = zeroMatrix(outputLayer, layers[len(layers)-1].Size())
+	network.OutputBias = make([]float64, outputLayer)
+	randomizeMatrix(network.OutputWeights, layers[len(layers)-1].Size())
+	network.ResetState()
+	return
+}
+
+// PrepareJordanNet create a Jordan network, whose hidden layer receives the features of each step
+// together with the outputs of the network at the previous step.
+// [inputLayer:int] number of features of each step
+// [hiddenLayer:int] number of hidden neurons, without recurrent weights
+// [outputLayer:int] number of outputs of each step, fed back to the hidden layer
+// [learningRate:float64] is the learning rate of neural network
+// [transfer:string] is the name of the transfer function of hidden neurons (see TransferFunctionByName)
+// Fed back outputs start each sequence at 0, change OutputContextValue to use another value.
+func PrepareJordanNet(inputLayer int, hiddenLayer int, outputLayer int, learningRate float64, transfer string) (jordan RecurrentNetwork) {
+	if inputLayer < 1 || hiddenLayer < 1 || outputLayer < 1 {
+		trainingLog.WithFields(logging.Fields{
+			"level":  "error",
+			"msg":    "jordan network init failed",
+			"layers": []int{inputLayer, hiddenLayer, outputLayer},
+		}).Error("Invalid layer sizes of Jordan network.")
+		return
+	}
+	hidden := NewSimpleRecurrentLayer(inputLayer+outputLayer, hiddenLayer, transfer, false)
+	jordan = NewRecurrentNetwork([]RecurrentLayer{hidden}, outputLayer, learningRate)
+	jordan.OutputContext = true
+	jordan.ResetState()
+
+	trainingLog.WithFields(logging.Fields{
+		"level":        "info",
+		"msg":          "jordan network init completed",
+		"inputLayer":   inputLayer,
+		"hiddenLayer":  hiddenLayer,
+		"outputLayer":  outputLayer,
+		"learningRate": learningRate,
+	}).Info("Complete Jordan network init.")
+	return
+}
+
+// PrepareStackedNet create a deep recurrent network, with a stack of Elman-like recurrent hidden layers.
+// [layer:[]int] number of features of each step, neurons of each hidden layer and outputs of each step
+// [learningRate:float64] is the learning rate of neural network
+// [transfer:string] is the name of the transfer function of hidden neurons (see TransferFunctionByName)
+func PrepareStackedNet(layer []int, learningRate float64, transfer string) RecurrentNetwork {
+	return prepareStackedNet(layer, learningRate, "simple", func(inputs int, size int) RecurrentLayer {
+		return NewSimpleRecurrentLayer(inputs, size, transfer, true)
+	})
+}
+
+// PrepareLSTMNet create a recurrent network with a stack of LSTM hidden layers.
+// [layer:[]int] number of features of each step, neurons of each hidden layer and outputs of each step
+// [learningRate:float64] is the learning rate of neural network
+// [peepholes:bool] whether gates read the cells of their neurons
+func PrepareLSTMNet(layer []int, learningRate float64, peepholes bool) RecurrentNetwork {
+	return prepareStackedNet(layer, learningRate, "lstm", func(inputs int, size int) RecurrentLayer {
+		return NewLSTMLayer(inputs, size, peepholes)
+	})
+}
+
+// PrepareGRUNet create a recurrent network with a stack of GRU hidden layers.
+// [layer:[]int] number of features of each step, neurons of each hidden layer and outputs of each step
+// [learningRate:float64] is the learning rate of neural network
+func PrepareGRUNet(layer []int, learningRate float64) RecurrentNetwork {
+	return prepareStackedNet(layer, learningRate, "gru", func(inputs int, size int) RecurrentLayer {
+		return NewGRULayer(inputs, size)
+	})
+}
+
+// prepareStackedNet create a recurrent network whose hidden layers, of the kind logged as cell, are created by newLayer.
+func prepareStackedNet(layer []int, learningRate float64, cell string, newLayer func(inputs int, size int) RecurrentLayer) (stacked RecurrentNetwork) {
+	valid := len(layer) >= 3
+	for _, neurons := range layer {
+		valid = valid && neurons > 0
+	}
+	if !valid {
+		trainingLog.WithFields(logging.Fields{
+			"level":  "error",
+			"msg":    "stacked recurrent network init failed",
+			"cell":   cell,
+			"layers": layer,
+		}).Error("Invalid layer sizes of stacked recurrent network.")
+		return
+	}
+	layers := make([]RecurrentLayer, len(layer)-2)
+	for i := range layers {
+		layers[i] = newLayer(layer[i], layer[i+1])
+	}
+	stacked = NewRecurrentNetwork(layers, layer[len(layer)-1], learningRate)
+
+	trainingLog.WithFields(logging.Fields{
+		"level":        "info",
+		"msg":          "stacked recurrent network init completed",
+		"cell":         cell,
+		"layers":       layer,
+		"learningRate": learningRate,
+	}).Info("Complete stacked RNN init.")
+	return
+}
+
+// log returns the channel of training entries of the network.
+func (network *RecurrentNetwork) log() logging.Channel {
+	return trainingLog.With(network.Logging)
+}
+
+// Features returns the number of features of each step.
+func (network *RecurrentNetwork) Features() int {
+	if len(network.Layers) == 0 {
+		return 0
+	}
+	if network.OutputContext {
+		return network.Layers[0].Inputs() - len(network.OutputBias)
+	}
+	return network.Layers[0].Inputs()
+}
+
+// ResetState sets the state of every layer, and fed back outputs, to their values at the beginning of a sequence.
+func (network *RecurrentNetwork) ResetState() {
+	for _, layer := range network.Layers {
+		layer.ResetState()
+	}
+	network.context = nil
+	if network.OutputContext {
+		network.context = make([]float64, len(network.OutputBias))
+		for k := range network.context {
+			network.context[k] = network.OutputContextValue
+		}
+	}
+}
+
+// State returns a copy of the state reached by the last step: the state of each layer,
+// followed by fed back outputs with OutputContext.
+func (network *RecurrentNetwork) State() []float64 {
+	var state []float64
+	for _, layer := range network.Layers {
+		state = append(state, layer.State()...)
+	}
+	return append(state, network.context...)
+}
+
+// SetState restores the state of the network from state, as returned by State.
+// It returns a ShapeError if state doesn't have the length of the state of the network.
+func (network *RecurrentNetwork) SetState(state []float64) error {
+	length := 0
+	if network.OutputContext {
+		length = len(network.OutputBias)
+	}
+	for _, layer := range network.Layers {
+		length += len(layer.State())
+	}
+	if len(state) != length {
+		return &ShapeError{What: "state", Expected: length, Actual: len(state)}
+	}
+	for _, layer := range network.Layers {
+		size := len(layer.State())
+		if errorValue := layer.SetState(state[:size]); errorValue != nil {
+			return errorValue
+		}
+		state = state[size:]
+	}
+	if network.OutputContext {
+		network.context = append([]float64(nil), state...)
+	}
+	return nil
+}
+
+// Step executes the network on pattern, the next step of the current sequence, updating the state of its layers.
+// It returns output values by network, a ConfigError if layers don't fit together
+// or a ShapeError if pattern features don't match the first layer.
+func (network *RecurrentNetwork) Step(pattern *Pattern) ([]float64, error) {
+	if errorValue := checkRecurrentNet(network); errorValue != nil {
+		return nil, errorValue
+	}
+	output, errorValue := network.step(pattern, false)
+	if errorValue != nil {
+		return nil, errorValue
+	}
+	return append([]float64(nil), output...), nil
+}
+
+// step executes the network on pattern, recording the step in every layer for backpropagation if record is true.
+func (network *RecurrentNetwork) step(pattern *Pattern, record bool) ([]float64, error) {
+	features := modelFeatures(network.Imputer, network.Encoder, network.Scaler, pattern)
+	if len(features) != network.Features() {
+		return nil, &ShapeError{What: "input features", Expected: network.Features(), Actual: len(features)}
+	}
+	values := features
+	if network.OutputContext {
+		if len(network.context) != len(network.OutputBias) {
+			network.ResetState()
+		}
+		values = append(append([]float64{}, features...), network.context...)
+	}
+	for _, layer := range network.Layers {
+		values = layer.Forward(values, record)
+	}
+
+	otf, _, _ := TransferFunctionByName(network.OutputTransfer)
+	output := make([]float64, len(network.OutputBias))
+	for j := range output {
+		sum, errorValue := util.ScalarProduct(network.OutputWeights[j], values)
+		if errorValue != nil {
+			return nil, errorValue
+		}
+		output[j] = otf(network.OutputBias[j] + sum)
+	}
+	if network.OutputContext {
+		network.context = append([]float64(nil), output...)
+	}
+	if record {
+		network.history = append(network.history, recurrentStep{hidden: values, output: output})
+	}
+	return output, nil
+}
+
+// truncate forgets recorded steps and error signals carried through states, in the network and in its layers.
+func (network *RecurrentNetwork) truncate() {
+	network.history = nil
+	for _, layer := range network.Layers {
+		layer.Truncate()
+	}
+}
+
+// parameters returns weights and bias of every layer and of output neurons as rows, with their gradients.
+func (network *RecurrentNetwork) parameters() ([][]float64, [][]float64) {
+	if len(network.biasGradients) != len(network.OutputBias) {
+		network.outputGradients = zeroMatrix(len(network.OutputWeights), network.Layers[len(network.Layers)-1].Size())
+		network.biasGradients = make([]float64, len(network.OutputBias))
+	}
+	var parameters, gradients [][]float64
+	for _, layer := range network.Layers {
+		parameters = append(parameters, layer.Parameters()...)
+		gradients = append(gradients, layer.Gradients()...)
+	}
+	parameters = append(append(parameters, network.OutputWeights...), network.OutputBias)
+	gradients = append(append(gradients, network.outputGradients...), network.biasGradients)
+	return parameters, gradients
+}
+
+// checkRecurrentNet returns a ConfigError if layers of a recurrent network don't fit together.
+func checkRecurrentNet(network *RecurrentNetwork) error {
+	if len(network.Layers) == 0 {
+		return &ConfigError{Field: "Layers", Reason: "has no recurrent layer"}
+	}
+	for i, layer := range network.Layers {
+		if errorValue := layer.Check(); errorValue != nil {
+			return fmt.Errorf("Layers[%d]: %w", i, errorValue)
+		}
+		if i > 0 && layer.Inputs() != network.Layers[i-1].Size() {
+			return &ConfigError{Field: fmt.Sprintf("Layers[%d]", i), Reason: fmt.Sprintf("has %d inputs for %d outputs of previous layer", layer.Inputs(), network.Layers[i-1].Size())}
+		}
+	}
+	if _, _, found := TransferFunctionByName(network.OutputTransfer); !found {
+		return &ConfigError{Field: "OutputTransfer", Reason: fmt.Sprintf("%q is not a known transfer function", network.OutputTransfer)}
+	}
+	if len(network.OutputBias) == 0 {
+		return &ConfigError{Field: "OutputBias", Reason: "has no output neuron"}
+	}
+	if errorValue := checkMatrix("OutputWeights", network.OutputWeights, len(network.OutputBias), network.Layers[len(network.Layers)-1].Size()); errorValue != nil {
+		return errorValue
+	}
+	if network.Features() < 1 {
+		return &ConfigError{Field: "Layers[0]", Reason: fmt.Sprintf("has %d inputs, no room for features", network.Layers[0].Inputs())}
+	}
+	return nil
+}
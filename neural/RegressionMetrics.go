@@ -0,0 +1,119 @@
+package neural
+
+import (
+	"math"
+)
+
+type RegressionScore struct {
+	// root mean squared error
+	RMSE float64
+	// mean absolute error
+	MAE float64
+	// coefficient of determination
+	R2 float64
+	// mean absolute percentage error (zero actual values are skipped)
+	MAPE float64
+}
+
+// RegressionMetrics calculate RMSE, MAE, R² and MAPE between two float64 based slices.
+// It returns a RegressionScore, a ShapeError if slices have different length
+// or an error wrapping ErrEmptyDataset if they are empty.
+func RegressionMetrics(actual []float64, predicted []float64) (RegressionScore, error) {
+	if len(actual) != len(predicted) {
+		return RegressionScore{}, &ShapeError{What: "predictions", Expected: len(actual), Actual: len(predicted)}
+	}
+	if len(actual) == 0 {
+		return RegressionScore{}, emptyDatasetError("RegressionMetrics")
+	}
+
+	mean := 0.0
+	for _, value := range actual {
+		mean += value
+	}
+	mean = mean / float64(len(actual))
+
+	var squared, absolute, total, percentage = 0.0, 0.0, 0.0, 0.0
+	var percentageCounter = 0
+	for index, value := range actual {
+		e := value - predicted[index]
+		squared += e * e
+		absolute += math.Abs(e)
+		total += (value - mean) * (value - mean)
+		if value != 0.0 {
+			percentage += math.Abs(e / value)
+			percentageCounter++
+		}
+	}
+
+	score := RegressionScore{
+		RMSE: math.Sqrt(squared / float64(len(actual))),
+		MAE:  absolute / float64(len(actual)),
+		R2:   math.NaN(),
+		MAPE: math.NaN(),
+	}
+	if total > 0.0 {
+		score.R2 = 1.0 - squared/total
+	}
+	if percentageCounter > 0 {
+		score.MAPE = percentage / float64(percentageCounter) * 100.0
+	}
+	return score, nil
+}
+
+// MultiTargetRegressionMetrics calculate regression metrics between rows of actual and predicted values,
+// a row for each pattern and a column for each target. RMSE and MAE are computed over every value,
+// R² and MAPE for each target and averaged over targets (skipping NaN ones), so that targets on different
+// scales weigh the same. It returns a ShapeError if rows or their lengths differ, or an error wrapping
+// ErrEmptyDataset if there are no rows.
+func MultiTargetRegressionMetrics(actual [][]float64, predicted [][]float64) (RegressionScore, error) {
+	if len(actual) != len(predicted) {
+		return RegressionScore{}, &ShapeError{What: "predictions", Expected: len(actual), Actual: len(predicted)}
+	}
+	if len(actual) == 0 {
+		return RegressionScore{}, emptyDatasetError("MultiTargetRegressionMetrics")
+	}
+	targets := len(actual[0])
+	var flatActual, flatPredicted []float64
+	for index := range actual {
+		if len(actual[index]) != targets {
+			return RegressionScore{}, &ShapeError{What: "targets", Expected: targets, Actual: len(actual[index])}
+		}
+		if len(predicted[index]) != targets {
+			return RegressionScore{}, &ShapeError{What: "predictions", Expected: targets, Actual: len(predicted[index])}
+		}
+		flatActual = append(flatActual, actual[index]...)
+		flatPredicted = append(flatPredicted, predicted[index]...)
+	}
+	score, errorValue := RegressionMetrics(flatActual, flatPredicted)
+	if errorValue != nil || targets == 1 {
+		return score, errorValue
+	}
+
+	var r2, percentage = 0.0, 0.0
+	var r2Counter, percentageCounter = 0, 0
+	for target := 0; target < targets; target++ {
+		columnActual := make([]float64, len(actual))
+		columnPredicted := make([]float64, len(actual))
+		for index := range actual {
+			columnActual[index] = actual[index][target]
+			columnPredicted[index] = predicted[index][target]
+		}
+		column, _ := RegressionMetrics(columnActual, columnPredicted)
+		if !math.IsNaN(column.R2) {
+			r2 += column.R2
+			r2Counter++
+		}
+		if !math.IsNaN(column.MAPE) {
+			percentage += column.MAPE
+			percentageCounter++
+		}
+	}
+	score.R2, score.MAPE = math.NaN(), math.NaN()
+	if r2Counter > 0 {
+		score.R2 = r2 / float64(r2Counter)
+	}
+	if percentageCounter > 0 {
+		score.MAPE = percentage / float64(percentageCounter)
+	}
+	return score, nil
+}
@@ -0,0 +1,136 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/util"
+	"math"
+)
+
+type ScalingMethod string
+
+const (
+	// MinMaxScaling maps each feature in [0, 1] using training min and max
+	MinMaxScaling ScalingMethod = "minmax"
+	// StandardScaling centers each feature on training mean with unit standard deviation
+	StandardScaling ScalingMethod = "standard"
+	// RobustScaling centers each feature on training median and divides by interquartile range
+	RobustScaling ScalingMethod = "robust"
+	// MaxAbsScaling divides each feature by its training max absolute value
+	MaxAbsScaling ScalingMethod = "maxabs"
+	// L2Normalization scales each pattern to unit euclidean norm (it doesn't need fitting)
+	L2Normalization ScalingMethod = "l2"
+)
+
+type Scaler struct {
+	// scaling method
+	Method ScalingMethod
+	// value subtracted from each feature
+	Offset []float64
+	// value each feature is divided by, after subtracting offset
+	Scale []float64
+}
+
+// NewScaler creates an unfitted Scaler with passed method.
+func NewScaler(method ScalingMethod) *Scaler {
+	return &Scaler{Method: method}
+}
+
+// FitScaler computes offset and scale of each feature from patterns passed (training set).
+// Constant features get a scale of 1, so they are only shifted.
+// It returns a ShapeError if patterns don't have the same number of features.
+func FitScaler(scaler *Scaler, patterns []Pattern) error {
+	if scaler.Method == L2Normalization || len(patterns) == 0 {
+		return nil
+	}
+	if errorValue := checkFeatures(patterns); errorValue != nil {
+		return errorValue
+	}
+	dim := len(patterns[0].Features)
+	scaler.Offset = make([]float64, dim)
+	scaler.Scale = make([]float64, dim)
+	column := make([]float64, len(patterns))
+
+	for f := 0; f < dim; f++ {
+		for index := range patterns {
+			column[index] = patterns[index].Features[f]
+		}
+		switch scaler.Method {
+		case MinMaxScaling:
+			minimum, maximum := math.Inf(1), math.Inf(-1)
+			for _, value := range column {
+				minimum = math.Min(minimum, value)
+				maximum = math.Max(maximum, value)
+			}
+			scaler.Offset[f], scaler.Scale[f] = minimum, maximum-minimum
+		case StandardScaling:
+			mean, variance := 0.0, 0.0
+			for _, value := range column {
+				mean += value
+			}
+			mean = mean / float64(len(column))
+			for _, value := range column {
+				variance += (value - mean) * (value - mean)
+			}
+			scaler.Offset[f], scaler.Scale[f] = mean, math.Sqrt(variance/float64(len(column)))
+		case RobustScaling:
+			scaler.Offset[f] = util.Percentile(column, 50.0)
+			scaler.Scale[f] = util.Percentile(column, 75.0) - util.Percentile(column, 25.0)
+		case MaxAbsScaling:
+			maximum := 0.0
+			for _, value := range column {
+				maximum = math.Max(maximum, math.Abs(value))
+			}
+			scaler.Offset[f], scaler.Scale[f] = 0.0, maximum
+		}
+		if scaler.Scale[f] == 0.0 {
+			scaler.Scale[f] = 1.0
+		}
+	}
+
+	trainingLog.WithFields(logging.Fields{
+		"level":    "debug",
+		"place":    "scaler",
+		"method":   "FitScaler",
+		"scaling":  scaler.Method,
+		"features": dim,
+		"patterns": len(patterns),
+	}).Debug("Complete scaler fitting.")
+	return nil
+}
+
+// ScaleFeatures applies scaler to a features vector.
+// It returns a new slice, leaving passed features unchanged. An unfitted scaler returns a copy.
+func ScaleFeatures(scaler *Scaler, features []float64) []float64 {
+	scaled := make([]float64, len(features))
+	copy(scaled, features)
+	if scaler.Method == L2Normalization {
+		norm := 0.0
+		for _, value := range features {
+			norm += value * value
+		}
+		if norm > 0.0 {
+			norm = math.Sqrt(norm)
+			for index := range scaled {
+				scaled[index] = scaled[index] / norm
+			}
+		}
+		return scaled
+	}
+	for index := range scaled {
+		if index < len(scaler.Offset) {
+			scaled[index] = (scaled[index] - scaler.Offset[index]) / scaler.Scale[index]
+		}
+	}
+	return scaled
+}
+
+// ScalePatterns applies scaler to features of each pattern.
+// It returns new patterns, leaving passed ones unchanged.
+func ScalePatterns(scaler *Scaler, patterns []Pattern) []Pattern {
+	scaled := make([]Pattern, len(patterns))
+	for index, pattern := range patterns {
+		scaled[index] = pattern
+		scaled[index].Features = ScaleFeatures(scaler, pattern.Features)
+	}
+	return scaled
+}
@@ -0,0 +1,208 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/util"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Sequence is an ordered list of steps, fed to a recurrent network one at a time starting from a reset state.
+type Sequence struct {
+	// identifier of the sequence in its source ("" if unknown)
+	ID string
+	// features of each step, with its target in MultipleExpectation (regression) or SingleExpectation (classification)
+	Steps []Pattern
+	// whether only the last step holding data has a target, such as the class of the whole sequence
+	FinalTarget bool
+	// whether each step holds data, padding steps are false and skipped by training (nil if every step holds data)
+	Mask []bool
+}
+
+// Masked tells whether step t of sequence is padding.
+func (sequence *Sequence) Masked(t int) bool {
+	return sequence.Mask != nil && !sequence.Mask[t]
+}
+
+// Length returns the number of steps of sequence holding data.
+func (sequence *Sequence) Length() int {
+	length := 0
+	for t := range sequence.Steps {
+		if !sequence.Masked(t) {
+			length++
+		}
+	}
+	return length
+}
+
+// lastStep returns the index of the last step of sequence holding data, -1 if there isn't any.
+func (sequence *Sequence) lastStep() int {
+	for t := len(sequence.Steps) - 1; t >= 0; t-- {
+		if !sequence.Masked(t) {
+			return t
+		}
+	}
+	return -1
+}
+
+// PadSequences returns copies of sequences extended to length steps (the longest sequence if length is 0)
+// with masked padding steps, whose features are all value and which have no target.
+// It returns a ShapeError if a sequence is longer than length.
+func PadSequences(sequences []Sequence, length int, value float64) ([]Sequence, error) {
+	if length <= 0 {
+		for _, sequence := range sequences {
+			if len(sequence.Steps) > length {
+				length = len(sequence.Steps)
+			}
+		}
+	}
+	padded := make([]Sequence, len(sequences))
+	for s, sequence := range sequences {
+		if len(sequence.Steps) > length {
+			return nil, fmt.Errorf("sequence %d: %w", s, &ShapeError{What: "sequence steps", Expected: length, Actual: len(sequence.Steps)})
+		}
+		padded[s] = sequence
+		padded[s].Steps = make([]Pattern, length)
+		padded[s].Mask = make([]bool, length)
+		copy(padded[s].Steps, sequence.Steps)
+		for t := range padded[s].Mask {
+			padded[s].Mask[t] = t < len(sequence.Steps) && !sequence.Masked(t)
+		}
+		features := 0
+		if len(sequence.Steps) > 0 {
+			features = len(sequence.Steps[0].Features)
+		}
+		for t := len(sequence.Steps); t < length; t++ {
+			padded[s].Steps[t].Features = make([]float64, features)
+			for f := range padded[s].Steps[t].Features {
+				padded[s].Steps[t].Features[f] = value
+			}
+		}
+	}
+	return padded, nil
+}
+
+// BucketSequences sorts sequences by Length, groups them in buckets of size sequences and pads
+// each bucket to its longest sequence with PadSequences, so that sequences of a batch are about as long.
+// Buckets are returned one after the other: train on them with BatchSize equal to size.
+// It returns a ConfigError if size is less than 1.
+func BucketSequences(sequences []Sequence, size int, value float64) ([]Sequence, error) {
+	if size < 1 {
+		return nil, &ConfigError{Field: "size", Reason: fmt.Sprintf("is %d, at least 1 needed", size)}
+	}
+	sorted := append([]Sequence{}, sequences...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Length() < sorted[j].Length()
+	})
+	bucketed := make([]Sequence, 0, len(sorted))
+	for start := 0; start < len(sorted); start += size {
+		end := start + size
+		if end > len(sorted) {
+			end = len(sorted)
+		}
+		bucket, errorValue := PadSequences(sorted[start:end], 0, value)
+		if errorValue != nil {
+			return nil, errorValue
+		}
+		bucketed = append(bucketed, bucket...)
+	}
+	return bucketed, nil
+}
+
+// CreateBinaryAdditionSequences creates k sequences adding two random numbers of d bits.
+// Step t holds the t-th bit of both numbers, least significant first, as features and the t-th bit
+// of their sum as target, so that the carry must be remembered from the previous step. Sequences have d+1 steps.
+func CreateBinaryAdditionSequences(d int, k int) []Sequence {
+	sequences := make([]Sequence, k)
+	for s := range sequences {
+		a := rand.Int63n(int64(1) << uint(d))
+		b := rand.Int63n(int64(1) << uint(d))
+		// most significant bit first
+		ab := util.ConvertIntToBinary(a, d+1)
+		bb := util.ConvertIntToBinary(b, d+1)
+		cb := util.ConvertIntToBinary(a+b, d+1)
+		sequences[s].ID = fmt.Sprintf("%d+%d", a, b)
+		sequences[s].Steps = make([]Pattern, d+1)
+		for t := range sequences[s].Steps {
+			bit := d - t
+			sequences[s].Steps[t] = Pattern{Features: []float64{ab[bit], bb[bit]}, MultipleExpectation: []float64{cb[bit]}, SingleExpectation: cb[bit]}
+		}
+	}
+	return sequences
+}
+
+// CreateAddingProblemSequences creates k sequences of the adding problem, with length steps each.
+// Step t holds a random value in [0, 1) and a marker, 1 for two steps (one in each half of the sequence)
+// and 0 for the others. The target of the sequence is half the sum of the two marked values, so that it fits
+// in [0, 1]: the network must remember the first marked value until the end of the sequence.
+// Predicting 0.5 for every sequence gives a mean squared error of about 1/24.
+// It logs an error and returns nil if length is less than 2.
+func CreateAddingProblemSequences(length int, k int) []Sequence {
+	if length < 2 {
+		loadingLog.WithFields(logging.Fields{
+			"level":  "error",
+			"place":  "sequences",
+			"method": "CreateAddingProblemSequences",
+			"length": length,
+		}).Error("Adding problem sequences need at least 2 steps.")
+		return nil
+	}
+	sequences := make([]Sequence, k)
+	for s := range sequences {
+		first := rand.Intn((length + 1) / 2)
+		second := (length+1)/2 + rand.Intn(length/2)
+		sequences[s].ID = fmt.Sprintf("%d+%d", first, second)
+		sequences[s].FinalTarget = true
+		sequences[s].Steps = make([]Pattern, length)
+		sum := 0.0
+		for t := range sequences[s].Steps {
+			marker := 0.0
+			value := rand.Float64()
+			if t == first || t == second {
+				marker = 1.0
+				sum += value
+			}
+			sequences[s].Steps[t].Features = []float64{value, marker}
+		}
+		last := &sequences[s].Steps[length-1]
+		last.MultipleExpectation = []float64{sum / 2.0}
+		last.SingleExpectation = sum / 2.0
+	}
+	return sequences
+}
+
+// CreateDelayedRecallSequences creates k sequences of delay+2 steps, recalling one of symbols symbols.
+// Features are a one-hot symbol followed by a cue flag: the first step shows a random symbol, delay
+// blank steps follow, and the last step only raises the cue. The target of the sequence is the symbol,
+// as class index in SingleExpectation and one-hot in MultipleExpectation of the last step.
+// It logs an error and returns nil if there are no symbols or delay is negative.
+func CreateDelayedRecallSequences(symbols int, delay int, k int) []Sequence {
+	if symbols < 1 || delay < 0 {
+		loadingLog.WithFields(logging.Fields{
+			"level":   "error",
+			"place":   "sequences",
+			"method":  "CreateDelayedRecallSequences",
+			"symbols": symbols,
+			"delay":   delay,
+		}).Error("Delayed recall sequences need a symbol and a non negative delay.")
+		return nil
+	}
+	sequences := make([]Sequence, k)
+	for s := range sequences {
+		symbol := rand.Intn(symbols)
+		sequences[s].ID = fmt.Sprintf("%d", symbol)
+		sequences[s].FinalTarget = true
+		sequences[s].Steps = make([]Pattern, delay+2)
+		for t := range sequences[s].Steps {
+			sequences[s].Steps[t].Features = make([]float64, symbols+1)
+		}
+		sequences[s].Steps[0].Features[symbol] = 1.0
+		last := &sequences[s].Steps[delay+1]
+		last.Features[symbols] = 1.0
+		last.MultipleExpectation = make([]float64, symbols)
+		last.MultipleExpectation[symbol] = 1.0
+		last.SingleExpectation = float64(symbol)
+	}
+	return sequences
+}
@@ -0,0 +1,296 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/util"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SequenceCSVOptions describes a CSV dataset with a row for each step, grouped in sequences by an id column.
+type SequenceCSVOptions struct {
+	// columns of each row, a step of its sequence (the sequence id column is ignored as a feature)
+	CSVOptions
+	// index of the column holding the id of the sequence of each row, negative indexes count from the end
+	IDColumn int
+	// name of the sequence id column, used instead of IDColumn (requires Header)
+	IDName string
+	// only the target of the last row of each sequence is used, as target of the whole sequence
+	FinalTarget bool
+}
+
+// SequenceJSONLinesOptions describes a JSON Lines dataset with a line for each sequence.
+type SequenceJSONLinesOptions struct {
+	// targets are numbers or arrays of numbers instead of class labels
+	Regression bool
+	// logger and levels of loading, instead of the ones set by logging.Configure (nil to use them)
+	Logging *logging.Options
+}
+
+// sequenceLine is a sequence as written in a line of a JSON Lines file.
+type sequenceLine struct {
+	ID       interface{}   `json:"id"`
+	Features []interface{} `json:"features"`
+	Targets  []interface{} `json:"targets"`
+	Target   interface{}   `json:"target"`
+}
+
+// LoadSequencesFromCSVFile load a CSV dataset with a row for each step into an array of Sequence.
+// See ReadSequencesFromCSV for options.
+func LoadSequencesFromCSVFile(filePath string, options SequenceCSVOptions) ([]Sequence, error, []string) {
+	return loadSequencesFile(filePath, "LoadSequencesFromCSVFile", loadingLog.With(options.Logging), func(r io.Reader) ([]Sequence, error, []string) {
+		return ReadSequencesFromCSV(r, options)
+	})
+}
+
+// ReadSequencesFromCSV reads a CSV dataset from r, with a row for each step, into an array of Sequence.
+// Rows are parsed as patterns described by options, and grouped by the value of the sequence id column
+// in order of first appearance, keeping the order of rows within each sequence.
+// It returns sequences and the class of each mapped value, sorted as Dataset.Classes (nil for regression).
+func ReadSequencesFromCSV(r io.Reader, options SequenceCSVOptions) ([]Sequence, error, []string) {
+	schema := &csvSchema{options: options.CSVOptions}
+	records, lines, errorValue := readRecords(r, schema)
+	if errorValue != nil {
+		return nil, errorValue, nil
+	}
+	if len(records) == 0 {
+		return nil, nil, nil
+	}
+
+	schema.columns = len(records[0])
+	var id int
+	if options.IDName != "" {
+		indexes, errorValue := schema.columnsByName([]string{options.IDName})
+		if errorValue != nil {
+			return nil, errorValue, nil
+		}
+		id = indexes[0]
+	} else if id, errorValue = schema.columnIndex(options.IDColumn); errorValue != nil {
+		return nil, errorValue, nil
+	}
+	schema.options.IgnoreColumns = append(append([]int{}, options.IgnoreColumns...), id)
+
+	patterns, errorValue, mapped := schema.patterns(records, lines)
+	if errorValue != nil {
+		return nil, errorValue, nil
+	}
+	var sequences []Sequence
+	positions := make(map[string]int)
+	for index, pattern := range patterns {
+		key := strings.TrimSpace(records[index][id])
+		position, found := positions[key]
+		if !found {
+			position = len(sequences)
+			positions[key] = position
+			sequences = append(sequences, Sequence{ID: key, FinalTarget: options.FinalTarget})
+		}
+		sequences[position].Steps = append(sequences[position].Steps, pattern)
+	}
+	if mapped != nil {
+		mapped = sortSequenceClasses(sequences, mapped)
+	}
+	return sequences, nil, mapped
+}
+
+// LoadSequencesFromJSONLinesFile load a JSON Lines dataset with a line for each sequence into an array of Sequence.
+// See ReadSequencesFromJSONLines for the format.
+func LoadSequencesFromJSONLinesFile(filePath string, options SequenceJSONLinesOptions) ([]Sequence, error, []string) {
+	return loadSequencesFile(filePath, "LoadSequencesFromJSONLinesFile", loadingLog.With(options.Logging), func(r io.Reader) ([]Sequence, error, []string) {
+		return ReadSequencesFromJSONLines(r, options)
+	})
+}
+
+// ReadSequencesFromJSONLines reads a JSON Lines dataset from r, one object for each sequence, into an array of Sequence.
+// Objects have an optional "id", "features" with the features of each step (an array of numbers,
+// or a number for a single feature, null for missing values), and either "targets" with the target of each step
+// or "target" with the target of the whole sequence (FinalTarget). Sequences without targets can be used for prediction.
+// Targets are class labels (strings or numbers), or numbers and arrays of numbers stored in MultipleExpectation
+// with options.Regression.
+// It returns sequences and the class of each mapped value, sorted as Dataset.Classes (nil for regression).
+func ReadSequencesFromJSONLines(r io.Reader, options SequenceJSONLinesOptions) ([]Sequence, error, []string) {
+	var sequences []Sequence
+	var classes []string
+	features := -1
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var object sequenceLine
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.UseNumber()
+		decoder.DisallowUnknownFields()
+		if errorValue := decoder.Decode(&object); errorValue != nil {
+			return nil, fmt.Errorf("line %d: %w", line, errorValue), nil
+		}
+		if object.Targets != nil && len(object.Targets) != len(object.Features) {
+			return nil, fmt.Errorf("line %d: %w", line, &ShapeError{What: "targets", Expected: len(object.Features), Actual: len(object.Targets)}), nil
+		}
+
+		sequence := Sequence{Steps: make([]Pattern, len(object.Features)), FinalTarget: object.Target != nil}
+		if object.ID != nil {
+			var errorValue error
+			if sequence.ID, _, errorValue = jsonScalar(object.ID); errorValue != nil {
+				return nil, fmt.Errorf("line %d, id: %w", line, errorValue), nil
+			}
+		}
+		for t, value := range object.Features {
+			values, errorValue := jsonValues(value)
+			if errorValue != nil {
+				return nil, fmt.Errorf("line %d, step %d: %w", line, t+1, errorValue), nil
+			}
+			if features < 0 {
+				features = len(values)
+			}
+			if len(values) != features {
+				return nil, fmt.Errorf("line %d, step %d: %w", line, t+1, &ShapeError{What: "step features", Expected: features, Actual: len(values)}), nil
+			}
+			sequence.Steps[t].Features = values
+		}
+
+		targets := object.Targets
+		if sequence.FinalTarget && len(sequence.Steps) > 0 {
+			targets = make([]interface{}, len(sequence.Steps))
+			targets[len(targets)-1] = object.Target
+		}
+		for t, value := range targets {
+			if value == nil {
+				continue
+			}
+			step := &sequence.Steps[t]
+			if options.Regression {
+				values, errorValue := jsonValues(value)
+				if errorValue != nil {
+					return nil, fmt.Errorf("line %d, target of step %d: %w", line, t+1, errorValue), nil
+				}
+				step.MultipleExpectation = values
+				if len(values) > 0 {
+					step.SingleExpectation = values[0]
+				}
+				continue
+			}
+			label, _, errorValue := jsonScalar(value)
+			if errorValue != nil {
+				return nil, fmt.Errorf("line %d, target of step %d: %w", line, t+1, errorValue), nil
+			}
+			found, index := util.StringInSlice(label, classes)
+			if !found {
+				index = len(classes)
+				classes = append(classes, label)
+			}
+			step.SingleRawExpectation = label
+			step.SingleExpectation = float64(index)
+		}
+		sequences = append(sequences, sequence)
+	}
+	if errorValue := scanner.Err(); errorValue != nil {
+		return nil, errorValue, nil
+	}
+	if options.Regression {
+		classes = nil
+	} else {
+		classes = sortSequenceClasses(sequences, classes)
+	}
+
+	loadingLog.With(options.Logging).WithFields(logging.Fields{
+		"level":     "info",
+		"place":     "sequences",
+		"msg":       "sequence reading completed",
+		"sequences": len(sequences),
+		"classes":   len(classes),
+	}).Debug("Complete JSON Lines sequence reading.")
+
+	return sequences, nil, classes
+}
+
+// sortSequenceClasses numbers the labelled steps of sequences with the sorted classes, as NewDataset does,
+// so that shuffling the rows of a file doesn't renumber its labels. It returns the sorted classes.
+func sortSequenceClasses(sequences []Sequence, classes []string) []string {
+	sorted := sortedClasses(classes)
+	positions := make(map[string]float64, len(sorted))
+	for position, class := range sorted {
+		positions[class] = float64(position)
+	}
+	for s := range sequences {
+		for t := range sequences[s].Steps {
+			step := &sequences[s].Steps[t]
+			if position, found := positions[step.SingleRawExpectation]; found {
+				step.SingleExpectation = position
+			}
+		}
+	}
+	return sorted
+}
+
+// jsonValues converts a decoded JSON number, or array of numbers, to float64 values (NaN for null).
+func jsonValues(value interface{}) ([]float64, error) {
+	array, isArray := value.([]interface{})
+	if !isArray {
+		array = []interface{}{value}
+	}
+	values := make([]float64, len(array))
+	for index, element := range array {
+		text, isString, errorValue := jsonScalar(element)
+		if errorValue != nil {
+			return nil, errorValue
+		}
+		if isString {
+			return nil, fmt.Errorf("cannot use string %q as a number", text)
+		}
+		if text == "" {
+			values[index] = math.NaN()
+			continue
+		}
+		if values[index], errorValue = strconv.ParseFloat(text, 64); errorValue != nil {
+			return nil, errorValue
+		}
+	}
+	return values, nil
+}
+
+// loadSequencesFile opens the file at filePath and reads sequences from it with read, logging the outcome as method on log.
+func loadSequencesFile(filePath string, method string, log logging.Channel, read func(r io.Reader) ([]Sequence, error, []string)) ([]Sequence, error, []string) {
+	file, errorValue := os.Open(filePath)
+	if errorValue != nil {
+		log.WithFields(logging.Fields{
+			"level":      "error",
+			"place":      "sequences",
+			"method":     method,
+			"filePath":   filePath,
+			"errorValue": errorValue,
+		}).Error("Failed to read file in specified path.")
+		return nil, errorValue, nil
+	}
+	defer file.Close()
+
+	sequences, errorValue, mapped := read(file)
+	if errorValue != nil {
+		log.WithFields(logging.Fields{
+			"level":      "error",
+			"place":      "sequences",
+			"method":     method,
+			"filePath":   filePath,
+			"errorValue": errorValue,
+		}).Error("Failed to parse file.")
+		return nil, fmt.Errorf("%s: %w", filePath, errorValue), nil
+	}
+
+	log.WithFields(logging.Fields{
+		"level":     "info",
+		"place":     "sequences",
+		"method":    method,
+		"sequences": len(sequences),
+	}).Info("File reading completed.")
+
+	return sequences, nil, mapped
+}
@@ -0,0 +1,62 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// recordingLogger keeps the methods of the entries it receives.
+type recordingLogger struct {
+	mutex   sync.Mutex
+	methods []interface{}
+}
+
+func (logger *recordingLogger) Log(level logging.Level, subsystem logging.Subsystem, msg string, fields logging.Fields) {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	logger.methods = append(logger.methods, fields["method"])
+}
+
+func TestSequenceLoadersLogging(t *testing.T) {
+	directory := t.TempDir()
+	csvPath := filepath.Join(directory, "sequences.csv")
+	jsonPath := filepath.Join(directory, "sequences.jsonl")
+	if errorValue := os.WriteFile(csvPath, []byte("id,x,class\n1,0.5,a\n1,0.2,b\n2,0.1,a\n"), 0o600); errorValue != nil {
+		t.Fatal(errorValue)
+	}
+	if errorValue := os.WriteFile(jsonPath, []byte(`{"id":1,"features":[0.5,0.2],"target":"a"}`+"\n"), 0o600); errorValue != nil {
+		t.Fatal(errorValue)
+	}
+
+	tests := []struct {
+		method string
+		load   func(options *logging.Options) ([]Sequence, error, []string)
+	}{
+		{"LoadSequencesFromCSVFile", func(options *logging.Options) ([]Sequence, error, []string) {
+			return LoadSequencesFromCSVFile(csvPath, SequenceCSVOptions{CSVOptions: CSVOptions{Header: true, Logging: options}})
+		}},
+		{"LoadSequencesFromJSONLinesFile", func(options *logging.Options) ([]Sequence, error, []string) {
+			return LoadSequencesFromJSONLinesFile(jsonPath, SequenceJSONLinesOptions{Logging: options})
+		}},
+	}
+	for _, test := range tests {
+		logger := &recordingLogger{}
+		sequences, errorValue, _ := test.load(&logging.Options{Logger: logger})
+		if errorValue != nil {
+			t.Fatalf("%s: %v", test.method, errorValue)
+		}
+		if len(sequences) == 0 {
+			t.Errorf("%s loaded no sequences", test.method)
+		}
+		found := false
+		for _, method := range logger.methods {
+			found = found || method == test.method
+		}
+		if !found {
+			t.Errorf("%s logged %v on the logger of its options, want an entry of the method", test.method, logger.methods)
+		}
+	}
+}
@@ -0,0 +1,486 @@
+package neural
+
+import (
+	"MultilayerPerceptron/logging"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+// mlpSnapshot is the serializable form of a MultiLayerNetwork,
+// where transfer and loss functions are stored by name.
+type mlpSnapshot struct {
+	NeuralLayers           []NeuralLayer
+	LearningRate           float64
+	TransferFunction       string
+	OutputTransferFunction string `json:",omitempty"`
+	LossFunction           string `json:",omitempty"`
+	Regularization         float64
+	BatchSize              int
+	Epoch                  int
+	Scaler                 *Scaler  `json:",omitempty"`
+	Encoder                *Encoder `json:",omitempty"`
+	Imputer                *Imputer `json:",omitempty"`
+	Classes                []string `json:",omitempty"`
+}
+
+// recurrentSnapshot is the serializable form of a RecurrentNetwork, with the current state of the sequence.
+type recurrentSnapshot struct {
+	Layers             []recurrentLayerSnapshot
+	OutputWeights      [][]float64
+	OutputBias         []float64
+	OutputTransfer     string
+	LossFunction       string `json:",omitempty"`
+	OutputContext      bool
+	OutputContextValue float64
+	LearningRate       float64
+	Regularization     float64
+	BatchSize          int
+	Truncation         int
+	GradientClip       float64
+	Epoch              int
+	Scaler             *Scaler  `json:",omitempty"`
+	Encoder            *Encoder `json:",omitempty"`
+	Imputer            *Imputer `json:",omitempty"`
+	Classes            []string `json:",omitempty"`
+	State              []float64
+}
+
+// elmanSnapshot is the serializable form of an ElmanNetwork written before it was built from a SimpleRecurrentLayer,
+// with input, hidden and output layers as in a multi layer network. It is only read, to convert older files.
+type elmanSnapshot struct {
+	mlpSnapshot
+	ContextLayer     NeuralLayer
+	RecurrentWeights [][]float64
+	ContextInit      ContextInit `json:",omitempty"`
+	ContextValue     float64
+	Truncation       int
+	GradientClip     float64
+}
+
+// recurrentLayerSnapshot is a recurrent layer, with the name of its type.
+type recurrentLayerSnapshot struct {
+	Type  string
+	Layer json.RawMessage
+}
+
+// WriteMLPNet serializes a multi layer Perceptron neural network as JSON, together with
+// its weights, feature scaler, categorical encoder, missing value imputer and class labels, so that Execute on raw inputs applies the same transform.
+// Custom loss functions (such as HuberLoss) aren't serialized and must be set again to resume training.
+func WriteMLPNet(w io.Writer, multiLayerPerceptron *MultiLayerNetwork) error {
+	snapshot, errorValue := newMLPSnapshot(multiLayerPerceptron)
+	if errorValue != nil {
+		return errorValue
+	}
+	return writeSnapshot(w, snapshot)
+}
+
+// ReadMLPNet deserializes a multi layer Perceptron neural network written by WriteMLPNet.
+func ReadMLPNet(r io.Reader) (multiLayerPerceptron MultiLayerNetwork, errorValue error) {
+	var snapshot mlpSnapshot
+	if errorValue = json.NewDecoder(r).Decode(&snapshot); errorValue != nil {
+		return
+	}
+	return restoreMLPSnapshot(&snapshot)
+}
+
+// WriteRecurrentNet serializes a recurrent network built from recurrent layers, such as an Elman, Jordan or
+// stacked network, as JSON, together with the type and weights of each layer, preprocessing, class labels,
+// training settings and current state, so that a sequence can be resumed after ReadRecurrentNet.
+// Layers must be of a type of this package. Custom loss functions (such as HuberLoss) aren't serialized
+// and must be set again to resume training.
+func WriteRecurrentNet(w io.Writer, network *RecurrentNetwork) error {
+	snapshot := recurrentSnapshot{
+		Layers:             make([]recurrentLayerSnapshot, len(network.Layers)),
+		OutputWeights:      network.OutputWeights,
+		OutputBias:         network.OutputBias,
+		OutputTransfer:     network.OutputTransfer,
+		OutputContext:      network.OutputContext,
+		OutputContextValue: network.OutputContextValue,
+		LearningRate:       network.LearningRate,
+		Regularization:     network.Regularization,
+		BatchSize:          network.BatchSize,
+		Truncation:         network.Truncation,
+		GradientClip:       network.GradientClip,
+		Epoch:              network.Epoch,
+		Scaler:             network.Scaler,
+		Encoder:            network.Encoder,
+		Imputer:            network.Imputer,
+		Classes:            network.Classes,
+		State:              network.State(),
+	}
+	if network.LossFunction != nil && sameFunction(network.LossFunction, MeanSquaredLoss) {
+		snapshot.LossFunction = "mse"
+	}
+	for i, layer := range network.Layers {
+		layerType := recurrentLayerType(layer)
+		if layerType == "" {
+			return fmt.Errorf("recurrent layer %d of type %T is not serializable", i, layer)
+		}
+		encoded, errorValue := json.Marshal(layer)
+		if errorValue != nil {
+			return errorValue
+		}
+		snapshot.Layers[i] = recurrentLayerSnapshot{Type: layerType, Layer: encoded}
+	}
+	return writeSnapshot(w, snapshot)
+}
+
+// ReadRecurrentNet deserializes a recurrent network written by WriteRecurrentNet.
+// It returns an error for unknown layer types and for layers that don't fit together.
+func ReadRecurrentNet(r io.Reader) (network RecurrentNetwork, errorValue error) {
+	var snapshot recurrentSnapshot
+	if errorValue = json.NewDecoder(r).Decode(&snapshot); errorValue != nil {
+		return
+	}
+	network.Layers = make([]RecurrentLayer, len(snapshot.Layers))
+	for i, layer := range snapshot.Layers {
+		if network.Layers[i] = newRecurrentLayer(layer.Type); network.Layers[i] == nil {
+			return network, fmt.Errorf("unknown type %q of recurrent layer %d", layer.Type, i)
+		}
+		if errorValue = json.Unmarshal(layer.Layer, network.Layers[i]); errorValue != nil {
+			return network, fmt.Errorf("recurrent layer %d: %w", i, errorValue)
+		}
+	}
+	network.OutputWeights = snapshot.OutputWeights
+	network.OutputBias = snapshot.OutputBias
+	network.OutputTransfer = snapshot.OutputTransfer
+	if snapshot.LossFunction == "mse" {
+		network.LossFunction = MeanSquaredLoss
+		network.LossFunctionGradient = MeanSquaredLossGradient
+	}
+	network.OutputContext = snapshot.OutputContext
+	network.OutputContextValue = snapshot.OutputContextValue
+	network.LearningRate = snapshot.LearningRate
+	network.Regularization = snapshot.Regularization
+	network.BatchSize = snapshot.BatchSize
+	network.Truncation = snapshot.Truncation
+	network.GradientClip = snapshot.GradientClip
+	network.Epoch = snapshot.Epoch
+	network.Scaler = snapshot.Scaler
+	network.Encoder = snapshot.Encoder
+	network.Imputer = snapshot.Imputer
+	network.Classes = snapshot.Classes
+	if errorValue = checkRecurrentNet(&network); errorValue != nil {
+		return
+	}
+	errorValue = network.SetState(snapshot.State)
+	return
+}
+
+// WriteElmanNet serializes an Elman network as WriteRecurrentNet does, so that it can also be read by ReadRecurrentNet.
+func WriteElmanNet(w io.Writer, elman *ElmanNetwork) error {
+	return WriteRecurrentNet(w, &elman.RecurrentNetwork)
+}
+
+// ReadElmanNet deserializes an Elman network written by WriteElmanNet, or by WriteRecurrentNet from a network
+// with a single recurrent SimpleRecurrentLayer. Files written by earlier versions of WriteElmanNet, with the
+// network stored as a multi layer network and a context layer, are converted with the same weights and state.
+// It returns a ConfigError if the network is not an Elman network.
+func ReadElmanNet(r io.Reader) (elman ElmanNetwork, errorValue error) {
+	var encoded json.RawMessage
+	if errorValue = json.NewDecoder(r).Decode(&encoded); errorValue != nil {
+		return
+	}
+	var format struct{ NeuralLayers json.RawMessage }
+	if errorValue = json.Unmarshal(encoded, &format); errorValue != nil {
+		return
+	}
+	if format.NeuralLayers != nil {
+		var snapshot elmanSnapshot
+		if errorValue = json.Unmarshal(encoded, &snapshot); errorValue != nil {
+			return
+		}
+		elman.RecurrentNetwork, errorValue = restoreElmanSnapshot(&snapshot)
+		return
+	}
+	if elman.RecurrentNetwork, errorValue = ReadRecurrentNet(bytes.NewReader(encoded)); errorValue != nil {
+		return
+	}
+	errorValue = checkElmanNet(&elman.RecurrentNetwork)
+	return
+}
+
+// restoreElmanSnapshot converts an Elman network written by earlier versions of WriteElmanNet to a RecurrentNetwork:
+// the hidden layer and recurrent weights become a SimpleRecurrentLayer, whose state is the context layer.
+func restoreElmanSnapshot(snapshot *elmanSnapshot) (network RecurrentNetwork, errorValue error) {
+	mlp, errorValue := restoreMLPSnapshot(&snapshot.mlpSnapshot)
+	if errorValue != nil {
+		return
+	}
+	if len(mlp.NeuralLayers) != 3 {
+		return network, &ConfigError{Field: "NeuralLayers", Reason: fmt.Sprintf("has %d layers, an Elman network needs 3", len(mlp.NeuralLayers))}
+	}
+	hiddenLayer, outputLayer := mlp.NeuralLayers[1], mlp.NeuralLayers[2]
+	hidden := &SimpleRecurrentLayer{
+		InputWeights:     make([][]float64, len(hiddenLayer.NeuronUnits)),
+		RecurrentWeights: snapshot.RecurrentWeights,
+		Bias:             make([]float64, len(hiddenLayer.NeuronUnits)),
+		Transfer:         snapshot.TransferFunction,
+		ContextInit:      snapshot.ContextInit,
+		ContextValue:     snapshot.ContextValue,
+	}
+	for j, neuron := range hiddenLayer.NeuronUnits {
+		hidden.InputWeights[j], hidden.Bias[j] = neuron.Weights, neuron.Bias
+	}
+	network.Layers = []RecurrentLayer{hidden}
+	network.OutputWeights = make([][]float64, len(outputLayer.NeuronUnits))
+	network.OutputBias = make([]float64, len(outputLayer.NeuronUnits))
+	for j, neuron := range outputLayer.NeuronUnits {
+		network.OutputWeights[j], network.OutputBias[j] = neuron.Weights, neuron.Bias
+	}
+	// output neurons used the transfer function of hidden neurons unless an output one was set
+	network.OutputTransfer = snapshot.OutputTransferFunction
+	if network.OutputTransfer == "" {
+		network.OutputTransfer = snapshot.TransferFunction
+	}
+	network.LossFunction, network.LossFunctionGradient = mlp.LossFunction, mlp.LossFunctionGradient
+	network.LearningRate = mlp.LearningRate
+	network.Regularization = mlp.Regularization
+	network.BatchSize = mlp.BatchSize
+	network.Truncation = snapshot.Truncation
+	network.GradientClip = snapshot.GradientClip
+	network.Epoch = mlp.Epoch
+	network.Scaler = mlp.Scaler
+	network.Encoder = mlp.Encoder
+	network.Imputer = mlp.Imputer
+	network.Classes = mlp.Classes
+	if errorValue = checkElmanNet(&network); errorValue != nil {
+		return
+	}
+	if errorValue = checkRecurrentNet(&network); errorValue != nil {
+		return
+	}
+	state := make([]float64, len(snapshot.ContextLayer.NeuronUnits))
+	for k, unit := range snapshot.ContextLayer.NeuronUnits {
+		state[k] = unit.Value
+	}
+	errorValue = network.SetState(state)
+	return
+}
+
+// recurrentLayerType returns the name a recurrent layer is serialized with, or an empty string for unknown types.
+func recurrentLayerType(layer RecurrentLayer) string {
+	switch layer.(type) {
+	case *SimpleRecurrentLayer:
+		return "simple"
+	case *LSTMLayer:
+		return "lstm"
+	case *GRULayer:
+		return "gru"
+	}
+	return ""
+}
+
+// newRecurrentLayer returns an empty recurrent layer of the type serialized with name, or nil for unknown names.
+func newRecurrentLayer(name string) RecurrentLayer {
+	switch name {
+	case "simple":
+		return &SimpleRecurrentLayer{}
+	case "lstm":
+		return &LSTMLayer{}
+	case "gru":
+		return &GRULayer{}
+	}
+	return nil
+}
+
+// newMLPSnapshot returns the serializable form of a network, or an error if its transfer function is not serializable.
+func newMLPSnapshot(multiLayerPerceptron *MultiLayerNetwork) (mlpSnapshot, error) {
+	snapshot := mlpSnapshot{
+		NeuralLayers:           multiLayerPerceptron.NeuralLayers,
+		LearningRate:           multiLayerPerceptron.LearningRate,
+		TransferFunction:       transferFunctionName(multiLayerPerceptron.TransferFunction),
+		OutputTransferFunction: transferFunctionName(multiLayerPerceptron.OutputTransferFunction),
+		Regularization:         multiLayerPerceptron.Regularization,
+		BatchSize:              multiLayerPerceptron.BatchSize,
+		Epoch:                  multiLayerPerceptron.Epoch,
+		Scaler:                 multiLayerPerceptron.Scaler,
+		Encoder:                multiLayerPerceptron.Encoder,
+		Imputer:                multiLayerPerceptron.Imputer,
+		Classes:                multiLayerPerceptron.Classes,
+	}
+	if multiLayerPerceptron.LossFunction != nil && sameFunction(multiLayerPerceptron.LossFunction, MeanSquaredLoss) {
+		snapshot.LossFunction = "mse"
+	}
+	if snapshot.TransferFunction == "" {
+		return snapshot, fmt.Errorf("transfer function of network is not serializable")
+	}
+	return snapshot, nil
+}
+
+// writeSnapshot writes snapshot as indented JSON.
+func writeSnapshot(w io.Writer, snapshot interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// restoreMLPSnapshot returns the network serialized in snapshot, or an error for unknown function names.
+func restoreMLPSnapshot(snapshot *mlpSnapshot) (multiLayerPerceptron MultiLayerNetwork, errorValue error) {
+	var found bool
+	multiLayerPerceptron.TransferFunction, multiLayerPerceptron.TransferFunctionDerivative, found = TransferFunctionByName(snapshot.TransferFunction)
+	if !found {
+		return multiLayerPerceptron, fmt.Errorf("unknown transfer function %q", snapshot.TransferFunction)
+	}
+	if snapshot.OutputTransferFunction != "" {
+		multiLayerPerceptron.OutputTransferFunction, multiLayerPerceptron.OutputTransferFunctionDerivative, found = TransferFunctionByName(snapshot.OutputTransferFunction)
+		if !found {
+			return multiLayerPerceptron, fmt.Errorf("unknown output transfer function %q", snapshot.OutputTransferFunction)
+		}
+	}
+	if snapshot.LossFunction == "mse" {
+		multiLayerPerceptron.LossFunction = MeanSquaredLoss
+		multiLayerPerceptron.LossFunctionGradient = MeanSquaredLossGradient
+	}
+	multiLayerPerceptron.NeuralLayers = snapshot.NeuralLayers
+	multiLayerPerceptron.LearningRate = snapshot.LearningRate
+	multiLayerPerceptron.Regularization = snapshot.Regularization
+	multiLayerPerceptron.BatchSize = snapshot.BatchSize
+	multiLayerPerceptron.Epoch = snapshot.Epoch
+	multiLayerPerceptron.Scaler = snapshot.Scaler
+	multiLayerPerceptron.Encoder = snapshot.Encoder
+	multiLayerPerceptron.Imputer = snapshot.Imputer
+	multiLayerPerceptron.Classes = snapshot.Classes
+	return
+}
+
+// SaveMLPNet writes a multi layer Perceptron neural network in the file at filePath.
+func SaveMLPNet(multiLayerPerceptron *MultiLayerNetwork, filePath string) error {
+	file, errorValue := os.Create(filePath)
+	if errorValue != nil {
+		return errorValue
+	}
+	if errorValue = WriteMLPNet(file, multiLayerPerceptron); errorValue != nil {
+		file.Close()
+		return errorValue
+	}
+
+	loadingLog.WithFields(logging.Fields{
+		"level":    "info",
+		"place":    "serialization",
+		"method":   "SaveMLPNet",
+		"filePath": filePath,
+	}).Info("Multilayer Perceptron saved.")
+
+	return file.Close()
+}
+
+// LoadMLPNet reads a multi layer Perceptron neural network from the file at filePath.
+func LoadMLPNet(filePath string) (MultiLayerNetwork, error) {
+	file, errorValue := os.Open(filePath)
+	if errorValue != nil {
+		return MultiLayerNetwork{}, errorValue
+	}
+	defer file.Close()
+	return ReadMLPNet(file)
+}
+
+// SaveRecurrentNet writes a recurrent network, with its current state, in the file at filePath.
+func SaveRecurrentNet(network *RecurrentNetwork, filePath string) error {
+	file, errorValue := os.Create(filePath)
+	if errorValue != nil {
+		return errorValue
+	}
+	if errorValue = WriteRecurrentNet(file, network); errorValue != nil {
+		file.Close()
+		return errorValue
+	}
+
+	loadingLog.WithFields(logging.Fields{
+		"level":    "info",
+		"place":    "serialization",
+		"method":   "SaveRecurrentNet",
+		"filePath": filePath,
+	}).Info("Recurrent network saved.")
+
+	return file.Close()
+}
+
+// LoadRecurrentNet reads a recurrent network written by SaveRecurrentNet from the file at filePath.
+func LoadRecurrentNet(filePath string) (RecurrentNetwork, error) {
+	file, errorValue := os.Open(filePath)
+	if errorValue != nil {
+		return RecurrentNetwork{}, errorValue
+	}
+	defer file.Close()
+	return ReadRecurrentNet(file)
+}
+
+// SaveElmanNet writes an Elman network, with its current state, in the file at filePath.
+func SaveElmanNet(elman *ElmanNetwork, filePath string) error {
+	file, errorValue := os.Create(filePath)
+	if errorValue != nil {
+		return errorValue
+	}
+	if errorValue = WriteElmanNet(file, elman); errorValue != nil {
+		file.Close()
+		return errorValue
+	}
+
+	loadingLog.WithFields(logging.Fields{
+		"level":    "info",
+		"place":    "serialization",
+		"method":   "SaveElmanNet",
+		"filePath": filePath,
+	}).Info("Elman network saved.")
+
+	return file.Close()
+}
+
+// LoadElmanNet reads an Elman network written by SaveElmanNet, or by earlier versions of it, from the file at filePath.
+func LoadElmanNet(filePath string) (ElmanNetwork, error) {
+	file, errorValue := os.Open(filePath)
+	if errorValue != nil {
+		return ElmanNetwork{}, errorValue
+	}
+	defer file.Close()
+	return ReadElmanNet(file)
+}
+
+// SavePerceptron writes a perceptron, with its preprocessing (scaler, encoder and imputer), as JSON in the file at filePath.
+func SavePerceptron(perceptron *Perceptron, filePath string) error {
+	file, errorValue := os.Create(filePath)
+	if errorValue != nil {
+		return errorValue
+	}
+	if errorValue = json.NewEncoder(file).Encode(perceptron); errorValue != nil {
+		file.Close()
+		return errorValue
+	}
+	return file.Close()
+}
+
+// LoadPerceptron reads a perceptron written by SavePerceptron from the file at filePath.
+func LoadPerceptron(filePath string) (perceptron Perceptron, errorValue error) {
+	file, errorValue := os.Open(filePath)
+	if errorValue != nil {
+		return
+	}
+	defer file.Close()
+	errorValue = json.NewDecoder(file).Decode(&perceptron)
+	return
+}
+
+// transferFunctionName returns the name of a transfer function known by TransferFunctionByName,
+// or an empty string for nil and unknown functions.
+func transferFunctionName(f transferFunction) string {
+	if f == nil {
+		return ""
+	}
+	for _, name := range []string{"heaviside", "sigmoid", "tanh", "linear"} {
+		known, _, _ := TransferFunctionByName(name)
+		if sameFunction(f, known) {
+			return name
+		}
+	}
+	return ""
+}
+
+// sameFunction tells whether two functions are the same top level function.
+func sameFunction(a interface{}, b interface{}) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
@@ -0,0 +1,199 @@
+package neural
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestMLPNetRoundTrip(t *testing.T) {
+	data := "color,size,weight,class\nred,1,?,a\ngreen,2,0.5,b\nred,3,0.25,a\nblue,4,?,b\n"
+	tests := []struct {
+		name       string
+		regression bool
+	}{
+		{"classification", false},
+		{"regression", true},
+	}
+	for _, test := range tests {
+		rand.Seed(1)
+		encoder, imputer := NewEncoder(OneHotEncoding, UnknownUnseen), NewImputer(MeanImputation)
+		imputer.Indicators = true
+		patterns, errorValue, classes := ReadPatternsFromCSV(strings.NewReader(data), CSVOptions{Header: true,
+			ColumnTypes: map[int]ColumnType{0: CategoricalColumn}, Encoder: encoder, Imputer: imputer, MissingValues: []string{"?"}})
+		if errorValue != nil {
+			t.Fatal(errorValue)
+		}
+		inputs := PreprocessedSize(imputer, encoder, len(patterns[0].Features))
+		var network MultiLayerNetwork
+		if test.regression {
+			network = PrepareRegressionMLPNet([]int{inputs, 3, 1}, 0.1, SigmoidTransfer, SigmoidTransferDerivative, MeanSquaredLoss, MeanSquaredLossGradient)
+			for index := range patterns {
+				patterns[index].MultipleExpectation = []float64{patterns[index].SingleExpectation}
+			}
+			classes = nil
+		} else {
+			network = PrepareMLPNet([]int{inputs, 3, len(classes)}, 0.1, HyperbolicTransfer, HyperbolicTransferDerivative)
+		}
+		network.Imputer, network.Encoder, network.Scaler = imputer, encoder, NewScaler(StandardScaling)
+		if errorValue := network.Fit(patterns, classes, 3); errorValue != nil {
+			t.Fatalf("%s: %v", test.name, errorValue)
+		}
+
+		var buffer bytes.Buffer
+		if errorValue := WriteMLPNet(&buffer, &network); errorValue != nil {
+			t.Fatalf("%s: %v", test.name, errorValue)
+		}
+		read, errorValue := ReadMLPNet(&buffer)
+		if errorValue != nil {
+			t.Fatalf("%s: %v", test.name, errorValue)
+		}
+		if read.Epoch != network.Epoch || len(read.Classes) != len(network.Classes) {
+			t.Errorf("%s: epochs and classes are not restored", test.name)
+		}
+		if test.regression && (read.LossFunction == nil || !sameFunction(read.LossFunction, MeanSquaredLoss)) {
+			t.Errorf("%s: loss function is not restored", test.name)
+		}
+		// raw patterns, with unseen categories and missing values, go through the restored preprocessing
+		for _, pattern := range append(patterns, Pattern{Features: []float64{5, math.NaN()}, Categories: []string{"purple"}}) {
+			expected, errorValue := Execute(&network, &pattern)
+			if errorValue != nil {
+				t.Fatalf("%s: %v", test.name, errorValue)
+			}
+			output, errorValue := Execute(&read, &pattern)
+			if errorValue != nil {
+				t.Fatalf("%s: %v", test.name, errorValue)
+			}
+			sameOutputs(t, [][]float64{expected}, [][]float64{output})
+		}
+	}
+}
+
+func TestRecurrentNetRoundTrip(t *testing.T) {
+	networks := map[string]func() RecurrentNetwork{
+		"elman":   func() RecurrentNetwork { return PrepareElmanNet(2, 4, 1, 0.1, "tanh").RecurrentNetwork },
+		"jordan":  func() RecurrentNetwork { return PrepareJordanNet(2, 4, 1, 0.1, "tanh") },
+		"stacked": func() RecurrentNetwork { return PrepareStackedNet([]int{2, 4, 3, 1}, 0.1, "tanh") },
+		"lstm":    func() RecurrentNetwork { return PrepareLSTMNet([]int{2, 4, 1}, 0.1, true) },
+		"gru":     func() RecurrentNetwork { return PrepareGRUNet([]int{2, 4, 1}, 0.1) },
+	}
+	for name, prepare := range networks {
+		t.Run(name, func(t *testing.T) {
+			rand.Seed(1)
+			network := prepare()
+			network.LossFunction, network.LossFunctionGradient = MeanSquaredLoss, MeanSquaredLossGradient
+			steps := CreateBinaryAdditionSequences(4, 1)[0].Steps
+			// the sequence is resumed after reading, from the state reached by the first steps
+			stepOutputs(t, &network, steps[:2])
+			var buffer bytes.Buffer
+			if errorValue := WriteRecurrentNet(&buffer, &network); errorValue != nil {
+				t.Fatal(errorValue)
+			}
+			read, errorValue := ReadRecurrentNet(&buffer)
+			if errorValue != nil {
+				t.Fatal(errorValue)
+			}
+			if read.LossFunction == nil || !sameFunction(read.LossFunction, MeanSquaredLoss) {
+				t.Errorf("loss function is not restored")
+			}
+			sameOutputs(t, stepOutputs(t, &network, steps[2:]), stepOutputs(t, &read, steps[2:]))
+		})
+	}
+}
+
+func TestElmanNetRoundTrip(t *testing.T) {
+	rand.Seed(1)
+	elman := PrepareElmanNet(2, 3, 2, 0.1, "sigmoid")
+	elman.ContextLayer().ContextInit, elman.ContextLayer().ContextValue = ConstantContext, 0.25
+	elman.GradientClip, elman.Classes = 1.0, []string{"a", "b"}
+	steps := CreateBinaryAdditionSequences(4, 1)[0].Steps
+	stepOutputs(t, &elman.RecurrentNetwork, steps[:2])
+
+	// an Elman network written by earlier versions, as a multi layer network with a context layer
+	hidden := elman.ContextLayer()
+	legacy := elmanSnapshot{
+		mlpSnapshot: mlpSnapshot{
+			NeuralLayers:     []NeuralLayer{PrepareLayer(2, 0), PrepareLayer(3, 0), PrepareLayer(2, 0)},
+			LearningRate:     elman.LearningRate,
+			TransferFunction: "sigmoid",
+			Classes:          elman.Classes,
+		},
+		ContextLayer:     PrepareLayer(3, 0),
+		RecurrentWeights: hidden.RecurrentWeights,
+		ContextInit:      ConstantContext,
+		ContextValue:     0.25,
+		GradientClip:     1.0,
+	}
+	for j := range hidden.Bias {
+		legacy.NeuralLayers[1].NeuronUnits[j].Weights, legacy.NeuralLayers[1].NeuronUnits[j].Bias = hidden.InputWeights[j], hidden.Bias[j]
+		legacy.ContextLayer.NeuronUnits[j].Value = elman.State()[j]
+	}
+	for j := range elman.OutputBias {
+		legacy.NeuralLayers[2].NeuronUnits[j].Weights, legacy.NeuralLayers[2].NeuronUnits[j].Bias = elman.OutputWeights[j], elman.OutputBias[j]
+	}
+
+	writers := map[string]func(buffer *bytes.Buffer) error{
+		"WriteElmanNet":     func(buffer *bytes.Buffer) error { return WriteElmanNet(buffer, &elman) },
+		"WriteRecurrentNet": func(buffer *bytes.Buffer) error { return WriteRecurrentNet(buffer, &elman.RecurrentNetwork) },
+		"legacy":            func(buffer *bytes.Buffer) error { return writeSnapshot(buffer, legacy) },
+	}
+	buffers := make(map[string]*bytes.Buffer)
+	for name, write := range writers {
+		buffers[name] = &bytes.Buffer{}
+		if errorValue := write(buffers[name]); errorValue != nil {
+			t.Fatalf("%s: %v", name, errorValue)
+		}
+	}
+	expected := stepOutputs(t, &elman.RecurrentNetwork, steps[2:])
+	for name, buffer := range buffers {
+		read, errorValue := ReadElmanNet(buffer)
+		if errorValue != nil {
+			t.Fatalf("%s: %v", name, errorValue)
+		}
+		if context := read.ContextLayer(); context.ContextInit != ConstantContext || context.ContextValue != 0.25 {
+			t.Errorf("%s: context initialization is %s %g", name, context.ContextInit, context.ContextValue)
+		}
+		if read.GradientClip != 1.0 || len(read.Classes) != 2 {
+			t.Errorf("%s: settings are not restored", name)
+		}
+		sameOutputs(t, expected, stepOutputs(t, &read.RecurrentNetwork, steps[2:]))
+	}
+
+	jordan := PrepareJordanNet(2, 3, 2, 0.1, "tanh")
+	var buffer bytes.Buffer
+	if errorValue := WriteRecurrentNet(&buffer, &jordan); errorValue != nil {
+		t.Fatal(errorValue)
+	}
+	var configError *ConfigError
+	if _, errorValue := ReadElmanNet(&buffer); !errors.As(errorValue, &configError) {
+		t.Errorf("ReadElmanNet of a Jordan network = %v, want a ConfigError", errorValue)
+	}
+}
+
+// stepOutputs executes network on steps, continuing the current sequence, and returns the outputs of each step.
+func stepOutputs(t *testing.T, network *RecurrentNetwork, steps []Pattern) [][]float64 {
+	t.Helper()
+	outputs := make([][]float64, len(steps))
+	for step := range steps {
+		var errorValue error
+		if outputs[step], errorValue = network.Step(&steps[step]); errorValue != nil {
+			t.Fatalf("Step %d: %v", step, errorValue)
+		}
+	}
+	return outputs
+}
+
+// sameOutputs reports an error if outputs differ from expected.
+func sameOutputs(t *testing.T, expected [][]float64, outputs [][]float64) {
+	t.Helper()
+	for step := range expected {
+		for j := range expected[step] {
+			if outputs[step][j] != expected[step][j] {
+				t.Fatalf("output %d of step %d = %g, want %g", j, step, outputs[step][j], expected[step][j])
+			}
+		}
+	}
+}
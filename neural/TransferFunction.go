@@ -6,6 +6,23 @@ import (
 
 type transferFunction func(float64) float64
 
+// TransferFunctionByName returns the transfer function with passed name
+// (heaviside, sigmoid, tanh, linear) and its derivative, which takes the output of the transfer function.
+// It returns false if name is unknown.
+func TransferFunctionByName(name string) (transferFunction, transferFunction, bool) {
+	switch name {
+	case "heaviside":
+		return HeavisideTransfer, HeavisideTransferDerivative, true
+	case "sigmoid":
+		return SigmoidTransfer, SigmoidTransferDerivative, true
+	case "tanh":
+		return HyperbolicTransfer, HyperbolicTransferDerivative, true
+	case "linear":
+		return LinearTransfer, LinearTransferDerivative, true
+	}
+	return nil, nil, false
+}
+
 func HeavisideTransfer(d float64) float64 {
 	if d >= 0.0 {
 		return 1.0
@@ -21,8 +38,10 @@ func SigmoidTransfer(d float64) float64 {
 	return 1 / (1 + math.Pow(math.E, -d))
 }
 
+// SigmoidTransferDerivative returns the derivative of SigmoidTransfer as a function of its value d,
+// as the other derivatives do.
 func SigmoidTransferDerivative(d float64) float64 {
-	return 1.0
+	return d * (1.0 - d)
 }
 
 func HyperbolicTransfer(d float64) float64 {
@@ -32,3 +51,11 @@ func HyperbolicTransfer(d float64) float64 {
 func HyperbolicTransferDerivative(d float64) float64 {
 	return 1 - math.Pow(d, 2)
 }
+
+func LinearTransfer(d float64) float64 {
+	return d
+}
+
+func LinearTransferDerivative(d float64) float64 {
+	return 1.0
+}
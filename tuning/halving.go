@@ -0,0 +1,233 @@
+package tuning
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/neural"
+	"MultilayerPerceptron/validation"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"text/tabwriter"
+)
+
+type Rung struct {
+	// Hyperband bracket of the rung (0 for plain successive halving)
+	Bracket int
+	// position of the rung inside its bracket
+	Number int
+	// epochs each configuration of the rung has been trained for
+	Epochs int
+	// ranked results of the configurations evaluated in the rung
+	Results []Result
+}
+
+// trial holds a configuration with its resumable k-fold validation state.
+type trial struct {
+	config Config
+	state  *validation.MLPKFoldState
+}
+
+// SuccessiveHalving evaluates configs with a budget of minEpochs, then repeatedly keeps the best
+// 1/eta of them and resumes their training with eta times more epochs, up to maxEpochs.
+// When a single configuration is left, it is trained directly for maxEpochs.
+// Patterns are split in k folds once and every configuration is scored with k-fold validation
+// on those folds, running up to workers evaluations in parallel.
+// It returns the leaderboard of each rung and the best result of the last rung, trained for maxEpochs.
+func SuccessiveHalving(configs []Config, dataset *neural.Dataset, k int, shuffle int, minEpochs int, maxEpochs int, eta int, workers int) ([]Rung, Result, error) {
+	if errorValue := checkBudget(configs, dataset.Patterns, minEpochs, maxEpochs, eta); errorValue != nil {
+		return nil, Result{}, errorValue
+	}
+	folds, errorValue := validation.KFoldIndexSplit(len(dataset.Patterns), k, shuffle)
+	if errorValue != nil {
+		return nil, Result{}, fmt.Errorf("SuccessiveHalving: %w", errorValue)
+	}
+	rungs, errorValue := successiveHalving(0, configs, dataset, folds, minEpochs, maxEpochs, eta, workers)
+	if errorValue != nil {
+		return nil, Result{}, errorValue
+	}
+	return rungs, rungs[len(rungs)-1].Results[0], nil
+}
+
+// Hyperband runs successive halving brackets trading off the number of configurations sampled
+// from the search space against the initial epochs budget given to each of them.
+// Brackets go from many configurations trained for minEpochs to few trained directly for maxEpochs,
+// and every bracket ends with its winner trained for maxEpochs. All brackets share the same k folds,
+// so their winners are compared on equal budget and equal folds.
+// It returns the leaderboard of each rung of every bracket and the best result trained for maxEpochs.
+func Hyperband(space SearchSpace, dataset *neural.Dataset, k int, shuffle int, minEpochs int, maxEpochs int, eta int, seed int64, workers int) ([]Rung, Result, error) {
+	if errorValue := space.Validate(); errorValue != nil {
+		return nil, Result{}, errorValue
+	}
+	if errorValue := checkBudget([]Config{{}}, dataset.Patterns, minEpochs, maxEpochs, eta); errorValue != nil {
+		return nil, Result{}, errorValue
+	}
+
+	folds, errorValue := validation.KFoldIndexSplit(len(dataset.Patterns), k, shuffle)
+	if errorValue != nil {
+		return nil, Result{}, fmt.Errorf("Hyperband: %w", errorValue)
+	}
+
+	random := rand.New(rand.NewSource(seed))
+	sMax := int(math.Floor(math.Log(float64(maxEpochs)/float64(minEpochs))/math.Log(float64(eta)) + 1e-9))
+
+	var rungs []Rung
+	var best Result
+	for s := sMax; s >= 0; s-- {
+		n := int(math.Ceil(float64(sMax+1) / float64(s+1) * math.Pow(float64(eta), float64(s))))
+		epochs := int(float64(maxEpochs) / math.Pow(float64(eta), float64(s)))
+		if epochs < minEpochs {
+			epochs = minEpochs
+		}
+		configs := make([]Config, n)
+		for i := range configs {
+			configs[i] = space.Sample(random)
+		}
+
+		bracket, errorValue := successiveHalving(sMax-s, configs, dataset, folds, epochs, maxEpochs, eta, workers)
+		if errorValue != nil {
+			return nil, Result{}, errorValue
+		}
+		rungs = append(rungs, bracket...)
+
+		winner := bracket[len(bracket)-1].Results[0]
+		if best.Rank == 0 || winner.Mean > best.Mean {
+			best = winner
+		}
+	}
+
+	validationLog.WithFields(logging.Fields{
+		"level":     "info",
+		"place":     "tuning",
+		"method":    "Hyperband",
+		"brackets":  sMax + 1,
+		"config":    best.Config.String(),
+		"meanScore": best.Mean,
+	}).Info("Hyperband completed.")
+
+	return rungs, best, nil
+}
+
+// successiveHalving runs one bracket of successive halving on folds starting from epochs budget,
+// until the best configuration has been trained for maxEpochs.
+// It returns the first error of the validations.
+func successiveHalving(bracket int, configs []Config, dataset *neural.Dataset, folds [][]int, epochs int, maxEpochs int, eta int, workers int) ([]Rung, error) {
+	trials := make([]trial, len(configs))
+	for i, config := range configs {
+		state, errorValue := validation.NewMLPFoldsState(config.Factory(dataset), dataset, folds)
+		if errorValue != nil {
+			return nil, errorValue
+		}
+		trials[i] = trial{config: config, state: state}
+	}
+
+	var rungs []Rung
+	for number := 0; ; number++ {
+		results := make([]Result, len(trials))
+		errorValues := make([]error, len(trials))
+		parallel(len(trials), workers, func(index int) {
+			config := trials[index].config
+			scores, _, errorValue := validation.ResumeMLPKFoldValidation(trials[index].state, dataset, epochs)
+			if errorValue != nil {
+				errorValues[index] = fmt.Errorf("config %s: %w", config.String(), errorValue)
+				return
+			}
+			config.Epochs = epochs
+			results[index] = newResult(config, scores)
+		})
+		if errorValue := firstError(errorValues); errorValue != nil {
+			return nil, errorValue
+		}
+
+		// rank trials together with their results
+		order := make([]int, len(results))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return better(results[order[i]], results[order[j]])
+		})
+		ranked := make([]trial, len(trials))
+		rankedResults := make([]Result, len(results))
+		for i, index := range order {
+			ranked[i] = trials[index]
+			rankedResults[i] = results[index]
+			rankedResults[i].Rank = i + 1
+		}
+		trials = ranked
+		rungs = append(rungs, Rung{Bracket: bracket, Number: number, Epochs: epochs, Results: rankedResults})
+
+		validationLog.WithFields(logging.Fields{
+			"level":     "info",
+			"place":     "tuning",
+			"method":    "SuccessiveHalving",
+			"bracket":   bracket,
+			"rung":      number,
+			"epochs":    epochs,
+			"trials":    len(trials),
+			"bestScore": rankedResults[0].Mean,
+		}).Info("Rung completed.")
+
+		if epochs >= maxEpochs {
+			break
+		}
+		keep := len(trials) / eta
+		if keep <= 1 {
+			// the winner of the bracket completes the whole budget
+			trials = trials[:1]
+			epochs = maxEpochs
+			continue
+		}
+		trials = trials[:keep]
+		epochs = epochs * eta
+		if epochs > maxEpochs {
+			epochs = maxEpochs
+		}
+	}
+	return rungs, nil
+}
+
+// checkBudget validates successive halving parameters.
+func checkBudget(configs []Config, patterns []neural.Pattern, minEpochs int, maxEpochs int, eta int) error {
+	if len(patterns) == 0 {
+		return neural.ErrEmptyDataset
+	}
+	if len(configs) == 0 {
+		return &neural.ConfigError{Field: "configs", Reason: "is empty"}
+	}
+	if eta < 2 {
+		return &neural.ConfigError{Field: "eta", Reason: fmt.Sprintf("is %d, at least 2 needed", eta)}
+	}
+	if minEpochs < 1 || maxEpochs < minEpochs {
+		return &neural.ConfigError{Field: "epochs", Reason: fmt.Sprintf("budget [%d, %d] is not a valid range", minEpochs, maxEpochs)}
+	}
+	return nil
+}
+
+// WriteLeaderboard writes the ranked results of each rung as aligned text tables.
+func WriteLeaderboard(w io.Writer, rungs []Rung) error {
+	for _, rung := range rungs {
+		if _, errorValue := fmt.Fprintf(w, "bracket %d, rung %d: %d configs, %d epochs\n", rung.Bracket, rung.Number, len(rung.Results), rung.Epochs); errorValue != nil {
+			return errorValue
+		}
+		if errorValue := WriteResults(w, rung.Results); errorValue != nil {
+			return errorValue
+		}
+		if _, errorValue := fmt.Fprintln(w); errorValue != nil {
+			return errorValue
+		}
+	}
+	return nil
+}
+
+// WriteLeaderboardSummary writes the best result of each rung as an aligned text table.
+func WriteLeaderboardSummary(w io.Writer, rungs []Rung) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "bracket\trung\tconfigs\tepochs\tbestMean\tbestStd\tbestConfig")
+	for _, rung := range rungs {
+		best := rung.Results[0]
+		fmt.Fprintf(table, "%d\t%d\t%d\t%d\t%.2f\t%.2f\t%s\n", rung.Bracket, rung.Number, len(rung.Results), rung.Epochs, best.Mean, best.Std, best.Config.String())
+	}
+	return table.Flush()
+}
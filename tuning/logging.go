@@ -0,0 +1,8 @@
+package tuning
+
+import (
+	"MultilayerPerceptron/logging"
+)
+
+// validationLog writes entries of hyperparameter search
+var validationLog = logging.For(logging.Validation)
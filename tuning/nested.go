@@ -0,0 +1,128 @@
+package tuning
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/neural"
+	"MultilayerPerceptron/validation"
+	"fmt"
+	"math"
+)
+
+// Searcher tunes hyperparameters using only patterns of passed dataset.
+// It returns ranked results and the best one.
+type Searcher func(dataset *neural.Dataset) ([]Result, Result, error)
+
+type NestedResult struct {
+	// score of the tuned model on each outer fold
+	OuterScores []float64
+	// configuration chosen by the inner search for each outer fold
+	Configs []Config
+	// best inner validation result of each outer fold
+	InnerBest []Result
+	// mean of outer fold scores
+	Mean float64
+	// standard deviation of outer fold scores
+	Std float64
+	// lowest outer fold score
+	Min float64
+	// highest outer fold score
+	Max float64
+}
+
+// GridSearcher returns a Searcher running GridSearch with innerK folds.
+func GridSearcher(space SearchSpace, innerK int, shuffle int, workers int) Searcher {
+	return func(dataset *neural.Dataset) ([]Result, Result, error) {
+		return GridSearch(space, dataset, innerK, shuffle, workers)
+	}
+}
+
+// RandomSearcher returns a Searcher running RandomSearch with innerK folds.
+func RandomSearcher(space SearchSpace, innerK int, shuffle int, iterations int, seed int64, workers int) Searcher {
+	return func(dataset *neural.Dataset) ([]Result, Result, error) {
+		return RandomSearch(space, dataset, innerK, shuffle, iterations, seed, workers)
+	}
+}
+
+// NestedCrossValidation splits patterns of dataset in outerK folds. For each outer fold, search tunes
+// hyperparameters on the other folds only, then a fresh network with the chosen configuration
+// is trained on them and scored on the outer fold, so that tuning never sees testing patterns.
+// It returns outer fold scores, the configuration chosen for each of them and summary statistics.
+func NestedCrossValidation(search Searcher, dataset *neural.Dataset, outerK int, shuffle int) (NestedResult, error) {
+	var result NestedResult
+	if len(dataset.Patterns) == 0 {
+		return result, fmt.Errorf("NestedCrossValidation: %w", neural.ErrEmptyDataset)
+	}
+
+	folds, errorValue := validation.KFoldPatternsSplit(dataset.Patterns, outerK, shuffle)
+	if errorValue != nil {
+		return result, fmt.Errorf("NestedCrossValidation: %w", errorValue)
+	}
+	for t := range folds {
+		var train []neural.Pattern
+		for i := range folds {
+			if i != t {
+				train = append(train, folds[i]...)
+			}
+		}
+		test := folds[t]
+
+		_, best, errorValue := search(neural.SubsetDataset(dataset, train))
+		if errorValue != nil {
+			return result, fmt.Errorf("NestedCrossValidation, outer fold %d: %w", t, errorValue)
+		}
+
+		model := best.Config.Model(dataset)
+		if errorValue = model.Fit(train, dataset.Classes, best.Config.Epochs); errorValue != nil {
+			return result, fmt.Errorf("NestedCrossValidation, outer fold %d: %w", t, errorValue)
+		}
+		actual := make([]float64, len(test))
+		predicted := make([]float64, len(test))
+		for i := range test {
+			actual[i] = test[i].SingleExpectation
+			if predicted[i], errorValue = model.Predict(&test[i]); errorValue != nil {
+				return result, fmt.Errorf("NestedCrossValidation, outer fold %d: %w", t, errorValue)
+			}
+		}
+		_, percentageCorrect, errorValue := neural.Accuracy(actual, predicted)
+		if errorValue != nil {
+			return result, fmt.Errorf("NestedCrossValidation, outer fold %d: %w", t, errorValue)
+		}
+
+		result.OuterScores = append(result.OuterScores, percentageCorrect)
+		result.Configs = append(result.Configs, best.Config)
+		result.InnerBest = append(result.InnerBest, best)
+
+		validationLog.WithFields(logging.Fields{
+			"level":             "info",
+			"place":             "tuning",
+			"method":            "NestedCrossValidation",
+			"foldNumber":        t,
+			"trainSetLen":       len(train),
+			"testSetLen":        len(test),
+			"config":            best.Config.String(),
+			"innerMeanScore":    best.Mean,
+			"percentageCorrect": percentageCorrect,
+		}).Info("Evaluation completed for current outer fold.")
+	}
+
+	summary := newResult(Config{}, result.OuterScores)
+	result.Mean = summary.Mean
+	result.Std = summary.Std
+	result.Min = math.Inf(1)
+	result.Max = math.Inf(-1)
+	for _, score := range result.OuterScores {
+		result.Min = math.Min(result.Min, score)
+		result.Max = math.Max(result.Max, score)
+	}
+
+	validationLog.WithFields(logging.Fields{
+		"level":      "info",
+		"place":      "tuning",
+		"method":     "NestedCrossValidation",
+		"outerFolds": outerK,
+		"meanScore":  result.Mean,
+		"stdScore":   result.Std,
+	}).Info("Evaluation completed for all outer folds.")
+
+	return result, nil
+}
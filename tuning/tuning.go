@@ -0,0 +1,293 @@
+package tuning
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/neural"
+	"MultilayerPerceptron/validation"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+type SearchSpace struct {
+	// number of neurons of each hidden layer
+	HiddenSizes []int
+	// number of hidden layers
+	Depths []int
+	// learning rates of network
+	LearningRates []float64
+	// transfer function names (see neural.TransferFunctionByName)
+	Activations []string
+	// L2 regularization strengths
+	Regularizations []float64
+	// mini-batch sizes (1 for online learning)
+	BatchSizes []int
+	// training epochs
+	Epochs []int
+}
+
+type Config struct {
+	// number of neurons of each hidden layer
+	HiddenSize int
+	// number of hidden layers
+	Depth int
+	// learning rate of network
+	LearningRate float64
+	// transfer function name
+	Activation string
+	// L2 regularization strength
+	Regularization float64
+	// mini-batch size
+	BatchSize int
+	// training epochs
+	Epochs int
+}
+
+type Result struct {
+	// evaluated configuration
+	Config Config
+	// scores reached for each fold
+	Scores []float64
+	// mean of fold scores
+	Mean float64
+	// standard deviation of fold scores
+	Std float64
+	// position in ranking (1 is the best)
+	Rank int
+}
+
+// Layers returns the layers neurons number [input, hidden..., output] of config.
+func (config Config) Layers(inputs int, outputs int) []int {
+	layers := []int{inputs}
+	for d := 0; d < config.Depth; d++ {
+		layers = append(layers, config.HiddenSize)
+	}
+	return append(layers, outputs)
+}
+
+// Factory returns a validation.MLPFactory building networks with config hyperparameters for dataset:
+// the input layer is sized by dataset.Inputs, and networks get the Imputer and Encoder of dataset,
+// fitted again on the training patterns of each fold.
+func (config Config) Factory(dataset *neural.Dataset) validation.MLPFactory {
+	tf, tfd, _ := neural.TransferFunctionByName(config.Activation)
+	layers := config.Layers(dataset.Inputs(), len(dataset.Classes))
+	return func() neural.MultiLayerNetwork {
+		mlp := neural.PrepareMLPNet(layers, config.LearningRate, tf, tfd)
+		mlp.Regularization = config.Regularization
+		mlp.BatchSize = config.BatchSize
+		mlp.Imputer, mlp.Encoder = dataset.Imputer, dataset.Encoder
+		return mlp
+	}
+}
+
+// Model returns an untrained network with config hyperparameters for dataset, as built by Factory.
+func (config Config) Model(dataset *neural.Dataset) neural.Model {
+	mlp := config.Factory(dataset)()
+	return &mlp
+}
+
+func (config Config) String() string {
+	return fmt.Sprintf("hidden=%dx%d lr=%g act=%s l2=%g batch=%d epochs=%d",
+		config.Depth, config.HiddenSize, config.LearningRate, config.Activation, config.Regularization, config.BatchSize, config.Epochs)
+}
+
+// Grid returns every combination of hyperparameters of the search space.
+func (space SearchSpace) Grid() []Config {
+	var configs []Config
+	for _, hiddenSize := range space.HiddenSizes {
+		for _, depth := range space.Depths {
+			for _, learningRate := range space.LearningRates {
+				for _, activation := range space.Activations {
+					for _, regularization := range space.Regularizations {
+						for _, batchSize := range space.BatchSizes {
+							for _, epochs := range space.Epochs {
+								configs = append(configs, Config{HiddenSize: hiddenSize, Depth: depth, LearningRate: learningRate,
+									Activation: activation, Regularization: regularization, BatchSize: batchSize, Epochs: epochs})
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return configs
+}
+
+// Sample returns a configuration drawing each hyperparameter uniformly from the search space.
+func (space SearchSpace) Sample(random *rand.Rand) Config {
+	return Config{
+		HiddenSize:     space.HiddenSizes[random.Intn(len(space.HiddenSizes))],
+		Depth:          space.Depths[random.Intn(len(space.Depths))],
+		LearningRate:   space.LearningRates[random.Intn(len(space.LearningRates))],
+		Activation:     space.Activations[random.Intn(len(space.Activations))],
+		Regularization: space.Regularizations[random.Intn(len(space.Regularizations))],
+		BatchSize:      space.BatchSizes[random.Intn(len(space.BatchSizes))],
+		Epochs:         space.Epochs[random.Intn(len(space.Epochs))],
+	}
+}
+
+// Validate checks every dimension of the search space has at least one value
+// and every activation is a known transfer function.
+func (space SearchSpace) Validate() error {
+	if len(space.HiddenSizes) == 0 || len(space.Depths) == 0 || len(space.LearningRates) == 0 || len(space.Activations) == 0 ||
+		len(space.Regularizations) == 0 || len(space.BatchSizes) == 0 || len(space.Epochs) == 0 {
+		return &neural.ConfigError{Field: "SearchSpace", Reason: "has an empty dimension"}
+	}
+	for _, activation := range space.Activations {
+		if _, _, found := neural.TransferFunctionByName(activation); !found {
+			return &neural.ConfigError{Field: "Activations", Reason: fmt.Sprintf("has unknown activation %q", activation)}
+		}
+	}
+	return nil
+}
+
+// GridSearch evaluates every configuration of the search space with k-fold validation,
+// running up to workers evaluations in parallel.
+// It returns results ranked by mean score and the best one.
+func GridSearch(space SearchSpace, dataset *neural.Dataset, k int, shuffle int, workers int) ([]Result, Result, error) {
+	if errorValue := space.Validate(); errorValue != nil {
+		return nil, Result{}, errorValue
+	}
+	return Evaluate(space.Grid(), dataset, k, shuffle, workers)
+}
+
+// RandomSearch evaluates iterations configurations sampled from the search space with passed seed,
+// using k-fold validation and running up to workers evaluations in parallel.
+// It returns results ranked by mean score and the best one.
+func RandomSearch(space SearchSpace, dataset *neural.Dataset, k int, shuffle int, iterations int, seed int64, workers int) ([]Result, Result, error) {
+	if errorValue := space.Validate(); errorValue != nil {
+		return nil, Result{}, errorValue
+	}
+	random := rand.New(rand.NewSource(seed))
+	configs := make([]Config, iterations)
+	for i := range configs {
+		configs[i] = space.Sample(random)
+	}
+	return Evaluate(configs, dataset, k, shuffle, workers)
+}
+
+// Evaluate scores each configuration with k-fold validation, running up to workers
+// evaluations in parallel goroutines. Patterns are split once, so that every configuration
+// is trained and tested on the same folds and scores differ only by the configuration.
+// It returns results ranked by mean score and the best one.
+func Evaluate(configs []Config, dataset *neural.Dataset, k int, shuffle int, workers int) ([]Result, Result, error) {
+	if len(dataset.Patterns) == 0 {
+		return nil, Result{}, fmt.Errorf("Evaluate: %w", neural.ErrEmptyDataset)
+	}
+	if len(configs) == 0 {
+		return nil, Result{}, &neural.ConfigError{Field: "configs", Reason: "is empty"}
+	}
+	folds, errorValue := validation.KFoldIndexSplit(len(dataset.Patterns), k, shuffle)
+	if errorValue != nil {
+		return nil, Result{}, fmt.Errorf("Evaluate: %w", errorValue)
+	}
+	results := make([]Result, len(configs))
+	errorValues := make([]error, len(configs))
+	parallel(len(configs), workers, func(index int) {
+		config := configs[index]
+		scores, _, _, errorValue := validation.FoldsValidation(config.Model(dataset),
+			dataset, config.Epochs, folds)
+		if errorValue != nil {
+			errorValues[index] = fmt.Errorf("config %s: %w", config.String(), errorValue)
+			return
+		}
+		results[index] = newResult(config, scores)
+
+		validationLog.WithFields(logging.Fields{
+			"level":     "info",
+			"place":     "tuning",
+			"method":    "Evaluate",
+			"config":    config.String(),
+			"meanScore": results[index].Mean,
+		}).Info("Evaluation completed for current config.")
+	})
+
+	if errorValue := firstError(errorValues); errorValue != nil {
+		return nil, Result{}, errorValue
+	}
+	Rank(results)
+	return results, results[0], nil
+}
+
+// firstError returns the first non nil error of errorValues.
+func firstError(errorValues []error) error {
+	for _, errorValue := range errorValues {
+		if errorValue != nil {
+			return errorValue
+		}
+	}
+	return nil
+}
+
+// parallel runs job for each index in [0, n) on up to workers goroutines and waits for completion.
+func parallel(n int, workers int, job func(index int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				job(index)
+			}
+		}()
+	}
+	for index := 0; index < n; index++ {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// newResult computes mean and standard deviation of scores.
+func newResult(config Config, scores []float64) Result {
+	mean, std := 0.0, 0.0
+	for _, score := range scores {
+		mean += score
+	}
+	mean = mean / float64(len(scores))
+	for _, score := range scores {
+		std += (score - mean) * (score - mean)
+	}
+	if len(scores) > 1 {
+		std = math.Sqrt(std / float64(len(scores)-1))
+	}
+	return Result{Config: config, Scores: scores, Mean: mean, Std: std}
+}
+
+// Rank sorts results by descending mean score (ties broken by lower deviation) and sets their rank.
+func Rank(results []Result) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return better(results[i], results[j])
+	})
+	for index := range results {
+		results[index].Rank = index + 1
+	}
+}
+
+// better tells whether result a ranks before result b.
+func better(a Result, b Result) bool {
+	if a.Mean != b.Mean {
+		return a.Mean > b.Mean
+	}
+	return a.Std < b.Std
+}
+
+// WriteResults writes ranked results as an aligned text table.
+func WriteResults(w io.Writer, results []Result) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "rank\tmean\tstd\tdepth\thidden\tlearningRate\tactivation\tregularization\tbatch\tepochs")
+	for _, result := range results {
+		fmt.Fprintf(table, "%d\t%.2f\t%.2f\t%d\t%d\t%g\t%s\t%g\t%d\t%d\n",
+			result.Rank, result.Mean, result.Std, result.Config.Depth, result.Config.HiddenSize, result.Config.LearningRate,
+			result.Config.Activation, result.Config.Regularization, result.Config.BatchSize, result.Config.Epochs)
+	}
+	return table.Flush()
+}
@@ -0,0 +1,58 @@
+package tuning
+
+import (
+	"MultilayerPerceptron/neural"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTuningCategoricalDataset(t *testing.T) {
+	// "blue" appears in a single pattern, so most training folds never see it
+	var data strings.Builder
+	data.WriteString("color,size,class\n")
+	for i := 0; i < 12; i++ {
+		color := []string{"red", "green"}[i%2]
+		if i == 11 {
+			color = "blue"
+		}
+		fmt.Fprintf(&data, "%s,%g,%s\n", color, float64(i)/12, []string{"a", "b"}[i%2])
+	}
+	filePath := filepath.Join(t.TempDir(), "colors.csv")
+	if errorValue := os.WriteFile(filePath, []byte(data.String()), 0o600); errorValue != nil {
+		t.Fatal(errorValue)
+	}
+
+	space := SearchSpace{HiddenSizes: []int{3}, Depths: []int{1}, LearningRates: []float64{0.1}, Activations: []string{"sigmoid"},
+		Regularizations: []float64{0}, BatchSizes: []int{1}, Epochs: []int{2}}
+	searches := map[string]func(dataset *neural.Dataset) error{
+		"Evaluate": func(dataset *neural.Dataset) error {
+			_, _, errorValue := Evaluate(space.Grid(), dataset, 3, 0, 1)
+			return errorValue
+		},
+		"SuccessiveHalving": func(dataset *neural.Dataset) error {
+			_, _, errorValue := SuccessiveHalving(space.Grid(), dataset, 3, 0, 1, 2, 2, 1)
+			return errorValue
+		},
+		"NestedCrossValidation": func(dataset *neural.Dataset) error {
+			_, errorValue := NestedCrossValidation(GridSearcher(space, 2, 0, 1), dataset, 3, 0)
+			return errorValue
+		},
+	}
+	for name, search := range searches {
+		for _, method := range []neural.CategoricalEncoding{neural.OneHotEncoding, neural.BinaryEncoding, neural.TargetEncoding} {
+			rand.Seed(1)
+			dataset, errorValue := neural.LoadDatasetFromCSVFile(filePath, neural.CSVOptions{Header: true,
+				ColumnTypes: map[int]neural.ColumnType{0: neural.CategoricalColumn}, Encoder: neural.NewEncoder(method, neural.UnknownUnseen)})
+			if errorValue != nil {
+				t.Fatal(errorValue)
+			}
+			if errorValue := search(dataset); errorValue != nil {
+				t.Errorf("%s with %s encoding: %v", name, method, errorValue)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package util
+
+import (
+	"math"
+)
+
+// Mean return the arithmetic mean of a float64 slice (NaN if empty).
+func Mean(v []float64) float64 {
+	if len(v) == 0 {
+		return math.NaN()
+	}
+	acc := 0.0
+	for _, e := range v {
+		acc += e
+	}
+	return acc / float64(len(v))
+}
+
+// StdDev return the sample standard deviation of a float64 slice (0 if it has less than 2 elements).
+func StdDev(v []float64) float64 {
+	if len(v) < 2 {
+		return 0.0
+	}
+	m := Mean(v)
+	acc := 0.0
+	for _, e := range v {
+		acc += (e - m) * (e - m)
+	}
+	return math.Sqrt(acc / float64(len(v)-1))
+}
+
+// NormalCDF return the cumulative distribution function of the standard normal distribution in x.
+func NormalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// StudentTTwoTailed return the two tailed p-value of statistic t for a Student t distribution
+// with df degrees of freedom.
+func StudentTTwoTailed(t float64, df float64) float64 {
+	if math.IsNaN(t) || df <= 0 {
+		return math.NaN()
+	}
+	if math.IsInf(t, 0) {
+		return 0.0
+	}
+	return RegularizedIncompleteBeta(df/(df+t*t), df/2.0, 0.5)
+}
+
+// ChiSquareSurvival return the probability that a chi-square distributed variable
+// with one degree of freedom exceeds x.
+func ChiSquareSurvival(x float64) float64 {
+	if x <= 0 {
+		return 1.0
+	}
+	return math.Erfc(math.Sqrt(x / 2.0))
+}
+
+// RegularizedIncompleteBeta return the regularized incomplete beta function I_x(a, b),
+// evaluated with its continued fraction expansion.
+func RegularizedIncompleteBeta(x float64, a float64, b float64) float64 {
+	if x <= 0 {
+		return 0.0
+	}
+	if x >= 1 {
+		return 1.0
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(x, a, b) / a
+	}
+	return 1.0 - front*betaContinuedFraction(1-x, b, a)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction of the incomplete beta function
+// with the modified Lentz method.
+func betaContinuedFraction(x float64, a float64, b float64) float64 {
+	const (
+		iterations = 300
+		epsilon    = 1e-14
+		tiny       = 1e-300
+	)
+	c := 1.0
+	d := 1.0 - (a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1.0 / d
+	h := d
+	for m := 1; m <= iterations; m++ {
+		fm := float64(m)
+		numerator := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1.0 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1.0 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1.0 / d
+		h *= d * c
+
+		numerator = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1.0 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1.0 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1.0 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1.0) < epsilon {
+			break
+		}
+	}
+	return h
+}
+
+// BinomialTwoTailed return the exact two tailed p-value of observing at most k successes
+// (or symmetrically at least n-k) in n trials with success probability 0.5.
+func BinomialTwoTailed(k int, n int) float64 {
+	if n == 0 {
+		return 1.0
+	}
+	if k > n-k {
+		k = n - k
+	}
+	p := 0.0
+	for i := 0; i <= k; i++ {
+		lgn, _ := math.Lgamma(float64(n + 1))
+		lgi, _ := math.Lgamma(float64(i + 1))
+		lgni, _ := math.Lgamma(float64(n - i + 1))
+		p += math.Exp(lgn - lgi - lgni - float64(n)*math.Ln2)
+	}
+	return math.Min(1.0, 2.0*p)
+}
@@ -1,18 +1,18 @@
 package util
 
 import (
-	log "github.com/sirupsen/logrus"
+	"MultilayerPerceptron/logging"
+	"errors"
+	"fmt"
 	"math"
 	"math/rand"
-	"os"
+	"sort"
 	"strconv"
 	"time"
 )
 
-func init() {
-	log.SetOutput(os.Stdout)
-	log.SetLevel(log.DebugLevel)
-}
+// ErrShapeMismatch is returned by functions combining slices of different length.
+var ErrShapeMismatch = errors.New("shape mismatch")
 
 // Random return pseudo random number in [min, max]
 func Random(min, max int) int {
@@ -61,24 +61,16 @@ func StringToFloat(slice []string, mode int, def float64) []float64 {
 }
 
 // ScalarProduct compute scalar product between two float64 based slices.
-// It returns a float64 value.
-func ScalarProduct(a []float64, b []float64) float64 {
+// It returns a float64 value, or an error wrapping ErrShapeMismatch if slices have different length.
+func ScalarProduct(a []float64, b []float64) (float64, error) {
 	if len(a) != len(b) {
-		log.WithFields(log.Fields{
-			"level":  "error",
-			"place":  "mixed",
-			"method": "ScalarProduct",
-			"msg":    "scalar product between slices",
-			"aLen":   len(a),
-			"bLen":   len(b),
-		}).Error("Failed to compute scalar product between slices: different length.")
-		return -1.0
+		return 0.0, fmt.Errorf("%w: scalar product between slices of length %d and %d", ErrShapeMismatch, len(a), len(b))
 	}
 	var result = 0.0
 	for index, value := range a {
 		result = result + (value * b[index])
 	}
-	return result
+	return result, nil
 }
 
 // MaxInSlice return max value in float64 slice
@@ -95,6 +87,21 @@ func MaxInSlice(v []float64) (float64, int) {
 	return mv, mi
 }
 
+// Percentile return the p-th percentile (p in [0, 100]) of a float64 slice,
+// linearly interpolating between closest ranks. The passed slice is not modified.
+func Percentile(v []float64, p float64) float64 {
+	if len(v) == 0 {
+		return math.NaN()
+	}
+	sorted := make([]float64, len(v))
+	copy(sorted, v)
+	sort.Float64s(sorted)
+	rank := p / 100.0 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
 func GenerateRandomIntWithBinaryDim(d int) int64 {
 	rand.Seed(time.Now().UTC().UnixNano())
 	return rand.Int63n(int64(2 ^ d))
@@ -121,7 +128,13 @@ func ConvertIntToBinary(n int64, d int) []float64 {
 	bi := make([]float64, d)
 	zn := d - len(bs)
 	if zn < 0 {
-		log.Warning("Too small base")
+		logging.For(logging.Loading).WithFields(logging.Fields{
+			"level":  "warn",
+			"place":  "util",
+			"method": "ConvertIntToBinary",
+			"number": n,
+			"digits": d,
+		}).Warn("Too small base")
 		bi = make([]float64, len(bs))
 		zn = 0
 	}
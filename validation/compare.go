@@ -0,0 +1,215 @@
+package validation
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/neural"
+	"MultilayerPerceptron/util"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+type TestResult struct {
+	// test statistic
+	Statistic float64
+	// two tailed p-value
+	PValue float64
+	// effect size of the test
+	EffectSize float64
+}
+
+type Comparison struct {
+	// score of first model on each fold of each repetition
+	ScoresA []float64
+	// score of second model on the same folds
+	ScoresB []float64
+	// mean score of first model
+	MeanA float64
+	// mean score of second model
+	MeanB float64
+	// paired t-test on fold scores, effect size is Cohen's d of differences
+	PairedTTest TestResult
+	// Nadeau–Bengio corrected resampled t-test on fold scores, effect size is Cohen's d of differences
+	CorrectedTTest TestResult
+	// McNemar's test on out-of-fold predictions of first repetition, effect size is the odds ratio OnlyA/OnlyB.
+	// With less than 25 discordant patterns the exact binomial test is used and the statistic is min(OnlyA, OnlyB),
+	// otherwise the statistic is the continuity corrected chi-square.
+	McNemar TestResult
+	// Wilcoxon signed-rank test on fold scores, effect size is r = z/sqrt(n)
+	Wilcoxon TestResult
+	// patterns correctly classified only by first model (first repetition)
+	OnlyA int
+	// patterns correctly classified only by second model (first repetition)
+	OnlyB int
+}
+
+// CompareClassifiers evaluates clones of two models trained for epochs on identical stratified folds of dataset,
+// repeating k-fold validation repeats times with seeds seed, seed+1, ...
+// It returns fold scores of both models with paired t-test, corrected resampled t-test,
+// McNemar's test and Wilcoxon signed-rank test on their differences.
+func CompareClassifiers(a neural.Model, b neural.Model, dataset *neural.Dataset, epochs int, k int, repeats int, seed int64) (Comparison, error) {
+	var comparison Comparison
+	if repeats < 1 {
+		return comparison, &neural.ConfigError{Field: "repeats", Reason: fmt.Sprintf("is %d, at least 1 needed", repeats)}
+	}
+
+	var oofA, oofB []float64
+	for r := 0; r < repeats; r++ {
+		folds, errorValue := stratifiedKFoldIndexSplit(dataset.Patterns, k, rand.New(rand.NewSource(seed+int64(r))))
+		if errorValue != nil {
+			return comparison, fmt.Errorf("CompareClassifiers: %w", errorValue)
+		}
+		splits := kFoldSplits(folds)
+		scoresA, predictionsA, errorValue := evaluateSplits(dataset.Patterns, splits, modelFit(a, epochs, dataset.Classes, make([]neural.Model, k)), "CompareClassifiers")
+		if errorValue != nil {
+			return comparison, errorValue
+		}
+		scoresB, predictionsB, errorValue := evaluateSplits(dataset.Patterns, splits, modelFit(b, epochs, dataset.Classes, make([]neural.Model, k)), "CompareClassifiers")
+		if errorValue != nil {
+			return comparison, errorValue
+		}
+		comparison.ScoresA = append(comparison.ScoresA, scoresA...)
+		comparison.ScoresB = append(comparison.ScoresB, scoresB...)
+		if r == 0 {
+			oofA = outOfFold(predictionsA)
+			oofB = outOfFold(predictionsB)
+		}
+	}
+	comparison.MeanA = util.Mean(comparison.ScoresA)
+	comparison.MeanB = util.Mean(comparison.ScoresB)
+
+	differences := make([]float64, len(comparison.ScoresA))
+	for i := range differences {
+		differences[i] = comparison.ScoresA[i] - comparison.ScoresB[i]
+	}
+	testTrainRatio := 1.0 / float64(k-1)
+	comparison.PairedTTest = pairedTTest(differences, 0.0)
+	comparison.CorrectedTTest = pairedTTest(differences, testTrainRatio)
+	comparison.Wilcoxon = wilcoxonSignedRank(differences)
+
+	for index, pattern := range dataset.Patterns {
+		correctA := oofA[index] == pattern.SingleExpectation
+		correctB := oofB[index] == pattern.SingleExpectation
+		if correctA && !correctB {
+			comparison.OnlyA++
+		} else if correctB && !correctA {
+			comparison.OnlyB++
+		}
+	}
+	comparison.McNemar = mcNemar(comparison.OnlyA, comparison.OnlyB)
+
+	validationLog.WithFields(logging.Fields{
+		"level":          "info",
+		"place":          "validation",
+		"method":         "CompareClassifiers",
+		"meanA":          comparison.MeanA,
+		"meanB":          comparison.MeanB,
+		"pairedT":        comparison.PairedTTest.PValue,
+		"correctedT":     comparison.CorrectedTTest.PValue,
+		"mcNemar":        comparison.McNemar.PValue,
+		"wilcoxon":       comparison.Wilcoxon.PValue,
+		"comparedScores": len(differences),
+	}).Info("Comparison completed.")
+
+	return comparison, nil
+}
+
+// pairedTTest tests whether mean of differences is zero. With testTrainRatio greater than 0 the variance
+// is inflated by the Nadeau–Bengio correction 1/n + n_test/n_train for overlapping training sets.
+func pairedTTest(differences []float64, testTrainRatio float64) TestResult {
+	n := float64(len(differences))
+	mean := util.Mean(differences)
+	std := util.StdDev(differences)
+	result := TestResult{EffectSize: math.NaN()}
+	if std == 0 {
+		if mean == 0 {
+			result.Statistic, result.PValue = 0.0, 1.0
+		} else {
+			result.Statistic, result.PValue = math.Copysign(math.Inf(1), mean), 0.0
+		}
+		return result
+	}
+	result.EffectSize = mean / std
+	result.Statistic = mean / math.Sqrt((1.0/n+testTrainRatio)*std*std)
+	result.PValue = util.StudentTTwoTailed(result.Statistic, n-1)
+	return result
+}
+
+// wilcoxonSignedRank tests whether differences are symmetric around zero, using the normal
+// approximation with tie and continuity corrections. Zero differences are discarded.
+func wilcoxonSignedRank(differences []float64) TestResult {
+	var nonZero []float64
+	for _, difference := range differences {
+		if difference != 0 {
+			nonZero = append(nonZero, difference)
+		}
+	}
+	n := float64(len(nonZero))
+	if n == 0 {
+		return TestResult{Statistic: 0.0, PValue: 1.0, EffectSize: 0.0}
+	}
+	sort.Slice(nonZero, func(i, j int) bool { return math.Abs(nonZero[i]) < math.Abs(nonZero[j]) })
+
+	positiveRanks, tieCorrection := 0.0, 0.0
+	for i := 0; i < len(nonZero); {
+		j := i
+		for j < len(nonZero) && math.Abs(nonZero[j]) == math.Abs(nonZero[i]) {
+			j++
+		}
+		rank := float64(i+j+1) / 2.0
+		for t := i; t < j; t++ {
+			if nonZero[t] > 0 {
+				positiveRanks += rank
+			}
+		}
+		ties := float64(j - i)
+		tieCorrection += ties*ties*ties - ties
+		i = j
+	}
+
+	mean := n * (n + 1) / 4.0
+	variance := n*(n+1)*(2*n+1)/24.0 - tieCorrection/48.0
+	z := 0.0
+	if deviation := math.Abs(positiveRanks-mean) - 0.5; deviation > 0 && variance > 0 {
+		z = math.Copysign(deviation/math.Sqrt(variance), positiveRanks-mean)
+	}
+	return TestResult{
+		Statistic:  positiveRanks,
+		PValue:     2.0 * (1.0 - util.NormalCDF(math.Abs(z))),
+		EffectSize: z / math.Sqrt(n),
+	}
+}
+
+// mcNemar tests whether the two discordant counts differ, using the exact binomial test on the smaller count
+// for less than 25 discordant patterns and the continuity corrected chi-square otherwise.
+// The statistic is the one of the test giving the p-value.
+func mcNemar(onlyA int, onlyB int) TestResult {
+	discordant := onlyA + onlyB
+	result := TestResult{EffectSize: math.NaN()}
+	if onlyB > 0 {
+		result.EffectSize = float64(onlyA) / float64(onlyB)
+	} else if onlyA > 0 {
+		result.EffectSize = math.Inf(1)
+	}
+	if discordant == 0 {
+		result.Statistic, result.PValue = 0.0, 1.0
+		return result
+	}
+	if discordant < 25 {
+		minimum := onlyA
+		if onlyB < minimum {
+			minimum = onlyB
+		}
+		result.Statistic = float64(minimum)
+		result.PValue = util.BinomialTwoTailed(minimum, discordant)
+		return result
+	}
+	difference := math.Abs(float64(onlyA-onlyB)) - 1.0
+	if difference < 0 {
+		difference = 0
+	}
+	result.Statistic = difference * difference / float64(discordant)
+	result.PValue = util.ChiSquareSurvival(result.Statistic)
+	return result
+}
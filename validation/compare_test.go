@@ -0,0 +1,65 @@
+package validation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStatisticalTests(t *testing.T) {
+	tests := []struct {
+		name   string
+		result TestResult
+		want   TestResult
+	}{
+		{
+			// two-tailed binomial test: 2 * (C(10,0) + C(10,1)) / 2^10
+			name:   "McNemar exact",
+			result: mcNemar(1, 9),
+			want:   TestResult{Statistic: 1, PValue: 0.021484375, EffectSize: 1.0 / 9.0},
+		},
+		{
+			// (|30-10| - 1)^2 / 40 with one degree of freedom
+			name:   "McNemar chi-square",
+			result: mcNemar(30, 10),
+			want:   TestResult{Statistic: 9.025, PValue: 0.002663119259138554, EffectSize: 3},
+		},
+		{
+			name:   "McNemar without discordant patterns",
+			result: mcNemar(0, 0),
+			want:   TestResult{Statistic: 0, PValue: 1, EffectSize: math.NaN()},
+		},
+		{
+			// W+ = 15 over 6 ranks, z = (|15 - 10.5| - 0.5) / sqrt(22.75)
+			name:   "Wilcoxon",
+			result: wilcoxonSignedRank([]float64{1, 2, 3, 4, 5, -6}),
+			want:   TestResult{Statistic: 15, PValue: 0.40167816646977306, EffectSize: 0.34236839400873037},
+		},
+		{
+			// t = 0.03 / sqrt((1/5 + 1/9) * 0.00025) with 4 degrees of freedom
+			name:   "Nadeau–Bengio",
+			result: pairedTTest([]float64{0.01, 0.02, 0.03, 0.04, 0.05}, 1.0/9.0),
+			want:   TestResult{Statistic: 3.4016802570830458, PValue: 0.02723513968825353, EffectSize: 1.897366596101028},
+		},
+		{
+			// t = 0.03 / sqrt(0.00025 / 5) with 4 degrees of freedom
+			name:   "paired t-test",
+			result: pairedTTest([]float64{0.01, 0.02, 0.03, 0.04, 0.05}, 0.0),
+			want:   TestResult{Statistic: 4.242640687119285, PValue: 0.013235599563697686, EffectSize: 1.897366596101028},
+		},
+	}
+	for _, test := range tests {
+		fields := []struct {
+			name      string
+			got, want float64
+		}{
+			{"Statistic", test.result.Statistic, test.want.Statistic},
+			{"PValue", test.result.PValue, test.want.PValue},
+			{"EffectSize", test.result.EffectSize, test.want.EffectSize},
+		}
+		for _, field := range fields {
+			if math.IsNaN(field.got) != math.IsNaN(field.want) || math.Abs(field.got-field.want) > 1e-6 {
+				t.Errorf("%s: %s = %g, want %g", test.name, field.name, field.got, field.want)
+			}
+		}
+	}
+}
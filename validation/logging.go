@@ -0,0 +1,8 @@
+package validation
+
+import (
+	"MultilayerPerceptron/logging"
+)
+
+// validationLog writes entries of validation and comparison of models
+var validationLog = logging.For(logging.Validation)
@@ -0,0 +1,318 @@
+package validation
+
+import (
+	"MultilayerPerceptron/logging"
+	"MultilayerPerceptron/neural"
+	"MultilayerPerceptron/util"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	// ConfidenceLevel of intervals reported by resampling strategies
+	ConfidenceLevel = 0.95
+	// standard normal quantile for ConfidenceLevel
+	confidenceZ = 1.959963984540054
+)
+
+type Estimate struct {
+	// estimated accuracy percentage
+	Score float64
+	// lower bound of the confidence interval of Score
+	Lower float64
+	// upper bound of the confidence interval of Score
+	Upper float64
+	// scores reached for each split (or bootstrap replicate)
+	Scores []float64
+}
+
+type BootstrapEstimate struct {
+	// leave-one-out bootstrap estimate, using only out-of-bag predictions
+	OutOfBag Estimate
+	// .632 bootstrap estimate
+	Point632 Estimate
+	// .632+ bootstrap estimate
+	Point632Plus Estimate
+}
+
+// LeaveOneOutValidation perform leave-one-out evaluation of model.
+// It returns the accuracy estimate with its Wilson score confidence interval, or the first error of fitting and prediction.
+func LeaveOneOutValidation(model neural.Model, dataset *neural.Dataset, epochs int) (Estimate, error) {
+	return leavePOutValidation(modelFit(model, epochs, dataset.Classes, make([]neural.Model, 1)), dataset.Patterns, 1, 0, "LeaveOneOutValidation")
+}
+
+// LeavePOutValidation perform leave-p-out evaluation of model.
+// If maxSplits is 0 every combination of p patterns is left out once,
+// otherwise maxSplits combinations are drawn at random.
+// It returns the accuracy estimate with its Wilson score confidence interval, or the first error of fitting and prediction.
+func LeavePOutValidation(model neural.Model, dataset *neural.Dataset, p int, maxSplits int, epochs int) (Estimate, error) {
+	return leavePOutValidation(modelFit(model, epochs, dataset.Classes, make([]neural.Model, 1)), dataset.Patterns, p, maxSplits, "LeavePOutValidation")
+}
+
+// BootstrapValidation perform bootstrap evaluation of model with passed number of replicates.
+// It returns out-of-bag, .632 and .632+ estimates with percentile confidence intervals, or the first error of fitting and prediction.
+func BootstrapValidation(model neural.Model, dataset *neural.Dataset, replicates int, epochs int) (BootstrapEstimate, error) {
+	return bootstrapValidation(modelFit(model, epochs, dataset.Classes, make([]neural.Model, 1)), dataset.Patterns, replicates, "BootstrapValidation")
+}
+
+// leavePOutValidation leaves out each combination of p patterns (or maxSplits random ones),
+// trains a fresh model on the others and predicts the left out patterns.
+func leavePOutValidation(fit fitFunction, patterns []neural.Pattern, p int, maxSplits int, method string) (Estimate, error) {
+	if p < 1 || p >= len(patterns) {
+		return Estimate{}, fmt.Errorf("%s: %w", method, &neural.ConfigError{Field: "p", Reason: fmt.Sprintf("is %d, must be in [1, %d] for %d patterns", p, len(patterns)-1, len(patterns))})
+	}
+	var scores []float64
+	var correct, total = 0, 0
+
+	evaluate := func(left []int) error {
+		var train, test []neural.Pattern
+		l := 0
+		for index := range patterns {
+			if l < len(left) && left[l] == index {
+				test = append(test, patterns[index])
+				l++
+			} else {
+				train = append(train, patterns[index])
+			}
+		}
+		predict, errorValue := fit(0, train)
+		if errorValue != nil {
+			return fmt.Errorf("%s, split %d: %w", method, len(scores), errorValue)
+		}
+		splitCorrect := 0
+		for _, pattern := range test {
+			predicted, errorValue := predict(&pattern)
+			if errorValue != nil {
+				return fmt.Errorf("%s, split %d: %w", method, len(scores), errorValue)
+			}
+			if predicted == pattern.SingleExpectation {
+				splitCorrect++
+			}
+		}
+		correct += splitCorrect
+		total += len(test)
+		scores = append(scores, float64(splitCorrect)/float64(len(test))*100.0)
+
+		validationLog.WithFields(logging.Fields{
+			"level":             "debug",
+			"place":             "validation",
+			"method":            method,
+			"splitNumber":       len(scores),
+			"leftOut":           left,
+			"percentageCorrect": scores[len(scores)-1],
+		}).Debug("Evaluation completed for current split.")
+		return nil
+	}
+
+	if maxSplits > 0 {
+		random := rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+		for s := 0; s < maxSplits; s++ {
+			left := random.Perm(len(patterns))[:p]
+			sort.Ints(left)
+			if errorValue := evaluate(left); errorValue != nil {
+				return Estimate{}, errorValue
+			}
+		}
+	} else {
+		left := make([]int, p)
+		for i := range left {
+			left[i] = i
+		}
+		for {
+			if errorValue := evaluate(left); errorValue != nil {
+				return Estimate{}, errorValue
+			}
+			// move to next combination in lexicographic order
+			i := p - 1
+			for i >= 0 && left[i] == len(patterns)-p+i {
+				i--
+			}
+			if i < 0 {
+				break
+			}
+			left[i]++
+			for j := i + 1; j < p; j++ {
+				left[j] = left[j-1] + 1
+			}
+		}
+	}
+
+	// with p > 1 each pattern is predicted in many splits: the interval is sized on distinct patterns, not predictions
+	estimate := wilsonEstimate(float64(correct)/float64(total), len(patterns))
+	estimate.Scores = scores
+
+	validationLog.WithFields(logging.Fields{
+		"level":     "info",
+		"place":     "validation",
+		"method":    method,
+		"p":         p,
+		"splits":    len(scores),
+		"meanScore": estimate.Score,
+		"lower":     estimate.Lower,
+		"upper":     estimate.Upper,
+	}).Info("Evaluation completed for all splits.")
+
+	return estimate, nil
+}
+
+// bootstrapValidation trains a fresh model on replicates bootstrap samples and evaluates it on the
+// respective out-of-bag patterns, combining results with resubstitution accuracy in .632 and .632+ estimates.
+func bootstrapValidation(fit fitFunction, patterns []neural.Pattern, replicates int, method string) (BootstrapEstimate, error) {
+	var n = len(patterns)
+	if n == 0 {
+		return BootstrapEstimate{}, fmt.Errorf("%s: %w", method, neural.ErrEmptyDataset)
+	}
+	if replicates < 1 {
+		return BootstrapEstimate{}, fmt.Errorf("%s: %w", method, &neural.ConfigError{Field: "replicates", Reason: fmt.Sprintf("is %d, at least 1 needed", replicates)})
+	}
+	random := rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+
+	// resubstitution error and no-information error rate of a model trained on all patterns
+	predict, errorValue := fit(0, patterns)
+	if errorValue != nil {
+		return BootstrapEstimate{}, fmt.Errorf("%s: %w", method, errorValue)
+	}
+	actualFrequency := make(map[float64]float64)
+	predictedFrequency := make(map[float64]float64)
+	resubstitutionError := 0.0
+	for _, pattern := range patterns {
+		predicted, errorValue := predict(&pattern)
+		if errorValue != nil {
+			return BootstrapEstimate{}, fmt.Errorf("%s: %w", method, errorValue)
+		}
+		actualFrequency[pattern.SingleExpectation]++
+		predictedFrequency[predicted]++
+		if predicted != pattern.SingleExpectation {
+			resubstitutionError++
+		}
+	}
+	resubstitutionError = resubstitutionError / float64(n)
+	noInformationError := 0.0
+	for class, frequency := range actualFrequency {
+		noInformationError += frequency / float64(n) * (1.0 - predictedFrequency[class]/float64(n))
+	}
+
+	// errors and evaluations of each pattern when out-of-bag
+	patternErrors := make([]float64, n)
+	patternEvaluations := make([]float64, n)
+	var oobScores, scores632, scores632Plus []float64
+
+	for b := 0; b < replicates; b++ {
+		inBag := make([]bool, n)
+		train := make([]neural.Pattern, n)
+		for i := 0; i < n; i++ {
+			index := random.Intn(n)
+			inBag[index] = true
+			train[i] = patterns[index]
+		}
+		predict, errorValue := fit(0, train)
+		if errorValue != nil {
+			return BootstrapEstimate{}, fmt.Errorf("%s, replicate %d: %w", method, b, errorValue)
+		}
+
+		replicateErrors, replicateEvaluations := 0.0, 0.0
+		for index, pattern := range patterns {
+			if inBag[index] {
+				continue
+			}
+			replicateEvaluations++
+			patternEvaluations[index]++
+			predicted, errorValue := predict(&pattern)
+			if errorValue != nil {
+				return BootstrapEstimate{}, fmt.Errorf("%s, replicate %d: %w", method, b, errorValue)
+			}
+			if predicted != pattern.SingleExpectation {
+				replicateErrors++
+				patternErrors[index]++
+			}
+		}
+		if replicateEvaluations == 0 {
+			continue
+		}
+		oobError := replicateErrors / replicateEvaluations
+		oobScores = append(oobScores, (1.0-oobError)*100.0)
+		scores632 = append(scores632, (1.0-point632(resubstitutionError, oobError))*100.0)
+		scores632Plus = append(scores632Plus, (1.0-point632Plus(resubstitutionError, oobError, noInformationError))*100.0)
+
+		validationLog.WithFields(logging.Fields{
+			"level":           "debug",
+			"place":           "validation",
+			"method":          method,
+			"replicateNumber": b,
+			"outOfBagLen":     replicateEvaluations,
+			"outOfBagError":   oobError,
+		}).Debug("Evaluation completed for current replicate.")
+	}
+
+	// leave-one-out bootstrap error averages errors of each pattern over replicates not containing it
+	leaveOneOutError, evaluated := 0.0, 0.0
+	for index := range patterns {
+		if patternEvaluations[index] > 0 {
+			leaveOneOutError += patternErrors[index] / patternEvaluations[index]
+			evaluated++
+		}
+	}
+	leaveOneOutError = leaveOneOutError / evaluated
+
+	estimate := BootstrapEstimate{
+		OutOfBag:     percentileEstimate((1.0-leaveOneOutError)*100.0, oobScores),
+		Point632:     percentileEstimate((1.0-point632(resubstitutionError, leaveOneOutError))*100.0, scores632),
+		Point632Plus: percentileEstimate((1.0-point632Plus(resubstitutionError, leaveOneOutError, noInformationError))*100.0, scores632Plus),
+	}
+
+	validationLog.WithFields(logging.Fields{
+		"level":         "info",
+		"place":         "validation",
+		"method":        method,
+		"replicates":    replicates,
+		"outOfBagScore": estimate.OutOfBag.Score,
+		"632Score":      estimate.Point632.Score,
+		"632PlusScore":  estimate.Point632Plus.Score,
+	}).Info("Evaluation completed for all replicates.")
+
+	return estimate, nil
+}
+
+// point632 combines resubstitution and out-of-bag error rates in the .632 estimator.
+func point632(resubstitutionError float64, oobError float64) float64 {
+	return 0.368*resubstitutionError + 0.632*oobError
+}
+
+// point632Plus combines resubstitution and out-of-bag error rates in the .632+ estimator,
+// weighting them by the relative overfitting rate with respect to noInformationError.
+func point632Plus(resubstitutionError float64, oobError float64, noInformationError float64) float64 {
+	oobError = math.Min(oobError, noInformationError)
+	overfittingRate := 0.0
+	if oobError > resubstitutionError && noInformationError > resubstitutionError {
+		overfittingRate = (oobError - resubstitutionError) / (noInformationError - resubstitutionError)
+	}
+	weight := 0.632 / (1.0 - 0.368*overfittingRate)
+	return (1.0-weight)*resubstitutionError + weight*oobError
+}
+
+// wilsonEstimate returns accuracy percentage of the rate p of correct predictions,
+// with its Wilson score confidence interval over total independent patterns.
+func wilsonEstimate(p float64, total int) Estimate {
+	if total == 0 || math.IsNaN(p) {
+		return Estimate{Score: math.NaN(), Lower: math.NaN(), Upper: math.NaN()}
+	}
+	n := float64(total)
+	z2 := confidenceZ * confidenceZ
+	center := (p + z2/(2*n)) / (1 + z2/n)
+	margin := confidenceZ / (1 + z2/n) * math.Sqrt(p*(1-p)/n+z2/(4*n*n))
+	return Estimate{Score: p * 100.0, Lower: math.Max(center-margin, 0.0) * 100.0, Upper: math.Min(center+margin, 1.0) * 100.0}
+}
+
+// percentileEstimate returns score with the percentile confidence interval of replicate scores.
+func percentileEstimate(score float64, scores []float64) Estimate {
+	alpha := (1.0 - ConfidenceLevel) / 2.0 * 100.0
+	return Estimate{
+		Score:  score,
+		Lower:  util.Percentile(scores, alpha),
+		Upper:  util.Percentile(scores, 100.0-alpha),
+		Scores: scores,
+	}
+}
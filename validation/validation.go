@@ -1,116 +1,162 @@
 package validation
 
 import (
+	"MultilayerPerceptron/logging"
 	"MultilayerPerceptron/neural"
 	"MultilayerPerceptron/util"
-	log "github.com/sirupsen/logrus"
+	"fmt"
+	"math"
 	"math/rand"
+	"sort"
 	"time"
 )
 
+// MLPFactory creates a fresh, untrained multi layer network. It is called once for each fold.
+// If the network has an Imputer, an Encoder or a Scaler, they are fitted again on the training patterns of the fold.
+type MLPFactory func() neural.MultiLayerNetwork
+
+// split holds indexes of training and testing patterns of a fold.
+type split struct {
+	train []int
+	test  []int
+}
+
+// predictFunction returns the class predicted for pattern by a trained model.
+type predictFunction func(pattern *neural.Pattern) (float64, error)
+
+// fitFunction trains a fresh model on train patterns of fold t.
+// It returns the prediction function of the trained model.
+type fitFunction func(t int, train []neural.Pattern) (predictFunction, error)
+
 // TrainTestPatternsSplit split an array of patterns in training and testing.
 // if shuffle is 0 the function takes the first percentage items as train and the other as test
-// otherwise the patterns array is shuffled before partitioning
-func TrainTestPatternsSplit(patterns []neural.Pattern, percentage float64, shuffle int) (train []neural.Pattern, test []neural.Pattern) {
-	var splitPivot = int(float64(len(patterns)) * percentage)
-	train = make([]neural.Pattern, splitPivot)
-	test = make([]neural.Pattern, len(patterns)-splitPivot)
-
-	if shuffle == 1 {
-		rand.Seed(time.Now().UTC().UnixNano())
-		perm := rand.Perm(len(patterns))
-		for i := 0; i < splitPivot; i++ {
-			train[i] = patterns[perm[i]]
-		}
-		for i := 0; i < len(patterns)-splitPivot; i++ {
-			test[i] = patterns[perm[i]]
-		}
-	} else {
-		train = patterns[:splitPivot]
-		test = patterns[splitPivot:]
+// otherwise the patterns array is shuffled before partitioning.
+// It returns a ConfigError if percentage is not in [0, 1].
+func TrainTestPatternsSplit(patterns []neural.Pattern, percentage float64, shuffle int) (train []neural.Pattern, test []neural.Pattern, errorValue error) {
+	s, errorValue := trainTestIndexSplit(len(patterns), percentage, shuffle)
+	if errorValue != nil {
+		return nil, nil, errorValue
 	}
+	train = selectPatterns(patterns, s.train)
+	test = selectPatterns(patterns, s.test)
 
-	log.WithFields(log.Fields{
+	validationLog.WithFields(logging.Fields{
 		"level":     "info",
 		"msg":       "splitting completed",
 		"trainSet":  len(train),
 		"testSet: ": len(test),
 	}).Info("Complete splitting train/test set.")
 
-	return train, test
+	return train, test, nil
 }
 
 // TrainTestPatternSplit split an array of patterns in training and testing.
 // if shuffle is 0 the function takes the first percentage items as train and the other as test
-// otherwise the patterns array is shuffled before partitioning
-func TrainTestPatternSplit(patterns []neural.Pattern, percentage float64, shuffle int) (train []neural.Pattern, test []neural.Pattern) {
-	var splitPivot = int(float64(len(patterns)) * percentage)
-	train = make([]neural.Pattern, splitPivot)
-	test = make([]neural.Pattern, len(patterns)-splitPivot)
+// otherwise the patterns array is shuffled before partitioning.
+// It returns a ConfigError if percentage is not in [0, 1].
+func TrainTestPatternSplit(patterns []neural.Pattern, percentage float64, shuffle int) (train []neural.Pattern, test []neural.Pattern, errorValue error) {
+	return TrainTestPatternsSplit(patterns, percentage, shuffle)
+}
 
+// trainTestIndexSplit split indexes of n patterns in training and testing.
+// Training and testing indexes never overlap.
+func trainTestIndexSplit(n int, percentage float64, shuffle int) (s split, errorValue error) {
+	if !(percentage >= 0.0 && percentage <= 1.0) {
+		return s, &neural.ConfigError{Field: "percentage", Reason: fmt.Sprintf("is %v, must be in [0, 1]", percentage)}
+	}
+	var splitPivot = int(float64(n) * percentage)
+	var perm []int
 	if shuffle == 1 {
-		rand.Seed(time.Now().UTC().UnixNano())
-		perm := rand.Perm(len(patterns))
-
-		for i := 0; i < splitPivot; i++ {
-			train[i] = patterns[perm[i]]
-		}
-		for i := 0; i < len(patterns)-splitPivot; i++ {
-			test[i] = patterns[perm[i]]
+		perm = rand.New(rand.NewSource(time.Now().UTC().UnixNano())).Perm(n)
+	} else {
+		perm = make([]int, n)
+		for i := range perm {
+			perm[i] = i
 		}
+	}
+	s.train = perm[:splitPivot]
+	s.test = perm[splitPivot:]
+	return s, nil
+}
 
-	} else {
-		train = patterns[:splitPivot]
-		test = patterns[splitPivot:]
+// KFoldPatternsSplit split an array of patterns in k subsets.
+// if shuffle is 0 the function partitions the items maintaining the order
+// otherwise the patterns array is shuffled before partitioning.
+// It returns a ConfigError if k is less than 2 or greater than the number of patterns.
+func KFoldPatternsSplit(patterns []neural.Pattern, k int, shuffle int) ([][]neural.Pattern, error) {
+	folds, errorValue := kFoldIndexSplit(len(patterns), k, shuffle)
+	if errorValue != nil {
+		return nil, errorValue
 	}
+	return selectFolds(patterns, folds), nil
+}
 
-	log.WithFields(log.Fields{
-		"level":     "info",
-		"msg":       "splitting completed",
-		"trainSet":  len(train),
-		"testSet: ": len(test),
-	}).Info("Complete splitting train/test set.")
+// checkFolds returns a ConfigError unless n patterns can be split in k non empty folds, k at least 2.
+func checkFolds(n int, k int) error {
+	if k < 2 || k > n {
+		return &neural.ConfigError{Field: "k", Reason: fmt.Sprintf("is %d, must be in [2, %d] for %d patterns", k, n, n)}
+	}
+	return nil
+}
 
-	return train, test
+// KFoldIndexSplit split indexes of n patterns in k subsets, shuffled if shuffle is 1.
+// It returns a ConfigError if k is less than 2 or greater than n.
+func KFoldIndexSplit(n int, k int, shuffle int) ([][]int, error) {
+	return kFoldIndexSplit(n, k, shuffle)
 }
 
-// KFoldPatternsSplit split an array of patterns in k subsets.
-// if shuffle is 0 the function partitions the items maintaining the order
-// otherwise the patterns array is shuffled before partitioning
-func KFoldPatternsSplit(patterns []neural.Pattern, k int, shuffle int) [][]neural.Pattern {
-	var size = len(patterns) / k
-	var freeElements = len(patterns) % k
+// checkIndexFolds returns a ConfigError unless folds are at least 2 non empty subsets of the indexes of n patterns.
+func checkIndexFolds(n int, folds [][]int) error {
+	if len(folds) < 2 {
+		return &neural.ConfigError{Field: "folds", Reason: fmt.Sprintf("are %d, at least 2 needed", len(folds))}
+	}
+	for f, fold := range folds {
+		if len(fold) == 0 {
+			return &neural.ConfigError{Field: fmt.Sprintf("folds[%d]", f), Reason: "is empty"}
+		}
+		for _, index := range fold {
+			if index < 0 || index >= n {
+				return &neural.ConfigError{Field: fmt.Sprintf("folds[%d]", f), Reason: fmt.Sprintf("has index %d, out of %d patterns", index, n)}
+			}
+		}
+	}
+	return nil
+}
+
+// kFoldIndexSplit split indexes of n patterns in k subsets.
+func kFoldIndexSplit(n int, k int, shuffle int) ([][]int, error) {
+	if errorValue := checkFolds(n, k); errorValue != nil {
+		return nil, errorValue
+	}
+	var size = n / k
+	var freeElements = n % k
 
-	folds := make([][]neural.Pattern, k)
+	folds := make([][]int, k)
 
 	var perm []int
 	if shuffle == 1 {
-		rand.Seed(time.Now().UTC().UnixNano())
-		perm = rand.Perm(len(patterns))
+		perm = rand.New(rand.NewSource(time.Now().UTC().UnixNano())).Perm(n)
 	}
 
 	currSize := 0
-	foldStart := 0
 	curr := 0
 	for f := 0; f < k; f++ {
-		curr = foldStart
 		currSize = size
 		if f < freeElements {
 			currSize++
 		}
-		folds[f] = make([]neural.Pattern, currSize)
+		folds[f] = make([]int, currSize)
 		for i := 0; i < currSize; i++ {
 			if shuffle == 1 {
-				folds[f][i] = patterns[perm[curr]]
+				folds[f][i] = perm[curr]
 			} else {
-				folds[f][i] = patterns[curr]
+				folds[f][i] = curr
 			}
 			curr++
 		}
-		foldStart = curr
 	}
 
-	log.WithFields(log.Fields{
+	validationLog.WithFields(logging.Fields{
 		"level":              "info",
 		"msg":                "splitting completed",
 		"numberOfFolds":      k,
@@ -118,86 +164,285 @@ func KFoldPatternsSplit(patterns []neural.Pattern, k int, shuffle int) [][]neura
 		"consideredElements": (size * k) + freeElements,
 	}).Info("Complete folds splitting.")
 
-	return folds
+	return folds, nil
 }
 
-// RandomSubsamplingValidation perform evaluation on neuron algorithm.
-// It returns scores reached for each fold iteration.
-func RandomSubsamplingValidation(neuron *neural.NeuronUnit, patterns []neural.Pattern, percentage float64, epochs int, folds int, shuffle int) []float64 {
-	var scores, actual, predicted []float64
-	var train, test []neural.Pattern
-	scores = make([]float64, folds)
+// StratifiedKFoldPatternsSplit split an array of patterns in k subsets preserving
+// the class proportions of SingleExpectation in every subset.
+// if shuffle is 0 the function partitions the items of each class maintaining the order
+// otherwise the items of each class are shuffled before partitioning.
+// It returns a ConfigError if k is less than 2 or greater than the number of patterns.
+func StratifiedKFoldPatternsSplit(patterns []neural.Pattern, k int, shuffle int) ([][]neural.Pattern, error) {
+	folds, errorValue := stratifiedKFoldIndexSplit(patterns, k, shuffleRandom(shuffle))
+	if errorValue != nil {
+		return nil, errorValue
+	}
+	return selectFolds(patterns, folds), nil
+}
+
+// SeededStratifiedKFoldPatternsSplit split an array of patterns in k stratified subsets,
+// shuffling the items of each class with passed seed, so that splits are reproducible.
+// It returns a ConfigError if k is less than 2 or greater than the number of patterns.
+func SeededStratifiedKFoldPatternsSplit(patterns []neural.Pattern, k int, seed int64) ([][]neural.Pattern, error) {
+	folds, errorValue := stratifiedKFoldIndexSplit(patterns, k, rand.New(rand.NewSource(seed)))
+	if errorValue != nil {
+		return nil, errorValue
+	}
+	return selectFolds(patterns, folds), nil
+}
 
-	for t := 0; t < folds; t++ {
-		train, test = TrainTestPatternsSplit(patterns, percentage, shuffle)
-		neural.TrainNeuron(neuron, train, epochs, 1)
-		for _, pattern := range test {
-			actual = append(actual, pattern.SingleExpectation)
-			predicted = append(predicted, neural.Predict(neuron, &pattern))
+// stratifiedKFoldIndexSplit groups pattern indexes by class and deals them round robin to folds,
+// so that each fold receives the same share of every class and fold sizes differ at most by one.
+// If random is nil the order of items of each class is maintained.
+func stratifiedKFoldIndexSplit(patterns []neural.Pattern, k int, random *rand.Rand) ([][]int, error) {
+	if errorValue := checkFolds(len(patterns), k); errorValue != nil {
+		return nil, errorValue
+	}
+	var classes []float64
+	groups := make(map[float64][]int)
+	for index, pattern := range patterns {
+		if _, found := groups[pattern.SingleExpectation]; !found {
+			classes = append(classes, pattern.SingleExpectation)
 		}
-		_, percentageCorrect := neural.Accuracy(actual, predicted)
-		scores[t] = percentageCorrect
+		groups[pattern.SingleExpectation] = append(groups[pattern.SingleExpectation], index)
+	}
+	sort.Float64s(classes)
 
-		log.WithFields(log.Fields{
-			"level":             "info",
-			"place":             "validation",
-			"method":            "RandomSubsamplingValidation",
-			"foldNumber":        t,
-			"trainSetLen":       len(train),
-			"testSetLen":        len(test),
-			"percentageCorrect": percentageCorrect,
-		}).Info("Evaluation completed for current fold.")
+	folds := make([][]int, k)
+	curr := 0
+	for _, class := range classes {
+		group := groups[class]
+		if random != nil {
+			random.Shuffle(len(group), func(i, j int) { group[i], group[j] = group[j], group[i] })
+		}
+		for _, index := range group {
+			folds[curr%k] = append(folds[curr%k], index)
+			curr++
+		}
 	}
 
-	acc := 0.0
-	for i := 0; i < len(scores); i++ {
-		acc += scores[i]
+	validationLog.WithFields(logging.Fields{
+		"level":              "info",
+		"msg":                "stratified splitting completed",
+		"numberOfFolds":      k,
+		"numberOfClasses":    len(classes),
+		"consideredElements": curr,
+	}).Info("Complete stratified folds splitting.")
+
+	return folds, nil
+}
+
+// RandomSubsamplingValidation perform evaluation of model.
+// For each fold a clone of model is trained only on the training split.
+// It returns scores reached for each fold iteration, the predictions of each fold
+// (aligned with patterns, NaN for patterns used in training) and the trained model of each fold.
+func RandomSubsamplingValidation(model neural.Model, dataset *neural.Dataset, percentage float64, epochs int, folds int, shuffle int) ([]float64, [][]float64, []neural.Model, error) {
+	splits, errorValue := subsamplingSplits(len(dataset.Patterns), percentage, folds, shuffle)
+	if errorValue != nil {
+		return nil, nil, nil, fmt.Errorf("RandomSubsamplingValidation: %w", errorValue)
 	}
-	mean := acc / float64(len(scores))
+	models := make([]neural.Model, folds)
+	scores, predictions, errorValue := evaluateSplits(dataset.Patterns, splits,
+		modelFit(model, epochs, dataset.Classes, models), "RandomSubsamplingValidation")
+	if errorValue != nil {
+		return nil, nil, nil, errorValue
+	}
+	return scores, predictions, models, nil
+}
 
-	log.WithFields(log.Fields{
-		"level":       "info",
-		"place":       "validation",
-		"method":      "RandomSubsamplingValidation",
-		"folds":       folds,
-		"trainSetLen": len(train),
-		"testSetLen":  len(test),
-		"meanScore":   mean,
-	}).Info("Evaluation completed for all folds.")
+// KFoldValidation perform evaluation of model.
+// For each fold a clone of model is trained only on the other folds.
+// It returns scores reached for each fold iteration, the out-of-fold prediction
+// of each pattern and the trained model of each fold.
+func KFoldValidation(model neural.Model, dataset *neural.Dataset, epochs int, k int, shuffle int) ([]float64, []float64, []neural.Model, error) {
+	folds, errorValue := kFoldIndexSplit(len(dataset.Patterns), k, shuffle)
+	if errorValue != nil {
+		return nil, nil, nil, fmt.Errorf("KFoldValidation: %w", errorValue)
+	}
+	return foldsValidation(model, dataset, epochs, folds, "KFoldValidation")
+}
 
-	return scores
+// FoldsValidation perform evaluation of model over folds computed in advance, such as the ones
+// returned by KFoldIndexSplit, so that several models can be compared on the same partition.
+// For each fold a clone of model is trained only on the other folds.
+// It returns scores reached for each fold iteration, the out-of-fold prediction
+// of each pattern and the trained model of each fold.
+func FoldsValidation(model neural.Model, dataset *neural.Dataset, epochs int, folds [][]int) ([]float64, []float64, []neural.Model, error) {
+	if errorValue := checkIndexFolds(len(dataset.Patterns), folds); errorValue != nil {
+		return nil, nil, nil, fmt.Errorf("FoldsValidation: %w", errorValue)
+	}
+	return foldsValidation(model, dataset, epochs, folds, "FoldsValidation")
 }
 
-// KFoldValidation perform evaluation on neuron algorithm.
-// It returns scores reached for each fold iteration.
-func KFoldValidation(neuron *neural.NeuronUnit, patterns []neural.Pattern, epochs int, k int, shuffle int) []float64 {
-	var scores, actual, predicted []float64
-	var train, test []neural.Pattern
-	scores = make([]float64, k)
-	folds := KFoldPatternsSplit(patterns, k, shuffle)
-	for t := 0; t < k; t++ {
-		train = nil
-		for i := 0; i < k; i++ {
-			if i != t {
-				train = append(train, folds[i]...)
+// foldsValidation trains a clone of model on all folds but one and scores it on that fold, for each fold.
+func foldsValidation(model neural.Model, dataset *neural.Dataset, epochs int, folds [][]int, method string) ([]float64, []float64, []neural.Model, error) {
+	models := make([]neural.Model, len(folds))
+	scores, predictions, errorValue := evaluateSplits(dataset.Patterns, kFoldSplits(folds),
+		modelFit(model, epochs, dataset.Classes, models), method)
+	if errorValue != nil {
+		return nil, nil, nil, errorValue
+	}
+	return scores, outOfFold(predictions), models, nil
+}
+
+// StratifiedKFoldValidation perform evaluation of model over stratified folds.
+// It returns scores reached for each fold iteration, the out-of-fold prediction
+// of each pattern and the trained model of each fold.
+func StratifiedKFoldValidation(model neural.Model, dataset *neural.Dataset, epochs int, k int, shuffle int) ([]float64, []float64, []neural.Model, error) {
+	folds, errorValue := stratifiedKFoldIndexSplit(dataset.Patterns, k, shuffleRandom(shuffle))
+	if errorValue != nil {
+		return nil, nil, nil, fmt.Errorf("StratifiedKFoldValidation: %w", errorValue)
+	}
+	models := make([]neural.Model, k)
+	scores, predictions, errorValue := evaluateSplits(dataset.Patterns, kFoldSplits(folds),
+		modelFit(model, epochs, dataset.Classes, models), "StratifiedKFoldValidation")
+	if errorValue != nil {
+		return nil, nil, nil, errorValue
+	}
+	return scores, outOfFold(predictions), models, nil
+}
+
+// RepeatedStratifiedKFoldValidation perform stratified k-fold evaluation of model
+// once for each seed passed, shuffling patterns with that seed.
+// It returns scores, out-of-fold predictions and trained models of each repetition.
+func RepeatedStratifiedKFoldValidation(model neural.Model, dataset *neural.Dataset, epochs int, k int, seeds []int64) ([][]float64, [][]float64, [][]neural.Model, error) {
+	scores := make([][]float64, len(seeds))
+	predictions := make([][]float64, len(seeds))
+	models := make([][]neural.Model, len(seeds))
+	for r, seed := range seeds {
+		folds, errorValue := stratifiedKFoldIndexSplit(dataset.Patterns, k, rand.New(rand.NewSource(seed)))
+		if errorValue != nil {
+			return nil, nil, nil, fmt.Errorf("RepeatedStratifiedKFoldValidation: %w", errorValue)
+		}
+		models[r] = make([]neural.Model, k)
+		var repeatPredictions [][]float64
+		scores[r], repeatPredictions, errorValue = evaluateSplits(dataset.Patterns, kFoldSplits(folds),
+			modelFit(model, epochs, dataset.Classes, models[r]), "RepeatedStratifiedKFoldValidation")
+		if errorValue != nil {
+			return nil, nil, nil, errorValue
+		}
+		predictions[r] = outOfFold(repeatPredictions)
+	}
+	return scores, predictions, models, nil
+}
+
+// MLPKFoldState holds folds and networks of a k-fold validation whose training can be resumed
+// with a larger epochs budget.
+type MLPKFoldState struct {
+	// indexes of testing patterns of each fold
+	Folds [][]int
+	// network of each fold, trained on the other folds
+	Models []neural.MultiLayerNetwork
+	// sorted class labels of the dataset
+	Classes []string
+}
+
+// NewMLPKFoldState splits patterns of dataset in k folds and creates a fresh network for each fold with factory.
+// It returns a ConfigError if k is less than 2 or greater than the number of patterns.
+func NewMLPKFoldState(factory MLPFactory, dataset *neural.Dataset, k int, shuffle int) (*MLPKFoldState, error) {
+	folds, errorValue := kFoldIndexSplit(len(dataset.Patterns), k, shuffle)
+	if errorValue != nil {
+		return nil, fmt.Errorf("NewMLPKFoldState: %w", errorValue)
+	}
+	return newMLPKFoldState(factory, dataset, folds), nil
+}
+
+// NewMLPFoldsState creates a fresh network with factory for each of folds computed in advance,
+// such as the ones returned by KFoldIndexSplit, so that several states share the same partition.
+// It returns a ConfigError if folds are not at least 2 non empty subsets of the patterns of dataset.
+func NewMLPFoldsState(factory MLPFactory, dataset *neural.Dataset, folds [][]int) (*MLPKFoldState, error) {
+	if errorValue := checkIndexFolds(len(dataset.Patterns), folds); errorValue != nil {
+		return nil, fmt.Errorf("NewMLPFoldsState: %w", errorValue)
+	}
+	return newMLPKFoldState(factory, dataset, folds), nil
+}
+
+// newMLPKFoldState creates a fresh network with factory for each fold.
+func newMLPKFoldState(factory MLPFactory, dataset *neural.Dataset, folds [][]int) *MLPKFoldState {
+	state := &MLPKFoldState{Folds: folds, Models: make([]neural.MultiLayerNetwork, len(folds)), Classes: dataset.Classes}
+	for t := range state.Models {
+		state.Models[t] = factory()
+	}
+	return state
+}
+
+// ResumeMLPKFoldValidation trains the network of each fold of state until it has completed
+// passed number of epochs overall, then evaluates it on its fold.
+// It returns scores reached for each fold iteration and the out-of-fold prediction of each pattern.
+func ResumeMLPKFoldValidation(state *MLPKFoldState, dataset *neural.Dataset, epochs int) ([]float64, []float64, error) {
+	scores, predictions, errorValue := evaluateSplits(dataset.Patterns, kFoldSplits(state.Folds),
+		func(t int, train []neural.Pattern) (predictFunction, error) {
+			var errorValue error
+			mlp := &state.Models[t]
+			if mlp.Epoch == 0 {
+				if mlp.Imputer, mlp.Encoder, mlp.Scaler, errorValue = neural.FitPreprocessing(mlp.Imputer, mlp.Encoder, mlp.Scaler, train); errorValue != nil {
+					return nil, errorValue
+				}
+			}
+			if errorValue = neural.MLPTrainUntil(mlp, train, state.Classes, epochs); errorValue != nil {
+				return nil, errorValue
+			}
+			return mlp.Predict, nil
+		}, "ResumeMLPKFoldValidation")
+	if errorValue != nil {
+		return nil, nil, errorValue
+	}
+	return scores, outOfFold(predictions), nil
+}
+
+// modelFit returns a fitFunction training a clone of model for each fold on classes and storing it in models.
+func modelFit(model neural.Model, epochs int, classes []string, models []neural.Model) fitFunction {
+	return func(t int, train []neural.Pattern) (predictFunction, error) {
+		models[t] = model.Clone()
+		if errorValue := models[t].Fit(train, classes, epochs); errorValue != nil {
+			return nil, errorValue
+		}
+		return models[t].Predict, nil
+	}
+}
+
+// evaluateSplits fits a model on training patterns of each split and scores it on testing ones.
+// It returns scores of each split and predictions of each split aligned with patterns
+// (NaN for patterns not in testing split), or the first error of fitting and prediction.
+func evaluateSplits(patterns []neural.Pattern, splits []split, fit fitFunction, method string) ([]float64, [][]float64, error) {
+	if len(patterns) == 0 {
+		return nil, nil, fmt.Errorf("%s: %w", method, neural.ErrEmptyDataset)
+	}
+	scores := make([]float64, len(splits))
+	predictions := make([][]float64, len(splits))
+
+	for t, s := range splits {
+		predict, errorValue := fit(t, selectPatterns(patterns, s.train))
+		if errorValue != nil {
+			return nil, nil, fmt.Errorf("%s, fold %d: %w", method, t, errorValue)
+		}
+
+		predictions[t] = make([]float64, len(patterns))
+		for i := range predictions[t] {
+			predictions[t][i] = math.NaN()
+		}
+		actual := make([]float64, len(s.test))
+		predicted := make([]float64, len(s.test))
+		for i, index := range s.test {
+			actual[i] = patterns[index].SingleExpectation
+			if predicted[i], errorValue = predict(&patterns[index]); errorValue != nil {
+				return nil, nil, fmt.Errorf("%s, fold %d: %w", method, t, errorValue)
 			}
+			predictions[t][index] = predicted[i]
 		}
-		test = folds[t]
-		neural.TrainNeuron(neuron, train, epochs, 1)
-		for _, pattern := range test {
-			actual = append(actual, pattern.SingleExpectation)
-			predicted = append(predicted, neural.Predict(neuron, &pattern))
+		_, percentageCorrect, errorValue := neural.Accuracy(actual, predicted)
+		if errorValue != nil {
+			return nil, nil, fmt.Errorf("%s, fold %d: %w", method, t, errorValue)
 		}
-		_, percentageCorrect := neural.Accuracy(actual, predicted)
 		scores[t] = percentageCorrect
 
-		log.WithFields(log.Fields{
+		validationLog.WithFields(logging.Fields{
 			"level":             "info",
 			"place":             "validation",
-			"method":            "KFoldValidation",
+			"method":            method,
 			"foldNumber":        t,
-			"trainSetLen":       len(train),
-			"testSetLen":        len(test),
+			"trainSetLen":       len(s.train),
+			"testSetLen":        len(s.test),
 			"percentageCorrect": percentageCorrect,
 		}).Info("Evaluation completed for current fold.")
 	}
@@ -206,152 +451,258 @@ func KFoldValidation(neuron *neural.NeuronUnit, patterns []neural.Pattern, epoch
 	for i := 0; i < len(scores); i++ {
 		acc += scores[i]
 	}
-
 	mean := acc / float64(len(scores))
 
-	log.WithFields(log.Fields{
-		"level":       "info",
-		"place":       "validation",
-		"method":      "KFoldValidation",
-		"folds":       k,
-		"trainSetLen": len(train),
-		"testSetLen":  len(test),
-		"meanScore":   mean,
+	validationLog.WithFields(logging.Fields{
+		"level":     "info",
+		"place":     "validation",
+		"method":    method,
+		"folds":     len(splits),
+		"meanScore": mean,
 	}).Info("Evaluation completed for all folds.")
 
-	return scores
+	return scores, predictions, nil
 }
 
-// MLPRandomSubsamplingValidation returns scores reached for each fold iteration.
-func MLPRandomSubsamplingValidation(mlp *neural.MultiLayerNetwork, patterns []neural.Pattern, percentage float64, epochs int, folds int, shuffle int, mapped []string) []float64 {
-	var scores, actual, predicted []float64
-	var train, test []neural.Pattern
-	scores = make([]float64, folds)
+// MLPRegressionRandomSubsamplingValidation perform evaluation on regression mlp algorithm.
+// For each fold a fresh network is created by factory and trained only on the training split.
+// It returns regression scores (RMSE, MAE, R², MAPE) reached for each fold iteration, the outputs
+// of each fold (aligned with patterns, nil for patterns used in training) and the trained network of each fold.
+// Scores of folds are averaged with MeanRegressionScore.
+func MLPRegressionRandomSubsamplingValidation(factory MLPFactory, dataset *neural.Dataset, percentage float64, epochs int, folds int, shuffle int) ([]neural.RegressionScore, [][][]float64, []neural.MultiLayerNetwork, error) {
+	splits, errorValue := subsamplingSplits(len(dataset.Patterns), percentage, folds, shuffle)
+	if errorValue != nil {
+		return nil, nil, nil, fmt.Errorf("MLPRegressionRandomSubsamplingValidation: %w", errorValue)
+	}
+	models := make([]neural.MultiLayerNetwork, folds)
+	scores, predictions, errorValue := evaluateRegressionSplits(factory, dataset.Patterns, splits,
+		epochs, models, "MLPRegressionRandomSubsamplingValidation")
+	if errorValue != nil {
+		return nil, nil, nil, errorValue
+	}
+	return scores, predictions, models, nil
+}
 
-	for t := 0; t < folds; t++ {
-		train, test = TrainTestPatternsSplit(patterns, percentage, shuffle)
-		neural.MLPTrain(mlp, patterns, mapped, epochs)
+// MLPRegressionKFoldValidation perform evaluation on regression mlp algorithm.
+// For each fold a fresh network is created by factory and trained only on the other folds.
+// It returns regression scores (RMSE, MAE, R², MAPE) reached for each fold iteration,
+// the out-of-fold outputs of each pattern and the trained network of each fold.
+// Scores of folds are averaged with MeanRegressionScore.
+func MLPRegressionKFoldValidation(factory MLPFactory, dataset *neural.Dataset, epochs int, k int, shuffle int) ([]neural.RegressionScore, [][]float64, []neural.MultiLayerNetwork, error) {
+	folds, errorValue := kFoldIndexSplit(len(dataset.Patterns), k, shuffle)
+	if errorValue != nil {
+		return nil, nil, nil, fmt.Errorf("MLPRegressionKFoldValidation: %w", errorValue)
+	}
+	models := make([]neural.MultiLayerNetwork, k)
+	scores, predictions, errorValue := evaluateRegressionSplits(factory, dataset.Patterns, kFoldSplits(folds),
+		epochs, models, "MLPRegressionKFoldValidation")
+	if errorValue != nil {
+		return nil, nil, nil, errorValue
+	}
 
-		for _, pattern := range test {
-			actual = append(actual, pattern.SingleExpectation)
-			oOut := neural.Execute(mlp, &pattern)
-			_, indexMaxOut := util.MaxInSlice(oOut)
-			predicted = append(predicted, float64(indexMaxOut))
+	outputs := make([][]float64, len(dataset.Patterns))
+	for _, foldPredictions := range predictions {
+		for index, output := range foldPredictions {
+			if output != nil {
+				outputs[index] = output
+			}
 		}
+	}
+	return scores, outputs, models, nil
+}
 
-		_, percentageCorrect := neural.Accuracy(actual, predicted)
-		scores[t] = percentageCorrect
-
-		log.WithFields(log.Fields{
-			"level":             "info",
-			"place":             "validation",
-			"method":            "MLPRandomSubsamplingValidation",
-			"foldNumber":        t,
-			"trainSetLen":       len(train),
-			"testSetLen":        len(test),
-			"percentageCorrect": percentageCorrect,
-		}).Info("Evaluation completed for current fold.")
+// evaluateRegressionSplits trains a fresh network on training patterns of each split and scores it on testing ones,
+// comparing every output with the respective MultipleExpectation value.
+// It returns the first error of fitting and prediction.
+func evaluateRegressionSplits(factory MLPFactory, patterns []neural.Pattern, splits []split, epochs int, models []neural.MultiLayerNetwork, method string) ([]neural.RegressionScore, [][][]float64, error) {
+	if len(patterns) == 0 {
+		return nil, nil, fmt.Errorf("%s: %w", method, neural.ErrEmptyDataset)
 	}
+	scores := make([]neural.RegressionScore, len(splits))
+	predictions := make([][][]float64, len(splits))
+
+	for t, s := range splits {
+		var errorValue error
+		train := selectPatterns(patterns, s.train)
+		models[t] = factory()
+		if models[t].Imputer, models[t].Encoder, models[t].Scaler, errorValue = neural.FitPreprocessing(models[t].Imputer, models[t].Encoder, models[t].Scaler, train); errorValue != nil {
+			return nil, nil, fmt.Errorf("%s, fold %d: %w", method, t, errorValue)
+		}
+		if errorValue = neural.MLPRegressionTrain(&models[t], train, epochs); errorValue != nil {
+			return nil, nil, fmt.Errorf("%s, fold %d: %w", method, t, errorValue)
+		}
 
-	acc := 0.0
-	for i := 0; i < len(scores); i++ {
-		acc += scores[i]
+		predictions[t] = make([][]float64, len(patterns))
+		var actual, predicted [][]float64
+		for _, index := range s.test {
+			if predictions[t][index], errorValue = neural.Execute(&models[t], &patterns[index]); errorValue != nil {
+				return nil, nil, fmt.Errorf("%s, fold %d: %w", method, t, errorValue)
+			}
+			actual = append(actual, patterns[index].MultipleExpectation)
+			predicted = append(predicted, predictions[t][index])
+		}
+		if scores[t], errorValue = neural.MultiTargetRegressionMetrics(actual, predicted); errorValue != nil {
+			return nil, nil, fmt.Errorf("%s, fold %d: %w", method, t, errorValue)
+		}
+
+		validationLog.WithFields(logging.Fields{
+			"level":       "info",
+			"place":       "validation",
+			"method":      method,
+			"foldNumber":  t,
+			"trainSetLen": len(s.train),
+			"testSetLen":  len(s.test),
+			"rmse":        scores[t].RMSE,
+			"mae":         scores[t].MAE,
+			"r2":          scores[t].R2,
+			"mape":        scores[t].MAPE,
+		}).Info("Evaluation completed for current fold.")
 	}
 
-	mean := acc / float64(len(scores))
+	mean, skippedR2, skippedMAPE := MeanRegressionScore(scores)
 
-	log.WithFields(log.Fields{
+	validationLog.WithFields(logging.Fields{
 		"level":       "info",
 		"place":       "validation",
-		"method":      "MLPRandomSubsamplingValidation",
-		"folds":       folds,
-		"trainSetLen": len(train),
-		"testSetLen":  len(test),
-		"meanScore":   mean,
+		"method":      method,
+		"folds":       len(splits),
+		"meanRMSE":    mean.RMSE,
+		"meanMAE":     mean.MAE,
+		"meanR2":      mean.R2,
+		"meanMAPE":    mean.MAPE,
+		"skippedR2":   skippedR2,
+		"skippedMAPE": skippedMAPE,
 	}).Info("Evaluation completed for all folds.")
 
-	return scores
+	return scores, predictions, nil
 }
 
-// MLPKFoldValidation RandomSubsamplingValidation perform evaluation on neuron algorithm.
-// It returns scores reached for each fold iteration.
-func MLPKFoldValidation(mlp *neural.MultiLayerNetwork, patterns []neural.Pattern, epochs int, k int, shuffle int, mapped []string) []float64 {
-	var scores, actual, predicted []float64
-	var train, test []neural.Pattern
-	scores = make([]float64, k)
-	folds := KFoldPatternsSplit(patterns, k, shuffle)
+// MeanRegressionScore averages regression scores of folds. R² and MAPE undefined in a fold (NaN for a constant
+// target or for zero actual values) are skipped, so that a single fold doesn't turn the mean into NaN.
+// It returns the mean score with the number of folds whose R² and whose MAPE were skipped.
+// R² and MAPE of the mean are NaN only if they are undefined in every fold.
+func MeanRegressionScore(scores []neural.RegressionScore) (mean neural.RegressionScore, skippedR2 int, skippedMAPE int) {
+	for _, score := range scores {
+		mean.RMSE += score.RMSE
+		mean.MAE += score.MAE
+		if math.IsNaN(score.R2) {
+			skippedR2++
+		} else {
+			mean.R2 += score.R2
+		}
+		if math.IsNaN(score.MAPE) {
+			skippedMAPE++
+		} else {
+			mean.MAPE += score.MAPE
+		}
+	}
+	mean.RMSE = mean.RMSE / float64(len(scores))
+	mean.MAE = mean.MAE / float64(len(scores))
+	mean.R2 = mean.R2 / float64(len(scores)-skippedR2)
+	mean.MAPE = mean.MAPE / float64(len(scores)-skippedMAPE)
+	return
+}
+
+// subsamplingSplits draws folds independent train/test splits of n patterns.
+func subsamplingSplits(n int, percentage float64, folds int, shuffle int) ([]split, error) {
+	if folds < 1 {
+		return nil, &neural.ConfigError{Field: "folds", Reason: fmt.Sprintf("is %d, at least 1 needed", folds)}
+	}
+	splits := make([]split, folds)
+	for t := range splits {
+		var errorValue error
+		if splits[t], errorValue = trainTestIndexSplit(n, percentage, shuffle); errorValue != nil {
+			return nil, errorValue
+		}
+	}
+	return splits, nil
+}
 
-	for t := 0; t < k; t++ {
-		train = nil
-		for i := 0; i < k; i++ {
+// kFoldSplits uses each fold as test set and the others as training set.
+func kFoldSplits(folds [][]int) []split {
+	splits := make([]split, len(folds))
+	for t := range folds {
+		splits[t].test = folds[t]
+		for i := range folds {
 			if i != t {
-				train = append(train, folds[i]...)
+				splits[t].train = append(splits[t].train, folds[i]...)
 			}
 		}
-		test = folds[t]
-		neural.MLPTrain(mlp, patterns, mapped, epochs)
-		for _, pattern := range test {
-			// get actual
-			actual = append(actual, pattern.SingleExpectation)
-			// get output from network
-			oOut := neural.Execute(mlp, &pattern)
-			// get index of max output
-			_, indexMaxOut := util.MaxInSlice(oOut)
-			// add to predicted values
-			predicted = append(predicted, float64(indexMaxOut))
-		}
-		_, percentageCorrect := neural.Accuracy(actual, predicted)
-		scores[t] = percentageCorrect
+	}
+	return splits
+}
 
-		log.WithFields(log.Fields{
-			"level":             "info",
-			"place":             "validation",
-			"method":            "MLPKFoldValidation",
-			"foldNumber":        t,
-			"trainSetLen":       len(train),
-			"testSetLen":        len(test),
-			"percentageCorrect": percentageCorrect,
-		}).Info("Evaluation completed for current fold.")
+// outOfFold merges predictions of k-fold splits, where each pattern is tested exactly once.
+func outOfFold(predictions [][]float64) []float64 {
+	if len(predictions) == 0 {
+		return nil
 	}
-	acc := 0.0
-	for i := 0; i < len(scores); i++ {
-		acc += scores[i]
+	merged := make([]float64, len(predictions[0]))
+	for i := range merged {
+		merged[i] = math.NaN()
+		for _, foldPredictions := range predictions {
+			if !math.IsNaN(foldPredictions[i]) {
+				merged[i] = foldPredictions[i]
+			}
+		}
 	}
-	mean := acc / float64(len(scores))
+	return merged
+}
 
-	log.WithFields(log.Fields{
-		"level":       "info",
-		"place":       "validation",
-		"method":      "MLPKFoldValidation",
-		"folds":       k,
-		"trainSetLen": len(train),
-		"testSetLen":  len(test),
-		"meanScore":   mean,
-	}).Info("Evaluation completed for all folds.")
+// selectPatterns returns patterns at passed indexes.
+func selectPatterns(patterns []neural.Pattern, indexes []int) []neural.Pattern {
+	selected := make([]neural.Pattern, len(indexes))
+	for i, index := range indexes {
+		selected[i] = patterns[index]
+	}
+	return selected
+}
 
-	return scores
+// selectFolds returns patterns of each fold of indexes.
+func selectFolds(patterns []neural.Pattern, folds [][]int) [][]neural.Pattern {
+	selected := make([][]neural.Pattern, len(folds))
+	for f, fold := range folds {
+		selected[f] = selectPatterns(patterns, fold)
+	}
+	return selected
+}
 
+// shuffleRandom returns a time seeded random source if shuffle is 1, nil otherwise.
+func shuffleRandom(shuffle int) *rand.Rand {
+	if shuffle == 1 {
+		return rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+	}
+	return nil
 }
 
-// RNNValidation perform evaluation on neuron algorithm.
-func RNNValidation(mlp *neural.MultiLayerNetwork, patterns []neural.Pattern, epochs int) (float64, []float64) {
+// RNNValidation trains model on MultipleExpectation of patterns, then scores the rounded
+// output probabilities of each pattern against its MultipleExpectation.
+// It returns the mean score, the score of each pattern and the first error of training and prediction.
+func RNNValidation(model neural.Model, patterns []neural.Pattern, epochs int) (float64, []float64, error) {
 	var scores []float64
 	scores = make([]float64, len(patterns))
-	neural.ElmanTrain(mlp, patterns, epochs)
+	if errorValue := model.Fit(patterns, nil, epochs); errorValue != nil {
+		return 0.0, nil, fmt.Errorf("RNNValidation: %w", errorValue)
+	}
 	pCor := 0.0
 
 	for pI, pattern := range patterns {
-		oOut := neural.Execute(mlp, &pattern, 1)
+		oOut, errorValue := model.PredictProba(&pattern)
+		if errorValue != nil {
+			return 0.0, nil, fmt.Errorf("RNNValidation, pattern %d: %w", pI, errorValue)
+		}
 		for oOutI, oOutV := range oOut {
 			oOut[oOutI] = util.Round(oOutV, .5, 0)
 		}
-		log.WithFields(log.Fields{
+		validationLog.WithFields(logging.Fields{
 			"a_p_b": pattern.Features,
 			"rea_c": pattern.MultipleExpectation,
 			"pre_c": oOut,
 		}).Debug()
 
-		_, pCor = neural.Accuracy(pattern.MultipleExpectation, oOut)
+		if _, pCor, errorValue = neural.Accuracy(pattern.MultipleExpectation, oOut); errorValue != nil {
+			return 0.0, nil, fmt.Errorf("RNNValidation, pattern %d: %w", pI, errorValue)
+		}
 		scores[pI] = pCor
 	}
 
@@ -361,7 +712,7 @@ func RNNValidation(mlp *neural.MultiLayerNetwork, patterns []neural.Pattern, epo
 	}
 	mean := acc / float64(len(scores))
 
-	log.WithFields(log.Fields{
+	validationLog.WithFields(logging.Fields{
 		"level":       "info",
 		"place":       "validation",
 		"method":      "RNNValidation",
@@ -370,5 +721,135 @@ func RNNValidation(mlp *neural.MultiLayerNetwork, patterns []neural.Pattern, epo
 		"meanScore":   mean,
 	}).Info("Evaluation completed for all patterns.")
 
-	return mean, scores
+	return mean, scores, nil
+}
+
+// SequenceValidation trains model on train sequences with FitSequences, then executes each test sequence
+// from a reset state, skipping masked steps, and scores its steps with a target (only the last one
+// with FinalTarget): the predicted class against SingleExpectation with classes, the rounded outputs
+// against MultipleExpectation with nil classes, as RNNValidation does.
+// It returns the mean score, the score of each test sequence (NaN for sequences without target,
+// left out of the mean) and the first error of training and prediction.
+func SequenceValidation(model neural.SequenceModel, train []neural.Sequence, test []neural.Sequence, classes []string, epochs int) (float64, []float64, error) {
+	if errorValue := model.FitSequences(train, classes, epochs); errorValue != nil {
+		return 0.0, nil, fmt.Errorf("SequenceValidation: %w", errorValue)
+	}
+	scores := make([]float64, len(test))
+	acc, scored := 0.0, 0
+	for sI := range test {
+		sequence := &test[sI]
+		last := -1
+		for t := range sequence.Steps {
+			if !sequence.Masked(t) {
+				last = t
+			}
+		}
+		model.ResetState()
+		score, targets := 0.0, 0
+		for t := range sequence.Steps {
+			if sequence.Masked(t) {
+				continue
+			}
+			oOut, errorValue := model.Step(&sequence.Steps[t])
+			if errorValue != nil {
+				return 0.0, nil, fmt.Errorf("SequenceValidation, sequence %d, step %d: %w", sI, t, errorValue)
+			}
+			if sequence.FinalTarget && t != last {
+				continue
+			}
+			pCor := 0.0
+			if classes != nil {
+				if _, indexMaxOut := util.MaxInSlice(oOut); float64(indexMaxOut) == sequence.Steps[t].SingleExpectation {
+					pCor = 100.0
+				}
+			} else {
+				if sequence.Steps[t].MultipleExpectation == nil {
+					continue
+				}
+				for oOutI, oOutV := range oOut {
+					oOut[oOutI] = util.Round(oOutV, .5, 0)
+				}
+				if _, pCor, errorValue = neural.Accuracy(sequence.Steps[t].MultipleExpectation, oOut); errorValue != nil {
+					return 0.0, nil, fmt.Errorf("SequenceValidation, sequence %d, step %d: %w", sI, t, errorValue)
+				}
+			}
+			score += pCor
+			targets++
+		}
+		if targets == 0 {
+			scores[sI] = math.NaN()
+			continue
+		}
+		scores[sI] = score / float64(targets)
+		acc += scores[sI]
+		scored++
+	}
+	model.ResetState()
+
+	mean := 0.0
+	if scored > 0 {
+		mean = acc / float64(scored)
+	}
+	validationLog.WithFields(logging.Fields{
+		"level":       "info",
+		"place":       "validation",
+		"method":      "SequenceValidation",
+		"trainSetLen": len(train),
+		"testSetLen":  len(test),
+		"meanScore":   mean,
+	}).Info("Evaluation completed for all sequences.")
+
+	return mean, scores, nil
+}
+
+// SequenceRegressionValidation trains model on MultipleExpectation of train sequences with FitSequences,
+// then executes each test sequence from a reset state, skipping masked steps, and compares the outputs
+// of its steps with a target (only the last one with FinalTarget) to their MultipleExpectation.
+// It returns RMSE, MAE, R² and MAPE of those steps as MultiTargetRegressionMetrics, and the first error of training and prediction.
+func SequenceRegressionValidation(model neural.SequenceModel, train []neural.Sequence, test []neural.Sequence, epochs int) (neural.RegressionScore, error) {
+	if errorValue := model.FitSequences(train, nil, epochs); errorValue != nil {
+		return neural.RegressionScore{}, fmt.Errorf("SequenceRegressionValidation: %w", errorValue)
+	}
+	var actual, predicted [][]float64
+	for sI := range test {
+		sequence := &test[sI]
+		last := -1
+		for t := range sequence.Steps {
+			if !sequence.Masked(t) {
+				last = t
+			}
+		}
+		model.ResetState()
+		for t := range sequence.Steps {
+			if sequence.Masked(t) {
+				continue
+			}
+			oOut, errorValue := model.Step(&sequence.Steps[t])
+			if errorValue != nil {
+				return neural.RegressionScore{}, fmt.Errorf("SequenceRegressionValidation, sequence %d, step %d: %w", sI, t, errorValue)
+			}
+			if (sequence.FinalTarget && t != last) || sequence.Steps[t].MultipleExpectation == nil {
+				continue
+			}
+			actual = append(actual, sequence.Steps[t].MultipleExpectation)
+			predicted = append(predicted, oOut)
+		}
+	}
+	model.ResetState()
+
+	score, errorValue := neural.MultiTargetRegressionMetrics(actual, predicted)
+	if errorValue != nil {
+		return neural.RegressionScore{}, fmt.Errorf("SequenceRegressionValidation: %w", errorValue)
+	}
+	validationLog.WithFields(logging.Fields{
+		"level":       "info",
+		"place":       "validation",
+		"method":      "SequenceRegressionValidation",
+		"trainSetLen": len(train),
+		"testSetLen":  len(test),
+		"rmse":        score.RMSE,
+		"r2":          score.R2,
+	}).Info("Evaluation completed for all sequences.")
+
+	return score, nil
 }
@@ -0,0 +1,52 @@
+package validation
+
+import (
+	"MultilayerPerceptron/neural"
+	"math"
+	"testing"
+)
+
+func TestMeanRegressionScore(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name                   string
+		scores                 []neural.RegressionScore
+		mean                   neural.RegressionScore
+		skippedR2, skippedMAPE int
+	}{
+		{
+			name:   "defined",
+			scores: []neural.RegressionScore{{RMSE: 1, MAE: 1, R2: 0.5, MAPE: 10}, {RMSE: 3, MAE: 2, R2: 0.7, MAPE: 20}},
+			mean:   neural.RegressionScore{RMSE: 2, MAE: 1.5, R2: 0.6, MAPE: 15},
+		},
+		{
+			name:      "constant target",
+			scores:    []neural.RegressionScore{{RMSE: 1, MAE: 1, R2: nan, MAPE: 10}, {RMSE: 3, MAE: 2, R2: 0.7, MAPE: 20}},
+			mean:      neural.RegressionScore{RMSE: 2, MAE: 1.5, R2: 0.7, MAPE: 15},
+			skippedR2: 1,
+		},
+		{
+			name:        "zero actual values",
+			scores:      []neural.RegressionScore{{RMSE: 1, MAE: 1, R2: 0.5, MAPE: nan}, {RMSE: 3, MAE: 2, R2: 0.7, MAPE: nan}},
+			mean:        neural.RegressionScore{RMSE: 2, MAE: 1.5, R2: 0.6, MAPE: nan},
+			skippedMAPE: 2,
+		},
+	}
+	for _, test := range tests {
+		mean, skippedR2, skippedMAPE := MeanRegressionScore(test.scores)
+		if !closeScore(mean, test.mean) || skippedR2 != test.skippedR2 || skippedMAPE != test.skippedMAPE {
+			t.Errorf("%s: MeanRegressionScore = %+v, %d, %d, want %+v, %d, %d", test.name,
+				mean, skippedR2, skippedMAPE, test.mean, test.skippedR2, test.skippedMAPE)
+		}
+	}
+}
+
+// closeScore tells whether every metric of a and b is within 1e-9, NaN matching NaN.
+func closeScore(a neural.RegressionScore, b neural.RegressionScore) bool {
+	for _, pair := range [][2]float64{{a.RMSE, b.RMSE}, {a.MAE, b.MAE}, {a.R2, b.R2}, {a.MAPE, b.MAPE}} {
+		if math.IsNaN(pair[0]) != math.IsNaN(pair[1]) || math.Abs(pair[0]-pair[1]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}